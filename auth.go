@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// apiKeyHeader is the header clients may send the API key in instead of Authorization: Bearer
+const apiKeyHeader = "X-API-Key"
+
+// readAPIKeyFile reads the API key from a file, "-" reads it from stdin.
+// Surrounding whitespace such as a trailing newline is ignored.
+func readAPIKeyFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(io.LimitReader(os.Stdin, 4096))
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return key, nil
+}
+
+// requestAPIKey returns the key sent in the Authorization: Bearer or X-API-Key header
+func requestAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return r.Header.Get(apiKeyHeader)
+}
+
+// validAPIKey reports whether got matches the configured key, every API key check goes through it.
+// Both are hashed first so the constant-time comparison does not leak the key length either.
+func validAPIKey(configured, got string) bool {
+	if configured == "" || got == "" {
+		return false
+	}
+	a, b := sha256.Sum256([]byte(configured)), sha256.Sum256([]byte(got))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+// unauthorized rejects a request with 401
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-netutil"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// requireAPIKey rejects requests that do not send apiKey in a header
+func requireAPIKey(apiKey string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validAPIKey(apiKey, requestAPIKey(r)) {
+			unauthorized(w)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requirePathKey serves prefix+"<key>" for -api-key-in-path, rejecting any other key in the path
+func requirePathKey(apiKey, prefix string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, prefix)
+		if strings.Contains(key, "/") || !validAPIKey(apiKey, key) {
+			unauthorized(w)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testKey = "sk-netutil-0123456789abcdef0123456789abcdef"
+
+func TestValidAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		got        string
+		want       bool
+	}{
+		{"match", testKey, testKey, true},
+		{"different key", testKey, "sk-netutil-ffffffffffffffffffffffffffffffff", false},
+		{"prefix of the key", testKey, testKey[:20], false},
+		{"empty key", testKey, "", false},
+		{"no configured key", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validAPIKey(tt.configured, tt.got); got != tt.want {
+				t.Errorf("validAPIKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		header  map[string]string
+		want    int
+	}{
+		{"bearer header", requireAPIKey(testKey, ok), "/mcp", map[string]string{"Authorization": "Bearer " + testKey}, http.StatusOK},
+		{"x-api-key header", requireAPIKey(testKey, ok), "/mcp", map[string]string{"X-API-Key": testKey}, http.StatusOK},
+		{"wrong header key", requireAPIKey(testKey, ok), "/mcp", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"no header", requireAPIKey(testKey, ok), "/mcp", nil, http.StatusUnauthorized},
+		{"key in path", requirePathKey(testKey, "/sse/", ok), "/sse/" + testKey, nil, http.StatusOK},
+		{"wrong key in path", requirePathKey(testKey, "/sse/", ok), "/sse/sk-netutil-wrong", nil, http.StatusUnauthorized},
+		{"extra path segment", requirePathKey(testKey, "/sse/", ok), "/sse/" + testKey + "/x", nil, http.StatusUnauthorized},
+		{"no key in path", requirePathKey(testKey, "/sse/", ok), "/sse/", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// toolBinaries lists the external commands a tool cannot work without
+var toolBinaries = map[string][]string{
+	"latency":                 {"ping"},
+	"latency_multi":           {"ping"},
+	"latency_monitor":         {"ping"},
+	"path_mtu":                {"ping"},
+	"net_health":              {"ping"},
+	"traceroute":              {tracerouteBinary()},
+	"port_status":             {"ss"},
+	"socket_summary":          {"ss"},
+	"ss_query":                {"ss"},
+	"tcp_info":                {"ss"},
+	"systemd_logs":            {"journalctl"},
+	"manage_service":          {"systemctl"},
+	"service_status":          {"systemctl"},
+	"systemd_list_units":      {"systemctl"},
+	"systemd_failed_units":    {"systemctl"},
+	"systemd_list_unit_files": {"systemctl"},
+}
+
+// toolOptionalBinaries lists commands a tool uses for part of its output.
+// The tool still runs without them, the affected section reports the error.
+var toolOptionalBinaries = map[string][]string{
+	"neighbors":          {"ip"},
+	"bandwidth_test":     {"iperf3"},
+	"system_diagnostics": {"journalctl", "dmesg", "last", "lastb", "systemctl"},
+}
+
+// binaryPackages names the package that provides each command
+var binaryPackages = map[string]string{
+	"ping":       "iputils-ping (Debian/Ubuntu) or iputils (RHEL/Fedora)",
+	"traceroute": "traceroute",
+	"ss":         "iproute2 (Debian/Ubuntu) or iproute (RHEL/Fedora)",
+	"ip":         "iproute2 (Debian/Ubuntu) or iproute (RHEL/Fedora)",
+	"systemctl":  "systemd",
+	"journalctl": "systemd",
+	"dmesg":      "util-linux",
+	"last":       "util-linux (wtmpdb on newer Debian)",
+	"lastb":      "util-linux",
+	"iperf3":     "iperf3",
+}
+
+// registeredTools are the tools registerTool added to the server, in registration order
+var registeredTools []string
+
+// tracerouteBinary returns the traceroute command of the current OS
+func tracerouteBinary() string {
+	if runtime.GOOS == "windows" {
+		return "tracert"
+	}
+	return "traceroute"
+}
+
+// missingBinaries returns the commands in names that are not found in $PATH
+func missingBinaries(names []string) []string {
+	var missing []string
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// missingBinaryMessage explains which commands a tool lacks and how to install them
+func missingBinaryMessage(tool string, missing []string) string {
+	var hints []string
+	for _, name := range missing {
+		if pkg, ok := binaryPackages[name]; ok {
+			hints = append(hints, fmt.Sprintf("install package %s for %s", pkg, name))
+		}
+	}
+	msg := fmt.Sprintf("%s is unavailable: %s not found in $PATH.", tool, strings.Join(missing, ", "))
+	if len(hints) > 0 {
+		msg += " To fix it, " + strings.Join(hints, "; ") + "."
+	}
+	return msg
+}
+
+// requireBinaries wraps handler so calls fail with an install hint while a required command is missing.
+// The check runs on every call, so installing the package takes effect without a restart.
+// The returned description is annotated when a command is missing at startup.
+func requireBinaries(name, description string, handler mcp.ToolHandler) (string, mcp.ToolHandler) {
+	required := toolBinaries[name]
+	if len(required) == 0 {
+		return description, handler
+	}
+	if missing := missingBinaries(required); len(missing) > 0 {
+		slog.Warn("Tool is unavailable until its commands are installed", "tool", name, "missing", missing)
+		description = fmt.Sprintf("[Unavailable: %s not installed] %s", strings.Join(missing, ", "), description)
+	}
+	return description, func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		if missing := missingBinaries(required); len(missing) > 0 {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: missingBinaryMessage(name, missing)}}}, nil
+		}
+		return handler(ctx, args)
+	}
+}
+
+// BinaryStatus reports whether an external command is installed
+type BinaryStatus struct {
+	Name    string `json:"name"`
+	Found   bool   `json:"found"`
+	Path    string `json:"path,omitempty"`
+	Package string `json:"package,omitempty"` // Package to install when missing
+}
+
+// ToolCapability reports whether a registered tool can run on this host
+type ToolCapability struct {
+	Name      string   `json:"name"`
+	Available bool     `json:"available"`
+	Missing   []string `json:"missing,omitempty"`  // Required commands not installed
+	Degraded  []string `json:"degraded,omitempty"` // Optional commands not installed, part of the output is missing
+	Reason    string   `json:"reason,omitempty"`
+}
+
+// Capabilities describes which tools and external commands are usable
+type Capabilities struct {
+	OS       string           `json:"os"`
+	Root     bool             `json:"root"`
+	Tools    []ToolCapability `json:"tools"`
+	Binaries []BinaryStatus   `json:"binaries"`
+}
+
+// probeCapabilities checks every registered tool and the commands they use
+func probeCapabilities() Capabilities {
+	caps := Capabilities{OS: runtime.GOOS, Root: hasRootPrivileges()}
+	used := make(map[string]bool)
+	for _, name := range registeredTools {
+		tc := ToolCapability{Name: name, Available: true}
+		if tc.Missing = missingBinaries(toolBinaries[name]); len(tc.Missing) > 0 {
+			tc.Available = false
+			tc.Reason = missingBinaryMessage(name, tc.Missing)
+		} else if rootTools[name] && !caps.Root {
+			tc.Available = false
+			tc.Reason = "requires root privileges"
+		}
+		tc.Degraded = missingBinaries(toolOptionalBinaries[name])
+		for _, b := range append(toolBinaries[name], toolOptionalBinaries[name]...) {
+			used[b] = true
+		}
+		caps.Tools = append(caps.Tools, tc)
+	}
+
+	names := make([]string, 0, len(used))
+	for b := range used {
+		names = append(names, b)
+	}
+	sort.Strings(names)
+	for _, b := range names {
+		status := BinaryStatus{Name: b}
+		if path, err := exec.LookPath(b); err == nil {
+			status.Found, status.Path = true, path
+		} else {
+			status.Package = binaryPackages[b]
+		}
+		caps.Binaries = append(caps.Binaries, status)
+	}
+	return caps
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// stubPath points $PATH at a directory holding only the named executables
+func stubPath(t *testing.T, names ...string) string {
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestRequireBinaries(t *testing.T) {
+	stubPath(t, "ping")
+
+	called := false
+	handler := func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		called = true
+		return mcp.CallToolResult{}, nil
+	}
+
+	desc, wrapped := requireBinaries("port_status", "List ports", handler)
+	if desc != "[Unavailable: ss not installed] List ports" {
+		t.Errorf("description = %q, want the unavailable annotation", desc)
+	}
+	res, err := wrapped(context.Background(), nil)
+	if err != nil || !res.IsError || called {
+		t.Fatalf("call = %+v, %v, handler called %v, want an error result without calling the handler", res, err, called)
+	}
+	want := "port_status is unavailable: ss not found in $PATH. To fix it, install package iproute2 (Debian/Ubuntu) or iproute (RHEL/Fedora) for ss."
+	if res.Content[0].Text != want {
+		t.Errorf("error = %q, want %q", res.Content[0].Text, want)
+	}
+
+	desc, wrapped = requireBinaries("latency", "Ping a host", handler)
+	if desc != "Ping a host" {
+		t.Errorf("description = %q, want it unchanged when ping is installed", desc)
+	}
+	if res, err := wrapped(context.Background(), nil); err != nil || res.IsError || !called {
+		t.Errorf("call = %+v, %v, handler called %v, want the handler to run", res, err, called)
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	dir := stubPath(t, "ping")
+	saved := registeredTools
+	registeredTools = []string{"latency", "port_status", "neighbors"}
+	defer func() { registeredTools = saved }()
+
+	caps := probeCapabilities()
+	byName := make(map[string]ToolCapability)
+	for _, tc := range caps.Tools {
+		byName[tc.Name] = tc
+	}
+
+	if tc := byName["latency"]; !tc.Available || len(tc.Missing) > 0 {
+		t.Errorf("latency = %+v, want available", tc)
+	}
+	if tc := byName["port_status"]; tc.Available || !reflect.DeepEqual(tc.Missing, []string{"ss"}) || !strings.Contains(tc.Reason, "iproute2") {
+		t.Errorf("port_status = %+v, want unavailable with ss missing and an install hint", tc)
+	}
+	if tc := byName["neighbors"]; !tc.Available || !reflect.DeepEqual(tc.Degraded, []string{"ip"}) {
+		t.Errorf("neighbors = %+v, want available and degraded without ip", tc)
+	}
+
+	want := []BinaryStatus{
+		{Name: "ip", Package: binaryPackages["ip"]},
+		{Name: "ping", Found: true, Path: filepath.Join(dir, "ping")},
+		{Name: "ss", Package: binaryPackages["ss"]},
+	}
+	if !reflect.DeepEqual(caps.Binaries, want) {
+		t.Errorf("binaries = %+v, want %+v", caps.Binaries, want)
+	}
+}
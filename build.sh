@@ -6,11 +6,18 @@ set -e
 # Output directory
 mkdir -p dist
 
+# Build information, see pkg/version
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo unknown)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+PKG=github.com/ashton2914/mcp-netutil/pkg/version
+LDFLAGS="-X ${PKG}.Version=${VERSION} -X ${PKG}.Commit=${COMMIT} -X ${PKG}.BuildDate=${BUILD_DATE}"
+
 echo "=== Building for Linux ==="
 echo "Building for Linux/amd64..."
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o dist/mcp-netutil-linux-amd64 .
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags "$LDFLAGS" -o dist/mcp-netutil-linux-amd64 .
 echo "Building for Linux/arm64..."
-CGO_ENABLED=0 GOOS=linux GOARCH=arm64 go build -o dist/mcp-netutil-linux-arm64 .
+CGO_ENABLED=0 GOOS=linux GOARCH=arm64 go build -ldflags "$LDFLAGS" -o dist/mcp-netutil-linux-arm64 .
 
 echo "Build complete. Artifacts in dist/"
 ls -lh dist/
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileConfig is the JSON file loaded with -config.
+// Every field is optional and flags given on the command line take precedence.
+type fileConfig struct {
+	Address        string   `json:"address"`             // -a
+	Port           int      `json:"port"`                // -p
+	APIKey         string   `json:"api_key"`             // -o
+	APIKeyFile     string   `json:"api_key_file"`        // -api-key-file
+	APIKeyInPath   *bool    `json:"api_key_in_path"`     // -api-key-in-path
+	CacheDir       string   `json:"cache_dir"`           // -D
+	Timeout        string   `json:"timeout"`             // -timeout, e.g. "45s"
+	DialTimeout    string   `json:"dial_timeout"`        // -dial-timeout
+	DNSTimeout     string   `json:"dns_timeout"`         // -dns-timeout
+	MaxConcurrent  *int     `json:"max_concurrent"`      // -max-concurrent, 0 disables
+	ConcurrentWait string   `json:"max_concurrent_wait"` // -max-concurrent-wait
+	ResultCacheTTL string   `json:"result_cache_ttl"`    // -result-cache-ttl
+	MaxResultSize  *int     `json:"max_result_size"`     // -max-result-size, 0 disables
+	LogLevel       string   `json:"log_level"`           // -log-level
+	LogFormat      string   `json:"log_format"`          // -log-format
+	MaxMessageSize int      `json:"max_message_size"`    // -max_message_size
+	CORSOrigins    []string `json:"cors_origins"`        // -cors
+	Metrics        *bool    `json:"metrics"`             // -metrics
+	AllowNonroot   *bool    `json:"allow_nonroot"`       // -allow-nonroot
+	OUIFile        string   `json:"oui_file"`            // -oui-file
+	EnableTools    []string `json:"enable_tools"`        // -enable-tools
+	DisableTools   []string `json:"disable_tools"`       // -disable-tools
+	ConfirmTools   []string `json:"confirm_tools"`       // -confirm-tools
+	RedactPatterns []string `json:"redact_patterns"`     // -redact-pattern, one setting per pattern
+}
+
+// configSetting is a single config file value destined for a flag
+type configSetting struct {
+	field, flag, value string
+}
+
+// loadConfig reads and validates a config file
+func loadConfig(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg fileConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("field %q: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validate checks field values that JSON decoding alone does not catch
+func (c *fileConfig) validate() error {
+	if c.Port != 0 && (c.Port < 1 || c.Port > 65535) {
+		return fmt.Errorf("field \"port\": %d is not between 1 and 65535", c.Port)
+	}
+	if c.APIKey != "" && !isValidAPIKey(c.APIKey) {
+		return fmt.Errorf("field \"api_key\": must start with 'sk-netutil-' followed by 32 characters")
+	}
+	for _, f := range []struct{ name, value string }{{"timeout", c.Timeout}, {"dial_timeout", c.DialTimeout}, {"dns_timeout", c.DNSTimeout}, {"max_concurrent_wait", c.ConcurrentWait}, {"result_cache_ttl", c.ResultCacheTTL}} {
+		if f.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(f.value); err != nil || d < 0 {
+			return fmt.Errorf("field \"%s\": invalid duration '%s', use e.g. \"30s\" or \"2m\"", f.name, f.value)
+		}
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("field \"log_level\": %w", err)
+	}
+	if err := validateLogFormat(c.LogFormat); err != nil {
+		return fmt.Errorf("field \"log_format\": %w", err)
+	}
+	if c.MaxConcurrent != nil && *c.MaxConcurrent < 0 {
+		return fmt.Errorf("field \"max_concurrent\": must not be negative")
+	}
+	if c.MaxResultSize != nil && *c.MaxResultSize < 0 {
+		return fmt.Errorf("field \"max_result_size\": must not be negative")
+	}
+	if c.MaxMessageSize < 0 {
+		return fmt.Errorf("field \"max_message_size\": must not be negative")
+	}
+	return nil
+}
+
+// settings lists the values present in the file with the flag each one feeds
+func (c *fileConfig) settings() []configSetting {
+	var s []configSetting
+	add := func(field, flag, value string) {
+		if value != "" {
+			s = append(s, configSetting{field, flag, value})
+		}
+	}
+	add("address", "a", c.Address)
+	if c.Port != 0 {
+		add("port", "p", strconv.Itoa(c.Port))
+	}
+	add("api_key", "o", c.APIKey)
+	add("api_key_file", "api-key-file", c.APIKeyFile)
+	if c.APIKeyInPath != nil {
+		add("api_key_in_path", "api-key-in-path", strconv.FormatBool(*c.APIKeyInPath))
+	}
+	add("cache_dir", "D", c.CacheDir)
+	add("timeout", "timeout", c.Timeout)
+	add("dial_timeout", "dial-timeout", c.DialTimeout)
+	add("dns_timeout", "dns-timeout", c.DNSTimeout)
+	if c.MaxConcurrent != nil {
+		add("max_concurrent", "max-concurrent", strconv.Itoa(*c.MaxConcurrent))
+	}
+	add("max_concurrent_wait", "max-concurrent-wait", c.ConcurrentWait)
+	add("result_cache_ttl", "result-cache-ttl", c.ResultCacheTTL)
+	if c.MaxResultSize != nil {
+		add("max_result_size", "max-result-size", strconv.Itoa(*c.MaxResultSize))
+	}
+	add("log_level", "log-level", c.LogLevel)
+	add("log_format", "log-format", c.LogFormat)
+	if c.MaxMessageSize != 0 {
+		add("max_message_size", "max_message_size", strconv.Itoa(c.MaxMessageSize))
+	}
+	add("cors_origins", "cors", strings.Join(c.CORSOrigins, ","))
+	if c.Metrics != nil {
+		add("metrics", "metrics", strconv.FormatBool(*c.Metrics))
+	}
+	if c.AllowNonroot != nil {
+		add("allow_nonroot", "allow-nonroot", strconv.FormatBool(*c.AllowNonroot))
+	}
+	add("oui_file", "oui-file", c.OUIFile)
+	add("enable_tools", "enable-tools", strings.Join(c.EnableTools, ","))
+	add("disable_tools", "disable-tools", strings.Join(c.DisableTools, ","))
+	add("confirm_tools", "confirm-tools", strings.Join(c.ConfirmTools, ","))
+	// Patterns may contain commas, so each one is set separately
+	for _, p := range c.RedactPatterns {
+		add("redact_patterns", "redact-pattern", p)
+	}
+	return s
+}
+
+// envFlags maps NETUTIL_* environment variables to the flag each one feeds
+var envFlags = []struct{ env, flag string }{
+	{"NETUTIL_ADDR", "a"},
+	{"NETUTIL_PORT", "p"},
+	{"NETUTIL_API_KEY", "o"},
+	{"NETUTIL_API_KEY_FILE", "api-key-file"},
+	{"NETUTIL_CACHE_DIR", "D"},
+	{"NETUTIL_VERBOSE", "v"},
+}
+
+// applyEnv sets flags not given on the command line from the environment.
+// It runs before the config file is applied, so the precedence is flag > env > config file > default.
+func applyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	explicit := setFlags(fs)
+
+	for _, e := range envFlags {
+		value, ok := lookup(e.env)
+		if !ok || value == "" || explicit[e.flag] {
+			continue
+		}
+		if err := fs.Set(e.flag, value); err != nil {
+			return fmt.Errorf("%s: %w", e.env, err)
+		}
+	}
+	return nil
+}
+
+// linkedFlags are alternative ways to give one setting, setting either counts as setting both
+// so a lower-priority source cannot add the other one and make startup fail with a conflict
+var linkedFlags = map[string]string{
+	"o":            "api-key-file",
+	"api-key-file": "o",
+}
+
+// setFlags returns the flags already set, by the command line or an earlier source, with their linked flags
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		if other, ok := linkedFlags[f.Name]; ok {
+			set[other] = true
+		}
+	})
+	return set
+}
+
+// patternList is a repeatable flag of regular expressions
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *patternList) Set(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", value, err)
+	}
+	*p = append(*p, value)
+	return nil
+}
+
+// apply sets every flag that was not given explicitly on the command line from the file
+func (c *fileConfig) apply(fs *flag.FlagSet) error {
+	explicit := setFlags(fs)
+
+	for _, s := range c.settings() {
+		if explicit[s.flag] {
+			continue
+		}
+		if err := fs.Set(s.flag, s.value); err != nil {
+			return fmt.Errorf("field %q: %w", s.field, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testFlagSet defines the flags the precedence test sets, with the same names as main
+func testFlagSet() (*flag.FlagSet, map[string]*string) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	values := map[string]*string{
+		"a": fs.String("a", "", ""),
+		"p": fs.String("p", "", ""),
+		"D": fs.String("D", "default-cache", ""),
+		"o": fs.String("o", "", ""),
+	}
+	fs.String("api-key-file", "", "")
+	fs.Bool("v", false, "")
+	return fs, values
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	fs, values := testFlagSet()
+	// -a comes from the command line, -p from the environment and the file, -o only from the file
+	if err := fs.Parse([]string{"-a", "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"NETUTIL_ADDR": "10.0.0.2", "NETUTIL_PORT": "8080", "NETUTIL_VERBOSE": ""}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	if err := applyEnv(fs, lookup); err != nil {
+		t.Fatalf("applyEnv() error = %v", err)
+	}
+
+	cfg := &fileConfig{Address: "10.0.0.3", Port: 9090, APIKey: testKey}
+	if err := cfg.apply(fs); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+
+	want := map[string]string{
+		"a": "10.0.0.1",      // flag beats env and file
+		"p": "8080",          // env beats file
+		"o": testKey,         // file fills what neither set
+		"D": "default-cache", // default stays when nothing sets it
+	}
+	for name, w := range want {
+		if got := *values[name]; got != w {
+			t.Errorf("-%s = %q, want %q", name, got, w)
+		}
+	}
+	if v := fs.Lookup("v").Value.String(); v != "false" {
+		t.Errorf("-v = %s, want an empty variable ignored", v)
+	}
+}
+
+func TestConfigPrecedenceAPIKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		wantKey  string
+		wantFile string
+	}{
+		{name: "env key beats file key file", env: map[string]string{"NETUTIL_API_KEY": testKey}, wantKey: testKey},
+		{name: "flag key beats env key file", args: []string{"-o", testKey}, env: map[string]string{"NETUTIL_API_KEY_FILE": "/env/key"}, wantKey: testKey},
+		{name: "flag key file beats env key", args: []string{"-api-key-file", "/flag/key"}, env: map[string]string{"NETUTIL_API_KEY": testKey}, wantFile: "/flag/key"},
+		{name: "file key file when nothing else", wantFile: "/file/key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, values := testFlagSet()
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			lookup := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			if err := applyEnv(fs, lookup); err != nil {
+				t.Fatalf("applyEnv() error = %v", err)
+			}
+			cfg := &fileConfig{APIKeyFile: "/file/key"}
+			if err := cfg.apply(fs); err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if got := *values["o"]; got != tt.wantKey {
+				t.Errorf("-o = %q, want %q", got, tt.wantKey)
+			}
+			if got := fs.Lookup("api-key-file").Value.String(); got != tt.wantFile {
+				t.Errorf("-api-key-file = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	fs, _ := testFlagSet()
+	lookup := func(key string) (string, bool) {
+		if key == "NETUTIL_VERBOSE" {
+			return "maybe", true
+		}
+		return "", false
+	}
+	if err := applyEnv(fs, lookup); err == nil {
+		t.Error("applyEnv() accepted NETUTIL_VERBOSE=maybe")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `{"address":"127.0.0.1","port":8080,"timeout":"45s","max_concurrent":0,"cors_origins":["https://a.example"]}`},
+		{name: "unknown field", content: `{"adress":"127.0.0.1"}`, wantErr: `json: unknown field "adress"`},
+		{name: "wrong type", content: `{"port":"8080"}`, wantErr: `field "port": expected int, got string`},
+		{name: "port out of range", content: `{"port":70000}`, wantErr: `field "port": 70000 is not between 1 and 65535`},
+		{name: "bad duration", content: `{"dial_timeout":"5"}`, wantErr: `field "dial_timeout": invalid duration '5', use e.g. "30s" or "2m"`},
+		{name: "bad api key", content: `{"api_key":"secret"}`, wantErr: `field "api_key": must start with 'sk-netutil-' followed by 32 characters`},
+		{name: "negative limit", content: `{"max_result_size":-1}`, wantErr: `field "max_result_size": must not be negative`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("loadConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("loadConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loadConfig() accepted a missing file")
+	}
+}
+
+func TestConfigSettings(t *testing.T) {
+	zero, off := 0, false
+	cfg := &fileConfig{
+		Port:           8080,
+		MaxConcurrent:  &zero,
+		Metrics:        &off,
+		EnableTools:    []string{"latency", "traceroute"},
+		RedactPatterns: []string{`token=\w+`, `a,b`},
+	}
+	want := []configSetting{
+		{"port", "p", "8080"},
+		{"max_concurrent", "max-concurrent", "0"},
+		{"metrics", "metrics", "false"},
+		{"enable_tools", "enable-tools", "latency,traceroute"},
+		{"redact_patterns", "redact-pattern", `token=\w+`},
+		{"redact_patterns", "redact-pattern", "a,b"},
+	}
+	if got := cfg.settings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("settings() = %v, want %v", got, want)
+	}
+}
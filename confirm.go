@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// confirmSummaries describe what a call to a destructive tool will do, for -confirm-tools
+var confirmSummaries = map[string]mcp.ConfirmationSummary{
+	"pkill": func(args map[string]interface{}) (string, bool) {
+		pid, _ := args["pid"].(float64)
+		summary := fmt.Sprintf("Send %s to PID %d", signalLabel(args), int64(pid))
+		if graceful, _ := args["graceful"].(bool); graceful {
+			summary += ", then SIGKILL if it has not exited after the timeout"
+		}
+		return summary, true
+	},
+	"pkill_by_name": func(args map[string]interface{}) (string, bool) {
+		if dryRun, _ := args["dry_run"].(bool); dryRun {
+			return "", false
+		}
+		name, _ := args["name"].(string)
+		return fmt.Sprintf("Send %s to every process named '%s'", signalLabel(args), name), true
+	},
+	"manage_service": func(args map[string]interface{}) (string, bool) {
+		action, _ := args["action"].(string)
+		unit, _ := args["unit"].(string)
+		switch strings.ToLower(action) {
+		case "stop", "restart", "disable", "mask":
+			return fmt.Sprintf("Run systemctl %s on '%s'", strings.ToLower(action), unit), true
+		}
+		return "", false
+	},
+}
+
+// signalLabel names the signal argument of the pkill tools
+func signalLabel(args map[string]interface{}) string {
+	sig, _ := args["signal"].(string)
+	if sig == "" {
+		sig = "term"
+	}
+	return "SIG" + strings.ToUpper(strings.TrimPrefix(strings.ToLower(sig), "sig"))
+}
+
+// summarizeCall is the summary for tools without a dedicated one
+func summarizeCall(name string) mcp.ConfirmationSummary {
+	return func(args map[string]interface{}) (string, bool) {
+		argsJSON, _ := json.Marshal(args)
+		return fmt.Sprintf("Run %s with arguments %s", name, argsJSON), true
+	}
+}
+
+// requireConfirmation guards the tools listed in -confirm-tools with a confirmation token
+func requireConfirmation(server *mcp.Server, list string) error {
+	var unknown []string
+	for name := range parseToolList(list) {
+		if !selectedTools.known[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		summarize, ok := confirmSummaries[name]
+		if !ok {
+			summarize = summarizeCall(name)
+		}
+		server.RequireConfirmation(name, summarize)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown tool names: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsPolicy holds the origins allowed to call the HTTP endpoints from a browser
+type corsPolicy struct {
+	origins  map[string]bool
+	wildcard bool
+}
+
+// parseCORSOrigins builds a policy from a comma-separated origin list.
+// An empty list disables CORS headers entirely; "*" must be given explicitly to allow any origin.
+func parseCORSOrigins(list string) corsPolicy {
+	policy := corsPolicy{origins: make(map[string]bool)}
+	for _, origin := range strings.Split(list, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			policy.wildcard = true
+			continue
+		}
+		policy.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	return policy
+}
+
+// allowedOrigin returns the value for Access-Control-Allow-Origin, or "" if origin is not allowed
+func (c corsPolicy) allowedOrigin(origin string) string {
+	if c.wildcard {
+		return "*"
+	}
+	if origin != "" && c.origins[origin] {
+		return origin
+	}
+	return ""
+}
+
+// wrap adds CORS headers to responses and answers OPTIONS preflight requests
+func (c corsPolicy) wrap(methods string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allow := c.allowedOrigin(origin)
+		if allow != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allow)
+			// Browser clients need to read the session ID issued by /mcp
+			w.Header().Set("Access-Control-Expose-Headers", sessionHeader)
+			if allow != "*" {
+				w.Header().Add("Vary", "Origin")
+			}
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allow == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", methods+", OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, X-API-Key, "+sessionHeader)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next(w, r)
+	}
+}
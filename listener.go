@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// unixAddrPrefix selects a Unix domain socket listener, e.g. -a unix:/run/mcp-netutil.sock
+const unixAddrPrefix = "unix:"
+
+// isUnixAddr reports whether addr names a Unix domain socket
+func isUnixAddr(addr string) bool {
+	return strings.HasPrefix(addr, unixAddrPrefix)
+}
+
+// listen opens the HTTP listener, either TCP on addr:port or a Unix socket for "unix:<path>".
+// The returned cleanup closes the listener and removes the socket file.
+func listen(addr, port string) (net.Listener, func(), error) {
+	if !isUnixAddr(addr) {
+		ln, err := net.Listen("tcp", net.JoinHostPort(addr, port))
+		if err != nil {
+			return nil, nil, err
+		}
+		return ln, func() { ln.Close() }, nil
+	}
+
+	path := strings.TrimPrefix(addr, unixAddrPrefix)
+	if path == "" {
+		return nil, nil, fmt.Errorf("unix socket path cannot be empty")
+	}
+
+	// A socket left behind by a crashed run would make Listen fail with "address already in use".
+	// Only sockets are removed so a mistyped path never deletes a regular file.
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := listenPrivate(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		ln.Close()
+		os.Remove(path)
+	}
+	return ln, cleanup, nil
+}
+
+// listenPrivate creates the socket with mode 0600 before it becomes reachable at path.
+// Listen creates the file with the process umask, so it is bound inside a fresh 0700 directory
+// next to path, restricted there and then renamed into place; nobody else can connect in between.
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// Only processes running as the same user (or root) may connect
+	if err := os.Chmod(tmp, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to create socket %s: %w", path, err)
+	}
+	// The listener would unlink its original name on Close, the caller removes path instead
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	return ln, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "netutil.sock")
+
+	ln, cleanup, err := listen(unixAddrPrefix+path, "")
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the socket", len(entries))
+	}
+
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial socket: %v", err)
+	}
+	conn.Close()
+
+	cleanup()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after cleanup: %v", err)
+	}
+}
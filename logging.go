@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// newLogger builds the process logger from the -log-level and -log-format flags.
+// Logs always go to stderr since stdout carries the stdio transport.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateLogFormat(format); err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	if strings.ToLower(format) == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
+
+// parseLogLevel maps a -log-level value to a slog level, "" means info
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level '%s'. Allowed levels: debug, info, warn, error", level)
+	}
+}
+
+// validateLogFormat checks a -log-format value, "" means text
+func validateLogFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("invalid log format '%s'. Allowed formats: text, json", format)
+	}
+}
+
+// fatal logs msg at error level and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -4,225 +4,1083 @@ import (
 	"bufio"
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/ashton2914/mcp-netutil/pkg/bandwidth"
 	mcp_cache "github.com/ashton2914/mcp-netutil/pkg/cache"
 	"github.com/ashton2914/mcp-netutil/pkg/diagnostics"
+	"github.com/ashton2914/mcp-netutil/pkg/dns"
+	"github.com/ashton2914/mcp-netutil/pkg/health"
+	"github.com/ashton2914/mcp-netutil/pkg/httpcheck"
 	"github.com/ashton2914/mcp-netutil/pkg/latency"
 	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+	"github.com/ashton2914/mcp-netutil/pkg/oui"
 	"github.com/ashton2914/mcp-netutil/pkg/port"
+	"github.com/ashton2914/mcp-netutil/pkg/route"
 	"github.com/ashton2914/mcp-netutil/pkg/system"
 	"github.com/ashton2914/mcp-netutil/pkg/systemd"
 	"github.com/ashton2914/mcp-netutil/pkg/traceroute"
+	"github.com/ashton2914/mcp-netutil/pkg/version"
 )
 
 func main() {
-	// 1. Platform Check
-	if runtime.GOOS != "linux" {
-		log.Fatal("This program only supports Linux systems.")
-	}
-
-	// 2. Parse Flags
-	addr := flag.String("a", "", "listen address")
+	// 1. Parse Flags
+	addr := flag.String("a", "", "listen address, or unix:/path/to.sock for a Unix domain socket")
 	p := flag.String("p", "", "listen port")
 	cacheDir := flag.String("D", "", "Enable cache and define cache directory")
-	verbose := flag.Bool("v", false, "Enable verbose logging")
-	apiKey := flag.String("o", "", "Set API key for authentication")
+	verbose := flag.Bool("v", false, "Enable verbose logging (same as -log-level debug)")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn, error")
+	logFormat := flag.String("log-format", "text", "Log format: text, json")
+	apiKey := flag.String("o", "", "Set API key for authentication (visible to local users in ps, prefer -api-key-file or NETUTIL_API_KEY)")
+	apiKeyFile := flag.String("api-key-file", "", "Read the API key from a file, or from stdin with \"-\", so it does not appear in the process arguments")
+	apiKeyInPath := flag.Bool("api-key-in-path", false, "Legacy: expect the API key in the endpoint path (/sse/<key>, /mcp/<key>) instead of the Authorization or X-API-Key header")
 	genKey := flag.Bool("generate_key", false, "Generate a standard API key")
+	maxMessage := flag.Int("max_message_size", 10*1024*1024, "Maximum size in bytes of a single stdio JSON-RPC message")
+	corsOrigins := flag.String("cors", "", "Comma-separated list of origins allowed to call the HTTP endpoints (\"*\" allows any)")
+	allowNonroot := flag.Bool("allow-nonroot", false, "Allow starting without root; tools that need root return an error")
+	enableMetrics := flag.Bool("metrics", false, "Expose Prometheus metrics on /metrics (HTTP transport only)")
+	timeout := flag.Duration("timeout", mcp.DefaultToolTimeout, "Maximum duration of a single tool call (0 disables)")
+	dialTimeout := flag.Duration("dial-timeout", netdial.DefaultDialTimeout, "Maximum duration of establishing a TCP connection (bandwidth_test, http_check, DNS servers)")
+	dnsTimeout := flag.Duration("dns-timeout", netdial.DefaultLookupTimeout, "Maximum duration of a single DNS lookup of dns_compare and net_health")
+	maxConcurrent := flag.Int("max-concurrent", mcp.DefaultMaxConcurrent, "Maximum number of expensive tool calls (system_stats, traceroute, diagnostics, monitors, ...) running at once (0 disables)")
+	maxConcurrentWait := flag.Duration("max-concurrent-wait", mcp.DefaultConcurrencyWait, "How long an expensive tool call waits for a free slot before returning a server busy error (0 rejects immediately)")
+	resultCacheTTL := flag.Duration("result-cache-ttl", 0, "Reuse the result of an identical read-only tool call made within this duration, e.g. 3s (0 disables)")
+	maxResultSize := flag.Int("max-result-size", mcp.DefaultMaxResultSize, "Maximum size in bytes of a tool result returned or cached, longer output is truncated (0 disables)")
+	enableTools := flag.String("enable-tools", "", "Comma-separated list of the only tools to expose (default all)")
+	disableTools := flag.String("disable-tools", "", "Comma-separated list of tools to hide, e.g. pkill,pkill_by_name,manage_service for a read-only instance")
+	confirmTools := flag.String("confirm-tools", "", "Comma-separated list of tools that only run after the caller echoes back a confirmation token, e.g. pkill,pkill_by_name,manage_service")
+	ouiFile := flag.String("oui-file", "", "Load an IEEE OUI dataset (oui.txt or oui.csv) to name the vendor of every MAC address")
+	var redactPatterns patternList
+	flag.Var(&redactPatterns, "redact-pattern", "Regular expression of secrets to redact from cached output in addition to the built-in ones (repeatable)")
+	configPath := flag.String("config", "", "Load settings from a JSON config file (command-line flags take precedence)")
 	flag.Parse()
+	keyInArgs := false
+	flag.Visit(func(f *flag.Flag) {
+		keyInArgs = keyInArgs || f.Name == "o"
+	})
+
+	// 1.1 NETUTIL_* environment variables fill in flags not given on the command line
+	if err := applyEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		fatal("Invalid environment variable", "error", err)
+	}
+
+	// 1.2 Load the config file, flags given explicitly or through the environment win over its values
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err == nil {
+			err = cfg.apply(flag.CommandLine)
+		}
+		if err != nil {
+			fatal("Invalid config file", "path", *configPath, "error", err)
+		}
+	}
 
 	// 2.0 Handle Key Generation
 	if *genKey {
 		key, err := generateAPIKey()
 		if err != nil {
-			log.Fatalf("Failed to generate key: %v", err)
+			fatal("Failed to generate key", "error", err)
 		}
 		fmt.Println(key)
 		os.Exit(0)
 	}
 
-	// 3. Privilege Check (Required for actual operation)
-	if os.Geteuid() != 0 {
-		log.Fatal("This program must be run as root.")
+	// 2.0.1 Configure logging
+	level := *logLevel
+	if *verbose {
+		level = "debug"
+	}
+	logger, err := newLogger(os.Stderr, level, *logFormat)
+	if err != nil {
+		fatal("Invalid logging flags", "error", err)
+	}
+	slog.SetDefault(logger)
+
+	// 3. Privilege Check
+	// Most tools work unprivileged, the ones in rootTools report an error when called without root.
+	// Windows has no effective UID (Geteuid returns -1)
+	if runtime.GOOS != "windows" && !hasRootPrivileges() {
+		if !*allowNonroot {
+			fatal("This program must be run as root. Use -allow-nonroot to start with the unprivileged tool subset.")
+		}
+		slog.Warn("Running without root privileges, some tools will return an error", "uid", os.Geteuid())
 	}
 
-	// 2.0.1 Validate API Key if provided
+	// 2.0.2 Load and validate the API key if provided
+	if *apiKeyFile != "" {
+		if *apiKey != "" {
+			fatal("Set the API key with either -o or -api-key-file, not both")
+		}
+		if *apiKeyFile == "-" && !httpTransport(*addr, *p) {
+			fatal("-api-key-file - reads the key from stdin, which the stdio transport uses for messages")
+		}
+		key, err := readAPIKeyFile(*apiKeyFile)
+		if err != nil {
+			fatal("Failed to read API key file", "error", err)
+		}
+		*apiKey = key
+	} else if keyInArgs {
+		slog.Warn("The API key passed with -o is visible to every local user in the process list, use -api-key-file or NETUTIL_API_KEY instead")
+	}
 	if *apiKey != "" {
 		if !isValidAPIKey(*apiKey) {
-			log.Fatal("Invalid API key format. Must start with 'sk-netutil-' followed by 32 characters.")
+			fatal("Invalid API key format. Must start with 'sk-netutil-' followed by 32 characters.")
+		}
+	}
+
+	// 2.1 Initialize Cache if requested
+	if *cacheDir != "" {
+		if err := mcp_cache.SetRedactPatterns(redactPatterns); err != nil {
+			fatal("Invalid -redact-pattern", "error", err)
+		}
+		if err := mcp_cache.Init(*cacheDir); err != nil {
+			fatal("Failed to initialize cache", "dir", *cacheDir, "error", err)
+		}
+		slog.Info("Cache initialized", "dir", *cacheDir)
+	}
+
+	// 2.2 Load the full MAC vendor dataset, the built-in table only covers common vendors
+	if *ouiFile != "" {
+		n, err := oui.Load(*ouiFile)
+		if err != nil {
+			fatal("Failed to load OUI dataset", "path", *ouiFile, "error", err)
+		}
+		slog.Info("OUI dataset loaded", "path", *ouiFile, "prefixes", n)
+	}
+
+	// 3. Initialize Server
+	server := mcp.NewServer()
+	server.SetLogger(logger)
+	var metrics *mcp.Metrics
+	if *enableMetrics {
+		metrics = server.EnableMetrics()
+	}
+	server.SetToolTimeout(*timeout)
+	server.SetMaxResultSize(*maxResultSize)
+	netdial.SetTimeouts(*dialTimeout, *dnsTimeout)
+	server.SetMaxConcurrent(*maxConcurrent, *maxConcurrentWait)
+	for name := range expensiveTools {
+		server.LimitConcurrency(name)
+	}
+	server.SetResultCacheTTL(*resultCacheTTL)
+	for name := range cacheableTools {
+		server.CacheResults(name)
+	}
+	mcp_cache.SetMaxRecordSize(*maxResultSize)
+	if mcp_cache.Enabled() {
+		server.SetResourceProvider(cacheResources{})
+	}
+
+	// 4. Register Tools
+	selectedTools = newToolSelection(*enableTools, *disableTools)
+
+	// --- latency (ping) ---
+	registerTool(server, "latency", "Check network latency to a target", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"target": { "type": "string", "description": "Target IP or hostname (a URL or host:port is reduced to the host)" },
+			"mode": { "type": "string", "description": "quick (10 pkts) or standard (100 pkts)" },
+			"source": { "type": "string", "description": "Send the pings from this interface (e.g. eth1) or local IP instead of the default route" }
+		},
+		"required": ["target", "mode"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		target, _ := args["target"].(string)
+		mode, _ := args["mode"].(string)
+		source, _ := args["source"].(string)
+
+		res, err := latency.Run(ctx, target, mode, source)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.Marshal(res)
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("latency", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- latency_multi ---
+	registerTool(server, "latency_multi", "Check network latency to several targets at once (e.g. gateway, DNS server, app server), pinged concurrently so the results are correlated in time", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"targets": { "type": "array", "items": { "type": "string" }, "description": "Target IPs or hostnames (max 20)" },
+			"mode": { "type": "string", "description": "quick (10 pkts) or standard (100 pkts)" },
+			"source": { "type": "string", "description": "Send the pings from this interface (e.g. eth1) or local IP instead of the default route" }
+		},
+		"required": ["targets", "mode"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		var targets []string
+		if list, ok := args["targets"].([]interface{}); ok {
+			for _, v := range list {
+				if t, ok := v.(string); ok {
+					targets = append(targets, t)
+				}
+			}
+		}
+		mode, _ := args["mode"].(string)
+		source, _ := args["source"].(string)
+
+		res, err := latency.RunMulti(ctx, targets, mode, source)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		// Record each target like a latency call, so latency_summary covers them
+		for _, tr := range res {
+			if tr.Result != nil {
+				jsonBytes, _ := json.Marshal(tr.Result)
+				_ = mcp_cache.SaveRecord("latency", string(jsonBytes))
+			}
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- latency_monitor ---
+	registerTool(server, "latency_monitor", "Ping a target at a fixed interval and report a latency time series with threshold alerts", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"target": { "type": "string", "description": "Target IP or hostname (a URL or host:port is reduced to the host)" },
+			"interval": { "type": "integer", "description": "Seconds between samples (default 5)" },
+			"duration": { "type": "integer", "description": "Total monitoring time in seconds (default 20, must fit within the server tool timeout)" },
+			"loss_threshold": { "type": "number", "description": "Flag samples with packet loss above this percentage (0 disables)" },
+			"rtt_threshold": { "type": "number", "description": "Flag samples with average RTT above this many milliseconds (0 disables)" }
+		},
+		"required": ["target"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		target, _ := args["target"].(string)
+		interval := 5 * time.Second
+		if v, ok := args["interval"].(float64); ok {
+			interval = time.Duration(v) * time.Second
+		}
+		duration := 20 * time.Second
+		if v, ok := args["duration"].(float64); ok {
+			duration = time.Duration(v) * time.Second
+		}
+		lossThreshold, _ := args["loss_threshold"].(float64)
+		rttThreshold, _ := args["rtt_threshold"].(float64)
+
+		// Leave headroom for the final sample so the run completes before the tool timeout
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(duration+5*time.Second).After(deadline) {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("duration %s does not fit within the tool timeout, use a shorter duration or raise -timeout", duration)}}}, nil
+		}
+
+		res, err := latency.Monitor(ctx, target, interval, duration, lossThreshold, rttThreshold)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.Marshal(res)
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("latency_monitor", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- path_mtu ---
+	registerTool(server, "path_mtu", "Discover the path MTU to a network target (largest unfragmented packet)", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"target": { "type": "string", "description": "Target IP or hostname (a URL or host:port is reduced to the host)" }
+		},
+		"required": ["target"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		target, _ := args["target"].(string)
+
+		res, err := latency.DiscoverMTU(ctx, target)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.Marshal(res)
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("path_mtu", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- net_health ---
+	registerTool(server, "net_health", "Quick \"is the network up\" check: pings the default gateway and a public anchor and resolves a known name, returning healthy, degraded or down", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"anchor": { "type": "string", "description": "Public IP or hostname to ping (default 1.1.1.1)" },
+			"dns_name": { "type": "string", "description": "Name to resolve with the system resolver (default one.one.one.one)" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		opts := health.Options{}
+		opts.Anchor, _ = args["anchor"].(string)
+		opts.DNSName, _ = args["dns_name"].(string)
+
+		res, err := health.Check(ctx, opts)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("net_health", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- traceroute ---
+	registerTool(server, "traceroute", "Trace path to a network target, optionally naming each hop's owner", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"target": { "type": "string", "description": "Target IP or hostname (a URL or host:port is reduced to the host)" },
+			"enrich": { "type": "boolean", "description": "Return structured hops with reverse DNS names (default false, returns the raw output)" },
+			"asn": { "type": "boolean", "description": "Also look up each hop's origin ASN and AS name via Team Cymru DNS (implies enrich)" }
+		},
+		"required": ["target"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		target, _ := args["target"].(string)
+		enrich, _ := args["enrich"].(bool)
+		withASN, _ := args["asn"].(bool)
+
+		res, err := traceroute.Run(ctx, target)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		if enrich || withASN {
+			hops := traceroute.ParseHops(res)
+			traceroute.Enrich(ctx, hops, traceroute.EnrichOptions{ReverseDNS: true, ASN: withASN})
+			jsonBytes, _ := json.MarshalIndent(map[string]interface{}{"target": target, "hops": hops}, "", "  ")
+			res = string(jsonBytes)
+		}
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("traceroute", res)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: res}}}, nil
+	})
+
+	// --- bandwidth_test ---
+	registerTool(server, "bandwidth_test", "Measure TCP throughput to an iperf3 server in Mbps. Without a local iperf3 it streams plain TCP, which needs a TCP sink (up) or source (down) such as nc instead of an iperf3 server", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"host": { "type": "string", "description": "iperf3 server IP or hostname" },
+			"port": { "type": "integer", "description": "Server port (default 5201)" },
+			"duration": { "type": "integer", "description": "Test duration in seconds (default 10, max 60, must fit within the server tool timeout)" },
+			"direction": { "type": "string", "enum": ["up", "down"], "description": "up sends to the server, down receives from it (default up)" }
+		},
+		"required": ["host"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		opts := bandwidth.Options{Duration: bandwidth.DefaultDuration}
+		opts.Host, _ = args["host"].(string)
+		opts.Direction, _ = args["direction"].(string)
+		if v, ok := args["port"].(float64); ok {
+			opts.Port = int(v)
+		}
+		if v, ok := args["duration"].(float64); ok {
+			opts.Duration = time.Duration(v) * time.Second
+		}
+
+		// Leave headroom for connection setup so the run completes before the tool timeout
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(opts.Duration+5*time.Second).After(deadline) {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("duration %s does not fit within the tool timeout, use a shorter duration or raise -timeout", opts.Duration)}}}, nil
+		}
+
+		res, err := bandwidth.Run(ctx, opts)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("bandwidth_test", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- http_check ---
+	registerTool(server, "http_check", "Probe an HTTP(S) endpoint: status, DNS/connect/TLS/TTFB timings, redirects and certificate expiry", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"url": { "type": "string", "description": "http:// or https:// URL to check, a bare host or host:port uses https (http for port 80)" },
+			"method": { "type": "string", "enum": ["GET", "HEAD", "OPTIONS"], "description": "HTTP method (default GET)" },
+			"expected_status": { "type": "integer", "description": "Status code that counts as healthy (default any 2xx/3xx). A 3xx code is matched against the first response without following the redirect" },
+			"timeout": { "type": "integer", "description": "Timeout in seconds (default 10, max 60)" }
+		},
+		"required": ["url"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		var opts httpcheck.Options
+		opts.URL, _ = args["url"].(string)
+		opts.Method, _ = args["method"].(string)
+		if v, ok := args["expected_status"].(float64); ok {
+			opts.ExpectedStatus = int(v)
+		}
+		if v, ok := args["timeout"].(float64); ok {
+			opts.Timeout = time.Duration(v) * time.Second
+		}
+
+		res, err := httpcheck.Check(ctx, opts)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("http_check", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- dns_compare ---
+	registerTool(server, "dns_compare", "Resolve a name against several DNS resolvers and report whether their answers agree (propagation check)", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": { "type": "string", "description": "Domain name to resolve" },
+			"type": { "type": "string", "enum": ["A", "AAAA", "CNAME", "MX", "TXT", "NS"], "description": "Record type (default A)" },
+			"resolvers": { "type": "array", "items": { "type": "string" }, "description": "Resolver IPs (optionally IP:port) or \"system\" (default 8.8.8.8, 1.1.1.1, 9.9.9.9, system)" }
+		},
+		"required": ["name"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		name, _ := args["name"].(string)
+		recordType, _ := args["type"].(string)
+		var resolvers []string
+		if list, ok := args["resolvers"].([]interface{}); ok {
+			for _, v := range list {
+				if r, ok := v.(string); ok {
+					resolvers = append(resolvers, r)
+				}
+			}
+		}
+
+		res, err := dns.Compare(ctx, name, recordType, resolvers)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("dns_compare", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- routes ---
+	registerTool(server, "routes", "Show the IPv4 and IPv6 kernel routing tables", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := route.GetRoutes()
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- neighbors ---
+	registerTool(server, "neighbors", "Show the ARP (IPv4) and NDP (IPv6) neighbor tables", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := route.GetNeighbors(ctx)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- system_stats ---
+	registerTool(server, "system_stats", "Get system statistics", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"top_n": { "type": "integer", "description": "Number of top CPU/memory processes to list (1-100, default 10)" },
+			"sort_by": { "type": "string", "description": "Return a single process list sorted by cpu, mem, rss or name instead of the top CPU and top memory lists" },
+			"name_filter": { "type": "string", "description": "Only list processes whose name contains this (case-insensitive), e.g. nginx" },
+			"include_kernel_threads": { "type": "boolean", "description": "Also rank kernel threads and the server's own process (default false)" },
+			"disk_path": { "type": "string", "description": "Mountpoint to report disk usage for, e.g. /var (default / or C:\\ on Windows)" },
+			"interfaces": { "type": "array", "items": { "type": "string" }, "description": "Only report interfaces matching these glob patterns, e.g. [\"eth*\", \"en*\", \"bond*\"]" },
+			"exclude_interfaces": { "type": "array", "items": { "type": "string" }, "description": "Leave out interfaces matching these glob patterns, e.g. [\"veth*\", \"cni*\"]" },
+			"include_loopback": { "type": "boolean", "description": "Also report loopback interfaces (default false)" }
+		},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		procOpts := system.ProcessOptions{TopN: system.DefaultTopN}
+		if n, ok := args["top_n"].(float64); ok {
+			procOpts.TopN = int(n)
+			if procOpts.TopN < 1 || procOpts.TopN > system.MaxTopN {
+				return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("top_n must be between 1 and %d", system.MaxTopN)}}}, nil
+			}
+		}
+		procOpts.SortBy, _ = args["sort_by"].(string)
+		procOpts.NameFilter, _ = args["name_filter"].(string)
+		procOpts.IncludeKernelThreads, _ = args["include_kernel_threads"].(bool)
+		diskPath, _ := args["disk_path"].(string)
+
+		res, err := system.GetStats(ctx, procOpts, diskPath, networkFilterArgs(args))
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+		// Record to cache
+		_ = mcp_cache.SaveRecord("system_stats", res)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: res}}}, nil
+	})
+
+	// --- network_usage ---
+	registerTool(server, "network_usage", "Per-interface throughput over a short window plus byte, error and drop counters, without the CPU/process scan of system_stats", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"duration_ms": { "type": "integer", "description": "Sample window in milliseconds (100-30000, default 1000)" },
+			"interfaces": { "type": "array", "items": { "type": "string" }, "description": "Only report interfaces matching these glob patterns, e.g. [\"eth*\", \"en*\", \"bond*\"]" },
+			"exclude_interfaces": { "type": "array", "items": { "type": "string" }, "description": "Leave out interfaces matching these glob patterns, e.g. [\"veth*\", \"cni*\"]" },
+			"include_loopback": { "type": "boolean", "description": "Also report loopback interfaces (default false)" }
+		},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		duration := system.DefaultNetworkSample
+		if v, ok := args["duration_ms"].(float64); ok {
+			duration = time.Duration(v) * time.Millisecond
+			if duration < system.MinNetworkSample || duration > system.MaxNetworkSample {
+				return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("duration_ms must be between %d and %d", system.MinNetworkSample.Milliseconds(), system.MaxNetworkSample.Milliseconds())}}}, nil
+			}
+		}
+
+		res, err := system.GetNetworkUsage(ctx, duration, networkFilterArgs(args))
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("failed to get network stats: %v", err)}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("network_usage", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- logged_in_users ---
+	registerTool(server, "logged_in_users", "List currently logged-in users (username, tty, remote host, login time)", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := system.WhoIsLoggedIn(ctx)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("logged_in_users", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- host_info ---
+	registerTool(server, "host_info", "Describe this machine: hostname, OS, distro and version, kernel version and architecture, virtualization and logical CPU count", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := system.GetHostInfo(ctx)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("host_info", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- sensors ---
+	registerTool(server, "sensors", "Read temperature sensors (current, high and critical thresholds in Celsius)", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := system.GetTemperatures(ctx)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+		if len(res) == 0 {
+			return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: "No temperature sensors available on this system."}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("sensors", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- network_interfaces ---
+	registerTool(server, "network_interfaces", "List network interfaces with MAC, MTU, state and IP addresses", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := system.GetInterfaces()
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- pkill ---
+	registerTool(server, "pkill", "Send a signal to a process by PID (default SIGTERM)", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"pid": { "type": "integer", "description": "Process ID to signal" },
+			"signal": { "type": "string", "description": "Signal to send: term (default), kill, int, hup" },
+			"graceful": { "type": "boolean", "description": "Escalate to SIGKILL if the process has not exited after timeout seconds (signal term only)" },
+			"timeout": { "type": "integer", "description": "Seconds to wait for the process to exit (default 5, max 60), hup returns right after delivery" }
+		},
+		"required": ["pid"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		pidFloat, ok := args["pid"].(float64) // JSON numbers are floats
+		if !ok {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: "invalid pid"}}}, nil
+		}
+		pid := int32(pidFloat)
+
+		sigName, _ := args["signal"].(string)
+		sig, err := system.ParseSignal(sigName)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+		graceful, _ := args["graceful"].(bool)
+		wait := 5 * time.Second
+		if t, ok := args["timeout"].(float64); ok && t > 0 {
+			wait = time.Duration(t) * time.Second
+		}
+
+		res, err := system.SignalAndWait(ctx, pid, sig, wait, graceful)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- pkill_by_name ---
+	registerTool(server, "pkill_by_name", "Send a signal to all processes with an exact name (requires dry_run or confirm)", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": { "type": "string", "description": "Exact process name to match" },
+			"signal": { "type": "string", "description": "Signal to send: term (default), kill, int, hup" },
+			"dry_run": { "type": "boolean", "description": "Only list matching PIDs without signalling them" },
+			"confirm": { "type": "boolean", "description": "Must be true to actually signal the matching processes" },
+			"pids": { "type": "array", "items": { "type": "integer" }, "description": "Only signal these PIDs from the dry_run matches, skipping any that no longer match the name" }
+		},
+		"required": ["name"]
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		name, _ := args["name"].(string)
+		dryRun, _ := args["dry_run"].(bool)
+		confirm, _ := args["confirm"].(bool)
+
+		sigName, _ := args["signal"].(string)
+		sig, err := system.ParseSignal(sigName)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		if !dryRun && !confirm {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: "Refusing to signal processes without confirm: true. Use dry_run: true to list matches first."}}}, nil
+		}
+
+		var pids []int32
+		if list, ok := args["pids"].([]interface{}); ok {
+			for _, v := range list {
+				if p, ok := v.(float64); ok {
+					pids = append(pids, int32(p))
+				}
+			}
+		}
+
+		res, err := system.KillProcessByName(name, sig, dryRun, pids)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		if !dryRun {
+			// Record to cache
+			_ = mcp_cache.SaveRecord("pkill_by_name", resultStr)
 		}
-	}
 
-	if *verbose {
-		enableDebugLog = true
-	}
+		return mcp.CallToolResult{IsError: len(res.Failed) > 0, Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
 
-	// 2.1 Initialize Cache if requested
-	if *cacheDir != "" {
-		if err := mcp_cache.Init(*cacheDir); err != nil {
-			log.Fatalf("Failed to initialize cache at %s: %v", *cacheDir, err)
+	// --- list_processes ---
+	registerTool(server, "list_processes", "List running processes, optionally filtered by name substring", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": { "type": "string", "description": "Case-insensitive name substring to match (empty for all, capped at 200 results)" }
 		}
-		log.Printf("Cache initialized at %s", *cacheDir)
-	}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		name, _ := args["name"].(string)
 
-	// 3. Initialize Server
-	server := mcp.NewServer()
+		res, err := system.FindProcesses(name)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
 
-	// 4. Register Tools
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
 
-	// --- latency (ping) ---
-	server.RegisterTool("latency", "Check network latency to a target", json.RawMessage(`{
+	// --- fd_usage ---
+	registerTool(server, "fd_usage", "Report open file descriptors per process and system-wide, highlighting processes nearing their open files limit", json.RawMessage(`{
 		"type": "object",
 		"properties": {
-			"target": { "type": "string", "description": "Target IP or hostname" },
-			"mode": { "type": "string", "description": "quick (10 pkts) or standard (100 pkts)" }
-		},
-		"required": ["target", "mode"]
-	}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		target, _ := args["target"].(string)
-		mode, _ := args["mode"].(string)
+			"limit": { "type": "integer", "description": "Number of processes with the most open descriptors to list (1-200, default 20)" },
+			"threshold": { "type": "number", "description": "Percent of the soft RLIMIT_NOFILE at which a process is reported as near its limit (default 80)" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		limit := system.DefaultFDLimit
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+			if limit < 1 || limit > system.MaxFDLimit {
+				return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("limit must be between 1 and %d", system.MaxFDLimit)}}}, nil
+			}
+		}
+		threshold, _ := args["threshold"].(float64)
 
-		res, err := latency.Run(context.Background(), target, mode)
+		res, err := system.GetFDUsage(ctx, limit, threshold)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
 
-		jsonBytes, _ := json.Marshal(res)
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
 		resultStr := string(jsonBytes)
 
 		// Record to cache
-		_ = mcp_cache.SaveRecord("latency", resultStr)
+		_ = mcp_cache.SaveRecord("fd_usage", resultStr)
 
 		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
 	})
 
-	// --- traceroute ---
-	server.RegisterTool("traceroute", "Trace path to a network target", json.RawMessage(`{
+	// --- port_status ---
+	registerTool(server, "port_status", "Check status of ports", json.RawMessage(`{
 		"type": "object",
 		"properties": {
-			"target": { "type": "string", "description": "Target IP or hostname" }
-		},
-		"required": ["target"]
-	}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		target, _ := args["target"].(string)
+			"port": { "type": "integer", "description": "Specific port to check (optional, 0 for all)" },
+			"protocol": { "type": "string", "description": "Protocol filter: tcp, udp or all (default all)" },
+			"include_established": { "type": "boolean", "description": "Include established (non-listening) connections with their peer address" },
+			"resolve": { "type": "boolean", "description": "Reverse-resolve peer addresses into peer_host (adds DNS lookups, default false)" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		opts := port.Options{}
+		if p, ok := args["port"].(float64); ok {
+			opts.Port = int(p)
+		}
+		opts.Protocol, _ = args["protocol"].(string)
+		opts.IncludeEstablished, _ = args["include_established"].(bool)
+		opts.Resolve, _ = args["resolve"].(bool)
 
-		res, err := traceroute.Run(context.Background(), target)
+		res, err := port.GetPortStatus(ctx, opts)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
-		// Record to cache
-		_ = mcp_cache.SaveRecord("traceroute", res)
 
-		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: res}}}, nil
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
 	})
 
-	// --- system_stats ---
-	server.RegisterTool("system_stats", "Get system statistics", json.RawMessage(`{
+	// --- socket_summary ---
+	registerTool(server, "socket_summary", "Count TCP and UDP sockets per state (ESTAB, TIME_WAIT, CLOSE_WAIT, ...) to spot socket leaks or exhaustion", json.RawMessage(`{
 		"type": "object",
-		"properties": {},
-		"required": []
-	}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		res, err := system.GetStats(context.Background())
+		"properties": {}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := port.SummarizeSockets(ctx)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
 		// Record to cache
-		_ = mcp_cache.SaveRecord("system_stats", res)
+		_ = mcp_cache.SaveRecord("socket_summary", resultStr)
 
-		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: res}}}, nil
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
 	})
 
-	// --- pkill ---
-	server.RegisterTool("pkill", "Kill a process by PID", json.RawMessage(`{
+	// --- ss_query ---
+	registerTool(server, "ss_query", "Run ss with a constrained set of options, e.g. TCP internals (info) for sockets in a given state or on a given port. Only allowlisted flags and states are accepted", json.RawMessage(`{
 		"type": "object",
 		"properties": {
-			"pid": { "type": "integer", "description": "Process ID to kill" }
-		},
-		"required": ["pid"]
-	}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		pidFloat, ok := args["pid"].(float64) // JSON numbers are floats
-		if !ok {
-			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: "invalid pid"}}}, nil
+			"flags": {
+				"type": "array",
+				"items": { "type": "string", "enum": ["tcp", "udp", "raw", "unix", "listening", "all", "numeric", "resolve", "processes", "info", "memory", "timers", "extended", "ipv4", "ipv6", "summary"] },
+				"description": "ss options by name (tcp = -t, numeric = -n, info = -i, processes = -p, ...)"
+			},
+			"state": { "type": "string", "description": "State filter: established, syn-sent, syn-recv, fin-wait-1, fin-wait-2, time-wait, closed, close-wait, last-ack, listening, closing, all, connected, synchronized, bucket, big" },
+			"port": { "type": "integer", "description": "Only sockets with this local or peer port" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		var opts port.QueryOptions
+		if list, ok := args["flags"].([]interface{}); ok {
+			for _, v := range list {
+				if f, ok := v.(string); ok {
+					opts.Flags = append(opts.Flags, f)
+				}
+			}
+		}
+		opts.State, _ = args["state"].(string)
+		if p, ok := args["port"].(float64); ok {
+			opts.Port = int(p)
 		}
-		pid := int32(pidFloat)
 
-		err := system.KillProcess(pid)
+		resultStr, err := port.Query(ctx, opts)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
-		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("Process %d killed", pid)}}}, nil
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("ss_query", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
 	})
 
-	// --- port_status ---
-	server.RegisterTool("port_status", "Check status of ports", json.RawMessage(`{
+	// --- tcp_info ---
+	registerTool(server, "tcp_info", "Kernel TCP metrics of established connections (smoothed RTT, congestion window, retransmits, send rate) from ss -ti, to diagnose the throughput of a single flow", json.RawMessage(`{
 		"type": "object",
 		"properties": {
-			"port": { "type": "integer", "description": "Specific port to check (optional, 0 for all)" }
+			"port": { "type": "integer", "description": "Only connections with this local or peer port" },
+			"limit": { "type": "integer", "description": "Maximum connections to return (default 100, max 1000)" }
 		}
-	}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		portNum := 0
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		portNum, limit := 0, 0
 		if p, ok := args["port"].(float64); ok {
 			portNum = int(p)
 		}
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+		}
 
-		res, err := port.GetPortStatus(context.Background(), portNum)
+		res, err := port.GetTCPInfo(ctx, portNum, limit)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
 
 		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
-		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("tcp_info", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
 	})
 
 	// --- read_records ---
-	server.RegisterTool("read_records", "Read execution records from the database", json.RawMessage(`{
+	registerTool(server, "read_records", "Read execution records from the database", json.RawMessage(`{
 		"type": "object",
 		"properties": {
-			"tool_name": { "type": "string", "description": "Tool name to query (latency, traceroute, system_stats)" },
+			"tool_name": { "type": "string", "description": "Tool name to query (latency, traceroute, system_stats), omit or \"all\" for a timeline across every tool" },
 			"start_time": { "type": "string", "description": "Start time (YYYYMMDDhhmmss) for filtering" },
-			"end_time": { "type": "string", "description": "End time (YYYYMMDDhhmmss) for filtering" }
+			"end_time": { "type": "string", "description": "End time (YYYYMMDDhhmmss) for filtering" },
+			"limit": { "type": "integer", "description": "Maximum records to return, newest first (default 100, max 1000)" },
+			"offset": { "type": "integer", "description": "Records to skip, use next_offset from the previous page (default 0)" }
 		},
 		"required": ["start_time"]
-	}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		toolName, _ := args["tool_name"].(string)
+		startTime, _ := args["start_time"].(string)
+		endTime, _ := args["end_time"].(string)
+		limit, offset := 0, 0
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+		}
+		if o, ok := args["offset"].(float64); ok {
+			offset = int(o)
+		}
+
+		page, err := mcp_cache.QueryRecords(toolName, startTime, endTime, limit, offset)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(page, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- count_records ---
+	registerTool(server, "count_records", "Count execution records in the database, e.g. before paging through read_records", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"tool_name": { "type": "string", "description": "Tool name to count (latency, traceroute, system_stats), omit or \"all\" for every tool" },
+			"start_time": { "type": "string", "description": "Start time (YYYYMMDDhhmmss) for filtering" },
+			"end_time": { "type": "string", "description": "End time (YYYYMMDDhhmmss) for filtering" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		toolName, _ := args["tool_name"].(string)
+		startTime, _ := args["start_time"].(string)
+		endTime, _ := args["end_time"].(string)
+
+		count, err := mcp_cache.CountRecords(toolName, startTime, endTime)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(map[string]int{"count": count}, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- delete_records ---
+	registerTool(server, "delete_records", "Delete execution records from the database by tool name and/or time range (unfiltered deletes require confirm)", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"tool_name": { "type": "string", "description": "Only delete records of this tool" },
+			"start_time": { "type": "string", "description": "Start time (YYYYMMDDhhmmss) for filtering" },
+			"end_time": { "type": "string", "description": "End time (YYYYMMDDhhmmss) for filtering" },
+			"confirm": { "type": "boolean", "description": "Must be true to delete all records when no filter is given" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
 		toolName, _ := args["tool_name"].(string)
 		startTime, _ := args["start_time"].(string)
 		endTime, _ := args["end_time"].(string)
+		confirm, _ := args["confirm"].(bool)
+
+		var deleted int
+		var err error
+		if toolName == "" && startTime == "" && endTime == "" {
+			if !confirm {
+				return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: "Refusing to delete all records without confirm: true. Pass tool_name, start_time or end_time to delete a subset."}}}, nil
+			}
+			deleted, err = mcp_cache.ClearRecords()
+		} else {
+			deleted, err = mcp_cache.DeleteRecords(toolName, startTime, endTime)
+		}
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(map[string]int{"deleted": deleted}, "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- latency_summary ---
+	registerTool(server, "latency_summary", "Aggregate cached latency runs per target: min/avg/max latency and average packet loss over a time window", json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"start_time": { "type": "string", "description": "Start time (YYYYMMDDhhmmss), default all records" },
+			"end_time": { "type": "string", "description": "End time (YYYYMMDDhhmmss), default now" }
+		}
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		startTime, _ := args["start_time"].(string)
+		endTime, _ := args["end_time"].(string)
 
-		records, err := mcp_cache.QueryRecords(toolName, startTime, endTime)
+		aggregates, err := mcp_cache.AggregateLatency(startTime, endTime)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
 
-		jsonBytes, _ := json.MarshalIndent(records, "", "  ")
+		jsonBytes, _ := json.MarshalIndent(aggregates, "", "  ")
 		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
 	})
 
 	// --- systemd_logs ---
-	server.RegisterTool("systemd_logs", "View the journalctl logs for a specific unit (default last 100 lines)", json.RawMessage(`{
+	registerTool(server, "systemd_logs", "View the journalctl logs for a specific unit (default last 100 lines)", json.RawMessage(`{
 			"type": "object",
 			"properties": {
 				"unit": { "type": "string", "description": "Systemd unit name (e.g. ssh, nginx)" },
-				"lines": { "type": "integer", "description": "Number of lines to retrieve (default 100)" }
+				"lines": { "type": "integer", "description": "Number of lines to retrieve (default 100)" },
+				"since": { "type": "string", "description": "Show entries on or newer than this time (RFC3339 or systemd time, e.g. 'yesterday', '2024-01-02 14:00')" },
+				"until": { "type": "string", "description": "Show entries on or older than this time (RFC3339 or systemd time)" },
+				"priority": { "type": "string", "description": "Maximum priority to show: 0-7 or emerg, alert, crit, err, warning, notice, info, debug" },
+				"match": { "type": "string", "description": "Only show entries whose message matches this pattern (case-insensitive regex)" },
+				"max_bytes": { "type": "integer", "description": "Keep only the newest log text up to this many bytes and note how many earlier lines were omitted (default no limit); applied to the lines retrieved" },
+				"format": { "type": "string", "description": "text (default, journalctl lines) or json (entries with timestamp, priority, unit, pid and message)" }
 			},
 			"required": ["unit"]
-		}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
 		unit, _ := args["unit"].(string)
-		lines := 0
+		opts := systemd.JournalOptions{}
 		if l, ok := args["lines"].(float64); ok {
-			lines = int(l)
+			opts.Lines = int(l)
+		}
+		if b, ok := args["max_bytes"].(float64); ok {
+			opts.MaxBytes = int(b)
+		}
+		opts.Since, _ = args["since"].(string)
+		opts.Until, _ = args["until"].(string)
+		opts.Match, _ = args["match"].(string)
+		switch p := args["priority"].(type) {
+		case string:
+			opts.Priority = p
+		case float64:
+			opts.Priority = fmt.Sprintf("%d", int(p))
+		}
+
+		format, _ := args["format"].(string)
+		switch strings.ToLower(format) {
+		case "", "text":
+		case "json":
+			entries, omitted, err := systemd.GetJournalEntries(ctx, unit, opts)
+			if err != nil {
+				return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+			}
+			jsonBytes, _ := json.MarshalIndent(struct {
+				Entries []systemd.JournalEntry `json:"entries"`
+				Omitted int                    `json:"omitted,omitempty"` // Entries dropped by max_bytes
+			}{entries, omitted}, "", "  ")
+			resultStr := string(jsonBytes)
+
+			// Record to cache
+			_ = mcp_cache.SaveRecord("systemd_logs", resultStr)
+
+			return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+		default:
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("invalid format '%s'. Allowed formats: text, json", format)}}}, nil
 		}
 
-		logs, err := systemd.GetJournalLogs(unit, lines)
+		logs, err := systemd.GetJournalLogs(ctx, unit, opts)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
@@ -233,25 +1091,25 @@ func main() {
 			logContent += line + "\n"
 		}
 
-		// Record to cache (maybe truncate if too large?)
+		// Record to cache
 		_ = mcp_cache.SaveRecord("systemd_logs", logContent)
 
 		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: logContent}}}, nil
 	})
 
 	// --- manage_service ---
-	server.RegisterTool("manage_service", "Manage systemd services", json.RawMessage(`{
+	registerTool(server, "manage_service", "Manage systemd services", json.RawMessage(`{
 			"type": "object",
 			"properties": {
-				"unit": { "type": "string", "description": "Systemd unit name" },
-				"action": { "type": "string", "description": "Action to perform: start, stop, restart, reload, enable, disable, status" }
+				"unit": { "type": "string", "description": "Systemd unit name (not required for daemon-reload)" },
+				"action": { "type": "string", "description": "Action to perform: start, stop, restart, reload, enable, disable, status, mask, unmask, reset-failed, daemon-reload" }
 			},
-			"required": ["unit", "action"]
-		}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
+			"required": ["action"]
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
 		unit, _ := args["unit"].(string)
 		action, _ := args["action"].(string)
 
-		output, err := systemd.ControlService(unit, action)
+		output, err := systemd.ControlService(ctx, unit, action)
 		if err != nil {
 			// output might contain partial output even on error
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: fmt.Sprintf("Error: %v\nOutput: %s", err, output)}}}, nil
@@ -265,13 +1123,41 @@ func main() {
 		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultMsg}}}, nil
 	})
 
+	// --- service_status ---
+	registerTool(server, "service_status", "Get the structured status of a systemd unit (load/active/sub state, main PID, memory)", json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"unit": { "type": "string", "description": "Systemd unit name" }
+			},
+			"required": ["unit"]
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		unit, _ := args["unit"].(string)
+
+		res, err := systemd.GetUnitStatus(ctx, unit)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("service_status", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
 	// --- systemd_list_units ---
-	server.RegisterTool("systemd_list_units", "List all loaded systemd units (services)", json.RawMessage(`{
+	registerTool(server, "systemd_list_units", "List all loaded systemd units (default services)", json.RawMessage(`{
 			"type": "object",
-			"properties": {},
+			"properties": {
+				"type": { "type": "string", "description": "Unit type: service (default), timer, socket, target, mount, all" }
+			},
 			"required": []
-		}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		res, err := systemd.ListUnits()
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		unitType, _ := args["type"].(string)
+
+		res, err := systemd.ListUnits(ctx, unitType)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
@@ -282,13 +1168,37 @@ func main() {
 		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: res}}}, nil
 	})
 
-	// --- systemd_list_unit_files ---
-	server.RegisterTool("systemd_list_unit_files", "List all installed systemd unit files", json.RawMessage(`{
+	// --- systemd_failed_units ---
+	registerTool(server, "systemd_failed_units", "List systemd units in the failed state", json.RawMessage(`{
 			"type": "object",
 			"properties": {},
 			"required": []
-		}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		res, err := systemd.ListUnitFiles()
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		res, err := systemd.ListFailedUnits(ctx)
+		if err != nil {
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
+		}
+
+		jsonBytes, _ := json.MarshalIndent(res, "", "  ")
+		resultStr := string(jsonBytes)
+
+		// Record to cache
+		_ = mcp_cache.SaveRecord("systemd_failed_units", resultStr)
+
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
+	})
+
+	// --- systemd_list_unit_files ---
+	registerTool(server, "systemd_list_unit_files", "List all installed systemd unit files (default services)", json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"type": { "type": "string", "description": "Unit type: service (default), timer, socket, target, mount, all" }
+			},
+			"required": []
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		unitType, _ := args["type"].(string)
+
+		res, err := systemd.ListUnitFiles(ctx, unitType)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
@@ -300,12 +1210,57 @@ func main() {
 	})
 
 	// --- system_diagnostics ---
-	server.RegisterTool("system_diagnostics", "Get system diagnostics (logs, dmesg, login history)", json.RawMessage(`{
+	registerTool(server, "system_diagnostics", "Get system diagnostics (logs, dmesg, login history)", json.RawMessage(`{
 			"type": "object",
-			"properties": {},
+			"properties": {
+				"journal_lines": { "type": "integer", "description": "Number of journalctl error entries (default 100)" },
+				"syslog_lines": { "type": "integer", "description": "Number of syslog error lines (default 100)" },
+				"syslog_patterns": { "type": "array", "items": { "type": "string" }, "description": "Keywords that mark a syslog line as an error, matched case-insensitively at a word start (default error, failed, failure, fatal, panic, segfault, critical, call trace)" },
+				"syslog_severity": { "type": "string", "description": "Only report syslog lines of this severity or worse: 0-7 or emerg, alert, crit, err, warning, notice, info, debug (e.g. crit keeps panic, fatal, segfault, critical and call trace)" },
+				"syslog_rotations": { "type": "integer", "description": "Also scan up to this many rotated syslog files (syslog.1, syslog.2.gz, ...) for older errors (default 0, max 10)" },
+				"dmesg_lines": { "type": "integer", "description": "Number of dmesg entries (default 50)" },
+				"login_entries": { "type": "integer", "description": "Number of last/lastb entries (default 10)" },
+				"disk_threshold": { "type": "number", "description": "Report filesystems at or above this used-space percentage (default 90)" },
+				"restart_threshold": { "type": "integer", "description": "Report services restarted at least this many times as crash-looping (default 3)" },
+				"probe_timeout": { "type": "integer", "description": "Seconds each source (journalctl, dmesg, last, ...) may take before it is reported as timed out (default 10, max 60)" }
+			},
 			"required": []
-		}`), func(args map[string]interface{}) (mcp.CallToolResult, error) {
-		res, err := diagnostics.RunDiagnostics()
+		}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		opts := diagnostics.DiagnosticsOptions{}
+		if n, ok := args["journal_lines"].(float64); ok {
+			opts.JournalLines = int(n)
+		}
+		if n, ok := args["syslog_lines"].(float64); ok {
+			opts.SyslogLines = int(n)
+		}
+		if list, ok := args["syslog_patterns"].([]interface{}); ok {
+			for _, v := range list {
+				if p, ok := v.(string); ok {
+					opts.SyslogPatterns = append(opts.SyslogPatterns, p)
+				}
+			}
+		}
+		opts.SyslogSeverity, _ = args["syslog_severity"].(string)
+		if n, ok := args["syslog_rotations"].(float64); ok {
+			opts.SyslogRotations = int(n)
+		}
+		if n, ok := args["dmesg_lines"].(float64); ok {
+			opts.DmesgLines = int(n)
+		}
+		if n, ok := args["login_entries"].(float64); ok {
+			opts.LoginEntries = int(n)
+		}
+		if n, ok := args["disk_threshold"].(float64); ok {
+			opts.DiskThreshold = n
+		}
+		if n, ok := args["restart_threshold"].(float64); ok {
+			opts.RestartThreshold = int(n)
+		}
+		if n, ok := args["probe_timeout"].(float64); ok {
+			opts.ProbeTimeout = time.Duration(n * float64(time.Second))
+		}
+
+		res, err := diagnostics.RunDiagnostics(ctx, opts)
 		if err != nil {
 			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: err.Error()}}}, nil
 		}
@@ -319,11 +1274,48 @@ func main() {
 		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: resultStr}}}, nil
 	})
 
+	// --- capabilities ---
+	registerTool(server, "capabilities", "List which tools are usable on this host: missing external commands (ping, traceroute, ss, systemctl, ...) with the package that provides them, and tools that need root", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		jsonBytes, _ := json.MarshalIndent(probeCapabilities(), "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	// --- server_info ---
+	registerTool(server, "server_info", "Show which build of the server is running: version, git commit, build date, Go version and uptime", json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"required": []
+	}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		jsonBytes, _ := json.MarshalIndent(version.Get(), "", "  ")
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: string(jsonBytes)}}}, nil
+	})
+
+	if err := selectedTools.validate(); err != nil {
+		fatal("Invalid -enable-tools/-disable-tools", "error", err)
+	}
+	if err := requireConfirmation(server, *confirmTools); err != nil {
+		fatal("Invalid -confirm-tools", "error", err)
+	}
+
+	registerPrompts(server)
+
 	// 5. Start Server
-	if *addr != "" && *p != "" {
-		startSSEServer(server, *addr, *p, *apiKey)
+	if httpTransport(*addr, *p) {
+		startSSEServer(server, *addr, *p, *apiKey, *apiKeyInPath, parseCORSOrigins(*corsOrigins), metrics)
 	} else {
-		startStdioServer(server)
+		if metrics != nil {
+			slog.Warn("-metrics has no effect with the stdio transport")
+		}
+		startStdioServer(server, *maxMessage)
+	}
+
+	// 6. Write out queued cache records before exiting
+	if err := mcp_cache.Close(); err != nil {
+		slog.Error("Failed to close cache", "error", err)
 	}
 }
 
@@ -352,108 +1344,188 @@ func isValidAPIKey(key string) bool {
 	return true
 }
 
-func startStdioServer(server *mcp.Server) {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		var req mcp.JSONRPCRequest
-		if err := json.Unmarshal(line, &req); err != nil {
-			// Log error but continue
-			log.Printf("Invalid JSON: %v", err)
-			continue
+// networkFilterArgs reads the interface filter arguments shared by system_stats and network_usage
+func networkFilterArgs(args map[string]interface{}) system.NetworkFilter {
+	var filter system.NetworkFilter
+	for key, dst := range map[string]*[]string{"interfaces": &filter.Include, "exclude_interfaces": &filter.Exclude} {
+		if list, ok := args[key].([]interface{}); ok {
+			for _, v := range list {
+				if p, ok := v.(string); ok {
+					*dst = append(*dst, p)
+				}
+			}
 		}
+	}
+	filter.Loopback, _ = args["include_loopback"].(bool)
+	return filter
+}
+
+func startStdioServer(server *mcp.Server, maxMessageSize int) {
+	// Notifications may be emitted from other goroutines, so stdout writes are serialized
+	var writeLock sync.Mutex
+	writeMessage := func(msg interface{}) {
+		msgBytes, _ := json.Marshal(msg)
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		fmt.Println(string(msgBytes))
+	}
+	server.SetNotificationHandler(func(n mcp.JSONRPCNotification) {
+		writeMessage(n)
+	})
 
-		if enableDebugLog {
-			log.Printf("[DEBUG] Stdio Request: %+v", req)
+	// stdin may never reach EOF, so queued cache records are also flushed on SIGINT/SIGTERM
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		<-sigCh
+		if err := mcp_cache.Close(); err != nil {
+			slog.Error("Failed to close cache", "error", err)
 		}
+		os.Exit(0)
+	}()
 
+	handle := func(req mcp.JSONRPCRequest) {
 		resp := server.HandleRequest(req)
 		if resp == nil {
-			continue
+			return
 		}
 
-		if enableDebugLog {
-			log.Printf("[DEBUG] Stdio Response: %+v", resp)
+		slog.Debug("Sending response", "transport", "stdio", "id", resp.ID, "error", resp.Error)
+		writeMessage(resp)
+	}
+
+	var calls sync.WaitGroup
+	scanner := bufio.NewScanner(os.Stdin)
+	// The default 64KB token limit silently drops large requests
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		req, errResp := mcp.ParseRequest(line)
+		if errResp != nil {
+			slog.Warn("Invalid JSON-RPC request", "transport", "stdio", "error", errResp.Error.Data)
+			writeMessage(errResp)
+			continue
 		}
 
-		respBytes, _ := json.Marshal(resp)
-		fmt.Println(string(respBytes))
+		// Tool calls run in the background so a notifications/cancelled line can still be read
+		if req.Method == "tools/call" {
+			calls.Add(1)
+			go func() {
+				defer calls.Done()
+				handle(req)
+			}()
+			continue
+		}
+		handle(req)
 	}
-}
+	// Answer the calls still running before the caller closes the cache
+	calls.Wait()
 
-var enableDebugLog bool
-
-func debugLog(format string, v ...interface{}) {
-	if enableDebugLog {
-		log.Printf("[DEBUG] "+format, v...)
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			fatal("Stdio message too large, raise -max_message_size", "limit", maxMessageSize, "error", err)
+		}
+		fatal("Failed to read stdin", "error", err)
 	}
 }
 
 // SessionManager manages SSE client sessions
+// Messages are JSON-RPC responses or notifications
 type SessionManager struct {
-	clients map[chan mcp.JSONRPCResponse]bool
+	clients map[chan interface{}]bool
 	lock    sync.RWMutex
 }
 
 func NewSessionManager() *SessionManager {
 	return &SessionManager{
-		clients: make(map[chan mcp.JSONRPCResponse]bool),
+		clients: make(map[chan interface{}]bool),
 	}
 }
 
-func (sm *SessionManager) Add(ch chan mcp.JSONRPCResponse) {
+func (sm *SessionManager) Add(ch chan interface{}) {
 	sm.lock.Lock()
 	defer sm.lock.Unlock()
 	sm.clients[ch] = true
-	debugLog("New SSE client connected, total clients: %d", len(sm.clients))
+	slog.Debug("SSE client connected", "clients", len(sm.clients))
 }
 
-func (sm *SessionManager) Remove(ch chan mcp.JSONRPCResponse) {
+func (sm *SessionManager) Remove(ch chan interface{}) {
 	sm.lock.Lock()
 	defer sm.lock.Unlock()
 	if _, ok := sm.clients[ch]; ok {
 		delete(sm.clients, ch)
 		close(ch)
-		debugLog("SSE client disconnected, total clients: %d", len(sm.clients))
+		slog.Debug("SSE client disconnected", "clients", len(sm.clients))
 	}
 }
 
-func (sm *SessionManager) Broadcast(resp mcp.JSONRPCResponse) {
+// Count returns the number of connected clients
+func (sm *SessionManager) Count() int {
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	return len(sm.clients)
+}
+
+func (sm *SessionManager) Broadcast(resp interface{}) {
 	sm.lock.RLock()
 	defer sm.lock.RUnlock()
 
-	debugLog("Broadcasting message to %d clients", len(sm.clients))
+	slog.Debug("Broadcasting message", "clients", len(sm.clients))
 	for ch := range sm.clients {
 		select {
 		case ch <- resp:
 		case <-time.After(100 * time.Millisecond):
-			debugLog("Warning: Dropped message for slow client")
+			slog.Warn("Dropped message for slow SSE client")
 		}
 	}
 }
 
-func startSSEServer(server *mcp.Server, addr, port, apiKey string) {
+// httpTransport reports whether the listen flags select the HTTP transport instead of stdio
+func httpTransport(addr, port string) bool {
+	return isUnixAddr(addr) || (addr != "" && port != "")
+}
+
+func startSSEServer(server *mcp.Server, addr, port, apiKey string, keyInPath bool, cors corsPolicy, metrics *mcp.Metrics) {
 	mux := http.NewServeMux()
 	sessionMgr := NewSessionManager()
+	server.SetNotificationHandler(func(n mcp.JSONRPCNotification) {
+		sessionMgr.Broadcast(n)
+	})
 
+	// The key is expected in the Authorization or X-API-Key header of every request.
+	// With -api-key-in-path it is part of the /sse, /message and /mcp paths instead, as in earlier versions.
 	ssePath := "/sse"
+	messagePath := "/message"
+	mcpPath := "/mcp"
+	metricsPath := "/metrics"
+	endpoint := "/message" // Advertised to SSE clients as the path to POST requests to
+	auth := func(path string, next http.HandlerFunc) http.HandlerFunc { return next }
 	if apiKey != "" {
-		ssePath = fmt.Sprintf("/sse/%s", apiKey)
+		if keyInPath {
+			// Any key in the path reaches the handler, which compares it with the configured one
+			ssePath, messagePath, mcpPath, metricsPath = "/sse/", "/message/", "/mcp/", "/metrics/"
+			endpoint = messagePath + apiKey
+			auth = func(path string, next http.HandlerFunc) http.HandlerFunc { return requirePathKey(apiKey, path, next) }
+		} else {
+			auth = func(_ string, next http.HandlerFunc) http.HandlerFunc { return requireAPIKey(apiKey, next) }
+		}
 	}
 
-	mux.HandleFunc(ssePath, func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(ssePath, cors.wrap("GET", auth(ssePath, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		// Buffer channel slightly to avoid dropping immediately on bursts
-		msgCh := make(chan mcp.JSONRPCResponse, 5)
+		msgCh := make(chan interface{}, 5)
 		sessionMgr.Add(msgCh)
 		defer sessionMgr.Remove(msgCh)
 
-		// Send endpoint event
-		fmt.Fprintf(w, "event: endpoint\ndata: /message\n\n")
+		// Send endpoint event. The session ID scopes request IDs to this client, whose
+		// responses are still broadcast to all connected clients.
+		b := make([]byte, 16)
+		rand.Read(b)
+		fmt.Fprintf(w, "event: endpoint\ndata: %s?sessionId=%s\n\n", endpoint, hex.EncodeToString(b))
 		w.(http.Flusher).Flush()
 
 		// Stream responses
@@ -480,41 +1552,84 @@ func startSSEServer(server *mcp.Server, addr, port, apiKey string) {
 				return
 			}
 		}
-	})
+	})))
 
-	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(messagePath, cors.wrap("POST", auth(messagePath, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		var req mcp.JSONRPCRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
 			return
 		}
 
-		if enableDebugLog {
-			log.Printf("[DEBUG] HTTP POST /message Request: %+v", req)
+		req, errResp := mcp.ParseRequest(body)
+		if errResp != nil {
+			// Reply directly so the client can correlate the failure with its request
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errResp)
+			return
 		}
 
 		// Handle asynchronously
+		// Clients that ignore the advertised endpoint share the empty session
+		session := r.URL.Query().Get("sessionId")
 		go func() {
-			resp := server.HandleRequest(req)
+			resp := server.HandleSessionRequest(session, req)
 			if resp != nil {
-				if enableDebugLog {
-					log.Printf("[DEBUG] HTTP POST /message Response: %+v", resp)
-				}
+				slog.Debug("Sending response", "transport", "sse", "id", resp.ID, "error", resp.Error)
 				sessionMgr.Broadcast(*resp)
 			}
 		}()
 
 		w.WriteHeader(http.StatusAccepted)
-	})
+	})))
+
+	// Streamable HTTP transport for current clients; /sse and /message remain for legacy ones
+	mux.HandleFunc(mcpPath, cors.wrap("POST, DELETE", auth(mcpPath, streamableHTTPHandler(server))))
+
+	if apiKey != "" && keyInPath {
+		// Without this the mux redirects the bare paths to the key-less "/sse/" etc. instead of rejecting them
+		for _, path := range []string{"/sse", "/message", "/mcp", "/metrics"} {
+			mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) { unauthorized(w) })
+		}
+	}
+
+	if metrics != nil {
+		metrics.RegisterGauge("netutil_sse_clients", "Number of connected SSE clients.", func() float64 {
+			return float64(sessionMgr.Count())
+		})
+		mux.HandleFunc(metricsPath, auth(metricsPath, metrics.ServeHTTP))
+	}
+
+	ln, cleanup, err := listen(addr, port)
+	if err != nil {
+		fatal("Failed to listen", "error", err)
+	}
+	defer cleanup()
+
+	httpServer := &http.Server{Handler: mux}
+
+	// Shut down on SIGINT/SIGTERM so the Unix socket file is removed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// SSE streams never finish on their own, so fall back to closing them
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			httpServer.Close()
+		}
+	}()
 
-	listenAddr := fmt.Sprintf("%s:%s", addr, port)
-	log.Printf("Starting SSE server on %s...", listenAddr)
-	if err := http.ListenAndServe(listenAddr, mux); err != nil {
-		log.Fatal(err)
+	slog.Info("Starting HTTP server", "addr", ln.Addr().String())
+	if err := httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+		cleanup()
+		fatal("HTTP server failed", "error", err)
 	}
 }
@@ -0,0 +1,240 @@
+package bandwidth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+)
+
+const (
+	// DefaultPort is the iperf3 server port
+	DefaultPort = 5201
+	// DefaultDuration is how long a test runs when no duration is given
+	DefaultDuration = 10 * time.Second
+	// MaxDuration bounds a single test
+	MaxDuration = 60 * time.Second
+)
+
+// Options configures a throughput test
+type Options struct {
+	Host      string
+	Port      int           // default 5201
+	Duration  time.Duration // default 10s, at most 60s
+	Direction string        // up (client sends, default) or down (server sends)
+}
+
+// Result holds the measured throughput
+type Result struct {
+	Host            string  `json:"host"`
+	Port            int     `json:"port"`
+	Direction       string  `json:"direction"`
+	Method          string  `json:"method"` // iperf3 or tcp
+	DurationSeconds float64 `json:"duration_seconds"`
+	BytesSent       int64   `json:"bytes_sent"`
+	BytesReceived   int64   `json:"bytes_received"`
+	Mbps            float64 `json:"mbps"`
+	Retransmits     *int    `json:"retransmits,omitempty"` // Only reported by iperf3 on Linux senders
+}
+
+// withDefaults validates opts and fills in unset values
+func (opts Options) withDefaults() (Options, error) {
+	if err := validateHost(opts.Host); err != nil {
+		return opts, fmt.Errorf("invalid host: %w", err)
+	}
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+	if opts.Port < 1 || opts.Port > 65535 {
+		return opts, fmt.Errorf("invalid port %d: must be between 1 and 65535", opts.Port)
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultDuration
+	}
+	if opts.Duration > MaxDuration {
+		return opts, fmt.Errorf("invalid duration %s: must be at most %s", opts.Duration, MaxDuration)
+	}
+	switch strings.ToLower(opts.Direction) {
+	case "", "up":
+		opts.Direction = "up"
+	case "down":
+		opts.Direction = "down"
+	default:
+		return opts, fmt.Errorf("invalid direction '%s'. Allowed directions: up, down", opts.Direction)
+	}
+	return opts, nil
+}
+
+// validateHost rejects hosts that could be interpreted as iperf3 flags
+func validateHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("host cannot be empty")
+	}
+	if strings.HasPrefix(host, "-") {
+		return fmt.Errorf("host must not start with '-'")
+	}
+	if len(host) > 253 || strings.ContainsAny(host, " \t\n;|&$`'\"\\/") {
+		return fmt.Errorf("host '%s' contains invalid characters", host)
+	}
+	return nil
+}
+
+// Run measures throughput to an iperf3 server.
+// iperf3 -J is used when installed, otherwise a built-in TCP streamer sends to
+// (up) or reads from (down) the port. The streamer does not speak the iperf3
+// protocol, so it needs a plain TCP sink or source such as nc, not an iperf3 server.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := exec.LookPath("iperf3"); err == nil {
+		return runIperf3(ctx, opts)
+	}
+	return runTCP(ctx, opts)
+}
+
+// runIperf3 runs iperf3 in client mode with JSON output
+func runIperf3(ctx context.Context, opts Options) (*Result, error) {
+	args := []string{"-c", opts.Host, "-p", strconv.Itoa(opts.Port), "-t", strconv.Itoa(int(opts.Duration.Seconds())), "-J"}
+	if opts.Direction == "down" {
+		args = append(args, "-R")
+	}
+
+	// iperf3 exits non-zero on failure but still prints the JSON report with an "error" field
+	output, runErr := exec.CommandContext(ctx, "iperf3", args...).Output()
+	res, err := parseIperf3Output(output)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("iperf3 failed: %w", runErr)
+		}
+		return nil, err
+	}
+
+	res.Host = opts.Host
+	res.Port = opts.Port
+	res.Direction = opts.Direction
+	return res, nil
+}
+
+// iperf3Report is the subset of iperf3 -J output that is reported
+type iperf3Report struct {
+	Error string `json:"error"`
+	End   struct {
+		SumSent struct {
+			Seconds       float64 `json:"seconds"`
+			Bytes         int64   `json:"bytes"`
+			BitsPerSecond float64 `json:"bits_per_second"`
+			Retransmits   *int    `json:"retransmits"`
+		} `json:"sum_sent"`
+		SumReceived struct {
+			Seconds       float64 `json:"seconds"`
+			Bytes         int64   `json:"bytes"`
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_received"`
+	} `json:"end"`
+}
+
+// parseIperf3Output extracts the totals from an iperf3 -J report
+func parseIperf3Output(output []byte) (*Result, error) {
+	var report iperf3Report
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse iperf3 output: %w", err)
+	}
+	if report.Error != "" {
+		return nil, fmt.Errorf("iperf3: %s", report.Error)
+	}
+
+	sent, received := report.End.SumSent, report.End.SumReceived
+	return &Result{
+		Method:          "iperf3",
+		DurationSeconds: received.Seconds,
+		BytesSent:       sent.Bytes,
+		BytesReceived:   received.Bytes,
+		// The receiver's rate is what actually made it across the path
+		Mbps:        round2(received.BitsPerSecond / 1e6),
+		Retransmits: sent.Retransmits,
+	}, nil
+}
+
+// streamBufferSize is the write/read chunk size of the built-in streamer
+const streamBufferSize = 128 * 1024
+
+// runTCP streams data over a plain TCP connection for the test duration
+func runTCP(ctx context.Context, opts Options) (*Result, error) {
+	conn, err := netdial.Dialer().DialContext(ctx, "tcp", net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(opts.Duration)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	// Unblock reads and writes if the tool call is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	res := &Result{Host: opts.Host, Port: opts.Port, Direction: opts.Direction, Method: "tcp"}
+	buf := make([]byte, streamBufferSize)
+	start := time.Now()
+	var ioErr error
+	for {
+		var n int
+		if opts.Direction == "up" {
+			n, ioErr = conn.Write(buf)
+			res.BytesSent += int64(n)
+		} else {
+			n, ioErr = conn.Read(buf)
+			res.BytesReceived += int64(n)
+		}
+		if ioErr != nil {
+			break
+		}
+	}
+	elapsed := time.Since(start)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// Hitting the deadline is the normal end of a test, an early EOF means the peer stopped sending
+	var netErr net.Error
+	if !(errors.As(ioErr, &netErr) && netErr.Timeout()) && !errors.Is(ioErr, io.EOF) {
+		return nil, fmt.Errorf("transfer failed after %d bytes: %w", res.BytesSent+res.BytesReceived, ioErr)
+	}
+	if res.BytesSent+res.BytesReceived == 0 {
+		// An iperf3 server waits for its control handshake and never sends data to a plain reader
+		return nil, fmt.Errorf("no data transferred: without iperf3 installed the built-in streamer needs a plain TCP %s on port %d, not an iperf3 server", peerRole(opts.Direction), opts.Port)
+	}
+
+	res.DurationSeconds = round2(elapsed.Seconds())
+	if elapsed > 0 {
+		res.Mbps = round2(float64(res.BytesSent+res.BytesReceived) * 8 / elapsed.Seconds() / 1e6)
+	}
+	return res, nil
+}
+
+// peerRole names what the built-in streamer expects at the other end
+func peerRole(direction string) string {
+	if direction == "up" {
+		return "sink"
+	}
+	return "source"
+}
+
+// round2 rounds to two decimal places
+func round2(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
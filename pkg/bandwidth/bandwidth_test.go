@@ -0,0 +1,114 @@
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const iperf3Fixture = `{
+	"start": {},
+	"end": {
+		"sum_sent": {"start": 0, "end": 10.0, "seconds": 10.0, "bytes": 1180000000, "bits_per_second": 944000000, "retransmits": 12},
+		"sum_received": {"start": 0, "end": 10.04, "seconds": 10.04, "bytes": 1175000000, "bits_per_second": 936254980}
+	}
+}`
+
+func TestParseIperf3Output(t *testing.T) {
+	res, err := parseIperf3Output([]byte(iperf3Fixture))
+	if err != nil {
+		t.Fatalf("parseIperf3Output() error = %v", err)
+	}
+	if res.BytesSent != 1180000000 || res.BytesReceived != 1175000000 {
+		t.Errorf("bytes = %d/%d, want 1180000000/1175000000", res.BytesSent, res.BytesReceived)
+	}
+	if res.Mbps != 936.25 {
+		t.Errorf("Mbps = %v, want 936.25", res.Mbps)
+	}
+	if res.Retransmits == nil || *res.Retransmits != 12 {
+		t.Errorf("Retransmits = %v, want 12", res.Retransmits)
+	}
+
+	if _, err := parseIperf3Output([]byte(`{"error": "unable to connect to server: Connection refused"}`)); err == nil {
+		t.Errorf("parseIperf3Output() with error field returned nil error")
+	}
+}
+
+func TestOptionsWithDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "defaults", opts: Options{Host: "10.0.0.1"}},
+		{name: "down", opts: Options{Host: "iperf.example.com", Direction: "down", Port: 5202}},
+		{name: "empty host", opts: Options{}, wantErr: true},
+		{name: "flag injection", opts: Options{Host: "-R"}, wantErr: true},
+		{name: "bad direction", opts: Options{Host: "10.0.0.1", Direction: "sideways"}, wantErr: true},
+		{name: "too long", opts: Options{Host: "10.0.0.1", Duration: 2 * MaxDuration}, wantErr: true},
+		{name: "bad port", opts: Options{Host: "10.0.0.1", Port: 70000}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.withDefaults()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withDefaults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Port == 0 || got.Duration == 0 || got.Direction == "") {
+				t.Errorf("withDefaults() = %+v, want defaults filled in", got)
+			}
+		})
+	}
+}
+
+func TestRunTCPUpload(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	res, err := runTCP(context.Background(), Options{Host: "127.0.0.1", Port: port, Duration: 200 * time.Millisecond, Direction: "up"})
+	if err != nil {
+		t.Fatalf("runTCP() error = %v", err)
+	}
+	if res.BytesSent == 0 || res.Mbps <= 0 {
+		t.Errorf("runTCP() = %+v, want bytes sent and a positive rate", res)
+	}
+}
+
+func TestRunTCPDownloadNoData(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Like an iperf3 server, accept and wait for a handshake without sending anything
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	res, err := runTCP(context.Background(), Options{Host: "127.0.0.1", Port: port, Duration: 200 * time.Millisecond, Direction: "down"})
+	if err == nil || !strings.Contains(err.Error(), "no data transferred") {
+		t.Errorf("runTCP() = %+v, %v, want a no data error", res, err)
+	}
+}
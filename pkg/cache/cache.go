@@ -2,91 +2,380 @@ package cache
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
+// errNotInitialized is returned by the queries before Init and after Close
+var errNotInitialized = errors.New("database not initialized")
+
+// connPragmas are applied to each SQLite connection
+const connPragmas = "_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+
+// maxOpenConns bounds the pool, SQLite serializes writers anyway and a few connections cover concurrent reads
+const maxOpenConns = 4
 
 // LocalTimeNow returns the current local time formatted as YYYYMMDDhhmmss
 func LocalTimeNow() string {
 	return time.Now().Local().Format("20060102150405")
 }
 
-// Init initializes the SQLite database at the specified directory
+// NotWritableError is returned by Init when the cache directory cannot be created or written to
+type NotWritableError struct {
+	Dir string
+	Err error
+}
+
+func (e *NotWritableError) Error() string {
+	return fmt.Sprintf("cache directory %s is not writable: %v", e.Dir, e.Err)
+}
+
+func (e *NotWritableError) Unwrap() error {
+	return e.Err
+}
+
+// Init initializes the SQLite database at the specified directory.
+// A database opened by an earlier Init is flushed and closed first, so Init can be called again to switch directories.
 func Init(dir string) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	if err := Close(); err != nil {
+		slog.Warn("Failed to close previous cache database", "error", err)
+	}
+
+	if err := checkWritable(dir); err != nil {
+		return err
 	}
 
 	dbPath := filepath.Join(dir, "cache.db")
-	var err error
-	DB, err = sql.Open("sqlite", dbPath)
+	// Pragmas in the DSN are applied to every pooled connection, busy_timeout in particular is per connection.
+	// WAL lets readers run alongside the writer and busy_timeout waits out locks instead of failing
+	// with "database is locked".
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?"+connPragmas)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxOpenConns)
+
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if journalMode != "wal" {
+		slog.Warn("SQLite WAL mode unavailable, concurrent access may block", "journal_mode", journalMode)
+	}
 
-	if err := createTables(); err != nil {
-		DB.Close()
+	if err := createTables(db); err != nil {
+		db.Close()
 		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	startWriter(db)
+	return nil
+}
+
+// checkWritable creates dir if needed and verifies a file can be created in it,
+// so a bad directory fails at startup instead of at the first insert
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &NotWritableError{Dir: dir, Err: err}
+	}
+	f, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return &NotWritableError{Dir: dir, Err: err}
+	}
+	f.Close()
+	os.Remove(f.Name())
 	return nil
 }
 
-func createTables() error {
+func createTables(db *sql.DB) error {
+	// avg_ms, jitter_ms and loss_pct are only set for latency records so they can be aggregated in SQL
 	query := `CREATE TABLE IF NOT EXISTS records (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		timestamp TEXT,
 		tool_name TEXT,
-		mcp_output TEXT
+		mcp_output TEXT,
+		avg_ms REAL,
+		jitter_ms REAL,
+		loss_pct REAL
 	)`
 
-	if _, err := DB.Exec(query); err != nil {
+	if _, err := db.Exec(query); err != nil {
 		return err
 	}
-	return nil
+	return migrateLatencyColumns(db)
 }
 
-// SaveRecord saves a tool execution record
+// QueueSize is how many records may wait for the background writer before new ones are dropped
+const QueueSize = 1000
+
+// maxBatch caps how many queued records are inserted in one transaction
+const maxBatch = 100
+
+// ErrQueueFull is returned by SaveRecord when the writer has fallen behind and the record was dropped
+var ErrQueueFull = errors.New("cache write queue full, record dropped")
+
+// pendingRecord is a queued insert, or a flush marker when done is set
+type pendingRecord struct {
+	timestamp, toolName, output string
+	done                        chan struct{}
+}
+
+// writer owns the open database and the queue feeding the background insert goroutine.
+// db is nil before Init and after Close, the lock keeps Close from clearing it under a caller.
+var writer struct {
+	db      *sql.DB
+	queue   chan pendingRecord
+	stopped chan struct{}
+	sync.RWMutex
+}
+
+// startWriter publishes db and launches the goroutine that inserts queued records
+func startWriter(db *sql.DB) {
+	writer.Lock()
+	defer writer.Unlock()
+	writer.db = db
+	writer.queue = make(chan pendingRecord, QueueSize)
+	writer.stopped = make(chan struct{})
+	go runWriter(db, writer.queue, writer.stopped)
+}
+
+// conn returns the open database, a query racing Close gets "sql: database is closed" from it instead of a nil handle
+func conn() (*sql.DB, error) {
+	writer.RLock()
+	defer writer.RUnlock()
+	if writer.db == nil {
+		return nil, errNotInitialized
+	}
+	return writer.db, nil
+}
+
+// Enabled reports whether the cache database is open
+func Enabled() bool {
+	_, err := conn()
+	return err == nil
+}
+
+// runWriter inserts records in batches until the queue is closed
+func runWriter(db *sql.DB, queue <-chan pendingRecord, stopped chan<- struct{}) {
+	defer close(stopped)
+	for first := range queue {
+		batch := []pendingRecord{first}
+		// Take whatever else is already waiting so a burst is committed in one transaction
+	drain:
+		for len(batch) < maxBatch {
+			select {
+			case r, ok := <-queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, r)
+			default:
+				break drain
+			}
+		}
+		insertBatch(db, batch)
+	}
+}
+
+// insertBatch writes a batch in a single transaction, then releases any flush markers in it
+func insertBatch(db *sql.DB, batch []pendingRecord) {
+	defer func() {
+		for _, r := range batch {
+			if r.done != nil {
+				close(r.done)
+			}
+		}
+	}()
+
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Failed to write cache records", "records", len(batch), "error", err)
+		return
+	}
+	stmt, err := tx.Prepare("INSERT INTO records (timestamp, tool_name, mcp_output, avg_ms, jitter_ms, loss_pct) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		slog.Error("Failed to write cache records", "records", len(batch), "error", err)
+		return
+	}
+	defer stmt.Close()
+
+	for _, r := range batch {
+		if r.done != nil {
+			continue
+		}
+		// mcp_output is the raw JSON string
+		avg, jitter, loss := latencyColumns(r.toolName, r.output)
+		if _, err := stmt.Exec(r.timestamp, r.toolName, r.output, avg, jitter, loss); err != nil {
+			tx.Rollback()
+			slog.Error("Failed to write cache records", "records", len(batch), "error", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Failed to write cache records", "records", len(batch), "error", err)
+	}
+}
+
+// maxRecordSize bounds the stored output of a record in bytes, 0 disables the limit
+var maxRecordSize atomic.Int64
+
+// SetMaxRecordSize sets the largest output a record stores in bytes, longer output is truncated
+// with a marker so a huge log dump cannot bloat the database. A non-positive value disables the limit.
+func SetMaxRecordSize(n int) {
+	maxRecordSize.Store(int64(n))
+}
+
+// truncateRecord cuts output to the record size limit on a UTF-8 boundary
+func truncateRecord(output string) string {
+	max := int(maxRecordSize.Load())
+	if max <= 0 || len(output) <= max {
+		return output
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...[truncated %d bytes]", output[:cut], len(output)-cut)
+}
+
+// SaveRecord queues a tool execution record for the background writer and returns immediately.
+// Secrets are redacted from output first so the cache does not become a copy of them.
+// Output longer than the record size limit is truncated after redaction.
+// If the writer has fallen behind the record is dropped with a warning instead of blocking the caller.
 func SaveRecord(toolName, output string) error {
-	if DB == nil {
+	if !Enabled() {
+		return nil
+	}
+	output = truncateRecord(Redact(output))
+
+	writer.RLock()
+	defer writer.RUnlock()
+	if writer.queue == nil {
+		return fmt.Errorf("cache is closed")
+	}
+
+	// The timestamp is taken now so it reflects when the tool ran, not when the row was written
+	select {
+	case writer.queue <- pendingRecord{timestamp: LocalTimeNow(), toolName: toolName, output: output}:
+		return nil
+	default:
+		slog.Warn("Cache write queue full, dropping record", "tool", toolName, "queue_size", QueueSize)
+		return ErrQueueFull
+	}
+}
+
+// Flush blocks until every record queued before the call has been written
+func Flush() {
+	writer.RLock()
+	defer writer.RUnlock()
+	if writer.queue == nil {
+		return
+	}
+	done := make(chan struct{})
+	writer.queue <- pendingRecord{done: done}
+	<-done
+}
+
+// Close writes the remaining queued records and closes the database
+func Close() error {
+	writer.Lock()
+	defer writer.Unlock()
+	if writer.queue != nil {
+		close(writer.queue)
+		<-writer.stopped
+		writer.queue = nil
+	}
+
+	if writer.db == nil {
 		return nil
 	}
-	// mcp_output is the raw JSON string
-	_, err := DB.Exec("INSERT INTO records (timestamp, tool_name, mcp_output) VALUES (?, ?, ?)", LocalTimeNow(), toolName, output)
+	err := writer.db.Close()
+	writer.db = nil
 	return err
 }
 
-// QueryRecords retrieves records based on criteria
-func QueryRecords(toolName, startTime, endTime string) ([]map[string]interface{}, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
+const (
+	// DefaultQueryLimit is the page size of QueryRecords when no limit is given
+	DefaultQueryLimit = 100
+	// MaxQueryLimit bounds a single page so one response stays within client context limits
+	MaxQueryLimit = 1000
+)
+
+// RecordPage is one page of QueryRecords results, newest first
+type RecordPage struct {
+	Records    []map[string]interface{} `json:"records"`
+	HasMore    bool                     `json:"has_more"`
+	NextOffset *int                     `json:"next_offset,omitempty"` // Offset of the next page, set when HasMore
+}
+
+// AllTools as a tool name matches the records of every tool, like an empty name
+const AllTools = "all"
+
+// toolFilter returns the tool name to filter on, "" for every tool
+func toolFilter(toolName string) string {
+	if strings.EqualFold(strings.TrimSpace(toolName), AllTools) {
+		return ""
 	}
+	return toolName
+}
 
-	query := "SELECT timestamp, tool_name, mcp_output FROM records WHERE 1=1"
+// recordFilter builds the WHERE clause shared by the record queries.
+// An empty or "all" tool name matches every tool, so the records form one timeline told apart by tool_name.
+func recordFilter(toolName, startTime, endTime string) (string, []interface{}) {
+	where := " WHERE 1=1"
 	var args []interface{}
 
-	if toolName != "" {
-		query += " AND tool_name = ?"
+	if toolName = toolFilter(toolName); toolName != "" {
+		where += " AND tool_name = ?"
 		args = append(args, toolName)
 	}
 	if startTime != "" {
-		query += " AND timestamp >= ?"
+		where += " AND timestamp >= ?"
 		args = append(args, startTime)
 	}
 	if endTime != "" {
-		query += " AND timestamp <= ?"
+		where += " AND timestamp <= ?"
 		args = append(args, endTime)
 	}
+	return where, args
+}
 
-	query += " ORDER BY timestamp DESC"
+// QueryRecords retrieves a page of records based on criteria.
+// limit defaults to DefaultQueryLimit and is capped at MaxQueryLimit.
+func QueryRecords(toolName, startTime, endTime string, limit, offset int) (*RecordPage, error) {
+	db, err := conn()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		limit = MaxQueryLimit
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset %d: must not be negative", offset)
+	}
 
-	rows, err := DB.Query(query, args...)
+	where, args := recordFilter(toolName, startTime, endTime)
+	// id breaks timestamp ties so pages do not overlap; one extra row tells whether another page exists
+	query := "SELECT timestamp, tool_name, mcp_output FROM records" + where + " ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit+1, offset)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +386,7 @@ func QueryRecords(toolName, startTime, endTime string) ([]map[string]interface{}
 		return nil, err
 	}
 
-	var results []map[string]interface{}
+	page := &RecordPage{Records: []map[string]interface{}{}}
 	for rows.Next() {
 		columns := make([]interface{}, len(cols))
 		columnPointers := make([]interface{}, len(cols))
@@ -119,8 +408,156 @@ func QueryRecords(toolName, startTime, endTime string) ([]map[string]interface{}
 				m[colName] = val
 			}
 		}
-		results = append(results, m)
+		page.Records = append(page.Records, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Records) > limit {
+		page.Records = page.Records[:limit]
+		page.HasMore = true
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	return page, nil
+}
+
+// CountRecords returns how many records match the criteria
+func CountRecords(toolName, startTime, endTime string) (int, error) {
+	db, err := conn()
+	if err != nil {
+		return 0, err
+	}
+
+	where, args := recordFilter(toolName, startTime, endTime)
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM records"+where, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ErrNoFilter is returned by DeleteRecords when no filter is given, use ClearRecords to wipe the table
+var ErrNoFilter = errors.New("refusing to delete records without a tool name or time range")
+
+// DeleteRecords removes the records matching the criteria and returns how many were deleted.
+// At least one of toolName, startTime and endTime must be set.
+func DeleteRecords(toolName, startTime, endTime string) (int, error) {
+	if toolFilter(toolName) == "" && startTime == "" && endTime == "" {
+		return 0, ErrNoFilter
+	}
+	where, args := recordFilter(toolName, startTime, endTime)
+	return deleteRecords(where, args)
+}
+
+// ClearRecords removes every record and returns how many were deleted
+func ClearRecords() (int, error) {
+	return deleteRecords("", nil)
+}
+
+// deleteRecords runs a DELETE after writing out queued records, so records saved just before are matched too
+func deleteRecords(where string, args []interface{}) (int, error) {
+	db, err := conn()
+	if err != nil {
+		return 0, err
+	}
+	Flush()
+
+	res, err := db.Exec("DELETE FROM records"+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// Record is a single stored tool execution
+type Record struct {
+	ID        int64  `json:"id"`
+	Timestamp string `json:"timestamp"`
+	ToolName  string `json:"tool_name"`
+	Output    string `json:"mcp_output"`
+}
+
+// ErrRecordNotFound is returned by GetRecord when no record has the given ID
+var ErrRecordNotFound = errors.New("record not found")
+
+// ToolNames returns the distinct tool names that have stored records
+func ToolNames() ([]string, error) {
+	db, err := conn()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT DISTINCT tool_name FROM records ORDER BY tool_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// RecentRecords returns the newest records, optionally restricted to one tool
+func RecentRecords(toolName string, limit int) ([]Record, error) {
+	db, err := conn()
+	if err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	query := "SELECT id, timestamp, tool_name, mcp_output FROM records"
+	var args []interface{}
+	if toolName != "" {
+		query += " WHERE tool_name = ?"
+		args = append(args, toolName)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.ID, &r.Timestamp, &r.ToolName, &r.Output); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// GetRecord returns the record with the given ID
+func GetRecord(id int64) (*Record, error) {
+	db, err := conn()
+	if err != nil {
+		return nil, err
+	}
+
+	var r Record
+	err = db.QueryRow("SELECT id, timestamp, tool_name, mcp_output FROM records WHERE id = ?", id).
+		Scan(&r.ID, &r.Timestamp, &r.ToolName, &r.Output)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
 }
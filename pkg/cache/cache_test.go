@@ -0,0 +1,522 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSaveRecordFlush(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	const n = 250
+	for i := 0; i < n; i++ {
+		if err := SaveRecord("latency", fmt.Sprintf(`{"i": %d}`, i)); err != nil {
+			t.Fatalf("SaveRecord() error = %v", err)
+		}
+	}
+	Flush()
+
+	records, err := RecentRecords("latency", n+10)
+	if err != nil {
+		t.Fatalf("RecentRecords() error = %v", err)
+	}
+	if len(records) != n {
+		t.Errorf("RecentRecords() returned %d records, want %d", len(records), n)
+	}
+	// Newest first, so the last queued record comes back first
+	if len(records) > 0 && records[0].Output != fmt.Sprintf(`{"i": %d}`, n-1) {
+		t.Errorf("RecentRecords()[0] = %q, want the last saved record", records[0].Output)
+	}
+}
+
+func TestCloseWritesQueuedRecords(t *testing.T) {
+	dir := t.TempDir()
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		SaveRecord("traceroute", "out")
+	}
+	if err := Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := SaveRecord("traceroute", "out"); err != nil {
+		t.Errorf("SaveRecord() after Close() error = %v, want nil (cache disabled)", err)
+	}
+
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+	records, err := RecentRecords("traceroute", 100)
+	if err != nil {
+		t.Fatalf("RecentRecords() error = %v", err)
+	}
+	if len(records) != 10 {
+		t.Errorf("RecentRecords() returned %d records, want 10", len(records))
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+	db := mustConn(t)
+
+	const writers, perWriter = 8, 50
+	errs := make(chan error, writers*perWriter*2)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				// Direct inserts bypass the queue so connections contend for the write lock
+				_, err := db.Exec("INSERT INTO records (timestamp, tool_name, mcp_output) VALUES (?, ?, ?)", LocalTimeNow(), "concurrent", fmt.Sprintf("%d-%d", w, i))
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if _, err := RecentRecords("concurrent", 10); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent access error = %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM records WHERE tool_name = 'concurrent'").Scan(&count); err != nil {
+		t.Fatalf("count error = %v", err)
+	}
+	if count != writers*perWriter {
+		t.Errorf("inserted %d records, want %d", count, writers*perWriter)
+	}
+}
+
+func TestAggregateLatency(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	rows := []struct{ ts, tool, output string }{
+		{"20240101100000", "latency", `{"target":"8.8.8.8","avg_latency":"10.000 ms","jitter":"1 ms","packet_loss":"0%"}`},
+		{"20240101110000", "latency", `{"target":"8.8.8.8","avg_latency":"20.000 ms","jitter":"1 ms","packet_loss":"10%"}`},
+		{"20240101120000", "latency", `{"target":"8.8.8.8","avg_latency":"30.000 ms"}`},
+		{"20240101130000", "latency", `{"target":"1.1.1.1","avg_latency":"5.5 ms","packet_loss":"0%"}`},
+		{"20240101133000", "latency", `{"target":"10.0.0.9","avg_latency":"N/A","packet_loss":"100%"}`},
+		{"20240101140000", "latency", `{"avg_latency":"7 ms"}`},
+		{"20240101150000", "latency", `"Please specify the test mode"`},
+		{"20240101160000", "traceroute", `{"target":"8.8.8.8","avg_latency":"99 ms"}`},
+		{"20240301000000", "latency", `{"target":"8.8.8.8","avg_latency":"500 ms"}`},
+	}
+	var batch []pendingRecord
+	for _, r := range rows {
+		batch = append(batch, pendingRecord{timestamp: r.ts, toolName: r.tool, output: r.output})
+	}
+	insertBatch(mustConn(t), batch)
+
+	got, err := AggregateLatency("20240101000000", "20240131235959")
+	if err != nil {
+		t.Fatalf("AggregateLatency() error = %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("AggregateLatency() = %+v, want 4 targets", got)
+	}
+
+	down := got[1]
+	if down.Target != "10.0.0.9" || down.Runs != 1 || down.AvgLatencyMs != nil {
+		t.Errorf("10.0.0.9 aggregate = %+v, want 1 run without latency", down)
+	}
+	if down.AvgPacketLoss == nil || *down.AvgPacketLoss != 100 {
+		t.Errorf("10.0.0.9 avg packet loss = %v, want 100", down.AvgPacketLoss)
+	}
+
+	google := got[2]
+	if google.Target != "8.8.8.8" || google.Runs != 3 || *google.MinLatencyMs != 10 || *google.AvgLatencyMs != 20 || *google.MaxLatencyMs != 30 {
+		t.Errorf("8.8.8.8 aggregate = %+v, want 3 runs 10/20/30 ms", google)
+	}
+	if google.AvgPacketLoss == nil || *google.AvgPacketLoss != 5 {
+		t.Errorf("8.8.8.8 avg packet loss = %v, want 5", google.AvgPacketLoss)
+	}
+	if got[3].Target != unknownTarget || got[3].Runs != 1 {
+		t.Errorf("unknown target aggregate = %+v, want 1 run", got[3])
+	}
+}
+
+func TestMigrateLatencyColumns(t *testing.T) {
+	dir := t.TempDir()
+	old, err := sql.Open("sqlite", "file:"+filepath.Join(dir, "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"CREATE TABLE records (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp TEXT, tool_name TEXT, mcp_output TEXT)",
+		`INSERT INTO records (timestamp, tool_name, mcp_output) VALUES ('20240101100000', 'latency', '{"target":"8.8.8.8","avg_latency":"12.5 ms","jitter":"0.8 ms","packet_loss":"20%"}')`,
+		`INSERT INTO records (timestamp, tool_name, mcp_output) VALUES ('20240101110000', 'latency', '{"target":"8.8.8.8","avg_latency":"N/A","jitter":"N/A","packet_loss":"100%"}')`,
+		`INSERT INTO records (timestamp, tool_name, mcp_output) VALUES ('20240101120000', 'routes', '[]')`,
+	} {
+		if _, err := old.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old.Close()
+
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+	db := mustConn(t)
+
+	tests := []struct {
+		ts                string
+		avg, jitter, loss sql.NullFloat64
+	}{
+		{"20240101100000", sql.NullFloat64{Float64: 12.5, Valid: true}, sql.NullFloat64{Float64: 0.8, Valid: true}, sql.NullFloat64{Float64: 20, Valid: true}},
+		{"20240101110000", sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{Float64: 100, Valid: true}},
+		{"20240101120000", sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}},
+	}
+	for _, tt := range tests {
+		var avg, jitter, loss sql.NullFloat64
+		if err := db.QueryRow("SELECT avg_ms, jitter_ms, loss_pct FROM records WHERE timestamp = ?", tt.ts).Scan(&avg, &jitter, &loss); err != nil {
+			t.Fatalf("%s: %v", tt.ts, err)
+		}
+		if avg != tt.avg || jitter != tt.jitter || loss != tt.loss {
+			t.Errorf("%s: columns = %v/%v/%v, want %v/%v/%v", tt.ts, avg, jitter, loss, tt.avg, tt.jitter, tt.loss)
+		}
+	}
+
+	// A second Init finds the columns present and leaves the table alone
+	Close()
+	if err := Init(dir); err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+}
+
+func TestQueryRecordsPaging(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	var batch []pendingRecord
+	for i := 0; i < 5; i++ {
+		batch = append(batch, pendingRecord{timestamp: fmt.Sprintf("2024010110000%d", i), toolName: "latency", output: fmt.Sprintf(`{"i": %d}`, i)})
+	}
+	batch = append(batch, pendingRecord{timestamp: "20240101100009", toolName: "routes", output: "[]"})
+	insertBatch(mustConn(t), batch)
+
+	tests := []struct {
+		name          string
+		limit, offset int
+		wantFirst     string
+		wantLen       int
+		wantNext      int // 0 means no further page
+	}{
+		{"first page", 2, 0, "20240101100004", 2, 2},
+		{"middle page", 2, 2, "20240101100002", 2, 4},
+		{"last page", 2, 4, "20240101100000", 1, 0},
+		{"default limit", 0, 0, "20240101100004", 5, 0},
+		{"past the end", 2, 10, "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := QueryRecords("latency", "20240101000000", "", tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("QueryRecords() error = %v", err)
+			}
+			if len(page.Records) != tt.wantLen {
+				t.Fatalf("QueryRecords() returned %d records, want %d", len(page.Records), tt.wantLen)
+			}
+			if tt.wantLen > 0 && page.Records[0]["timestamp"] != tt.wantFirst {
+				t.Errorf("first record timestamp = %v, want %s", page.Records[0]["timestamp"], tt.wantFirst)
+			}
+			if page.HasMore != (tt.wantNext != 0) {
+				t.Errorf("HasMore = %v, want %v", page.HasMore, tt.wantNext != 0)
+			}
+			if tt.wantNext != 0 && (page.NextOffset == nil || *page.NextOffset != tt.wantNext) {
+				t.Errorf("NextOffset = %v, want %d", page.NextOffset, tt.wantNext)
+			}
+		})
+	}
+
+	if _, err := QueryRecords("", "", "", 10, -1); err == nil {
+		t.Error("QueryRecords() with negative offset should fail")
+	}
+
+	count, err := CountRecords("latency", "20240101100001", "")
+	if err != nil {
+		t.Fatalf("CountRecords() error = %v", err)
+	}
+	if count != 4 {
+		t.Errorf("CountRecords() = %d, want 4", count)
+	}
+}
+
+func TestQueryRecordsAllTools(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	insertBatch(mustConn(t), []pendingRecord{
+		{timestamp: "20240101140100", toolName: "latency", output: "l1"},
+		{timestamp: "20240101140300", toolName: "system_stats", output: "s1"},
+		{timestamp: "20240101140200", toolName: "latency", output: "l2"},
+		{timestamp: "20240101140400", toolName: "traceroute", output: "t1"},
+		{timestamp: "20240101150000", toolName: "system_stats", output: "s2"},
+	})
+
+	want := []string{"traceroute/t1", "system_stats/s1", "latency/l2", "latency/l1"}
+	for _, toolName := range []string{"", "all", "ALL"} {
+		page, err := QueryRecords(toolName, "20240101140000", "20240101140500", 0, 0)
+		if err != nil {
+			t.Fatalf("QueryRecords(%q) error = %v", toolName, err)
+		}
+		var got []string
+		for _, r := range page.Records {
+			got = append(got, fmt.Sprintf("%v/%v", r["tool_name"], r["mcp_output"]))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("QueryRecords(%q) = %v, want %v", toolName, got, want)
+		}
+	}
+
+	if _, err := DeleteRecords("all", "", ""); !errors.Is(err, ErrNoFilter) {
+		t.Errorf("DeleteRecords(\"all\") error = %v, want ErrNoFilter", err)
+	}
+}
+
+func TestDeleteRecords(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	insertBatch(mustConn(t), []pendingRecord{
+		{timestamp: "20240101100000", toolName: "latency", output: "{}"},
+		{timestamp: "20240101110000", toolName: "latency", output: "{}"},
+		{timestamp: "20240101120000", toolName: "routes", output: "[]"},
+		{timestamp: "20240102100000", toolName: "routes", output: "[]"},
+	})
+
+	if _, err := DeleteRecords("", "", ""); !errors.Is(err, ErrNoFilter) {
+		t.Fatalf("DeleteRecords() without filter error = %v, want ErrNoFilter", err)
+	}
+	if count, _ := CountRecords("", "", ""); count != 4 {
+		t.Fatalf("unfiltered DeleteRecords() removed records, %d left", count)
+	}
+
+	tests := []struct {
+		name                         string
+		toolName, startTime, endTime string
+		want                         int
+	}{
+		{"tool and range", "latency", "20240101105959", "", 1},
+		{"range only", "", "", "20240101235959", 2},
+		{"nothing matches", "latency", "", "", 0},
+	}
+	for _, tt := range tests {
+		got, err := DeleteRecords(tt.toolName, tt.startTime, tt.endTime)
+		if err != nil {
+			t.Fatalf("%s: DeleteRecords() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: DeleteRecords() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// Records still in the write queue are deleted too
+	if err := SaveRecord("latency", "{}"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ClearRecords()
+	if err != nil {
+		t.Fatalf("ClearRecords() error = %v", err)
+	}
+	if got != 2 {
+		t.Errorf("ClearRecords() = %d, want 2", got)
+	}
+}
+
+func TestInitTwice(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+
+	if err := Init(dir1); err != nil {
+		t.Fatalf("first Init() error = %v", err)
+	}
+	first, err := conn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveRecord("latency", "{}"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Init(dir2); err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+	defer Close()
+
+	if second, _ := conn(); second == first {
+		t.Fatal("second Init() kept the first handle")
+	}
+	if err := first.Ping(); err == nil {
+		t.Error("first handle is still open after the second Init()")
+	}
+
+	if err := SaveRecord("routes", "[]"); err != nil {
+		t.Fatal(err)
+	}
+	Flush()
+	if count, err := CountRecords("", "", ""); err != nil || count != 1 {
+		t.Errorf("CountRecords() in second dir = %d, %v, want 1", count, err)
+	}
+
+	// The record queued before the switch was written to the first database
+	old, err := sql.Open("sqlite", "file:"+filepath.Join(dir1, "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	var tool string
+	if err := old.QueryRow("SELECT tool_name FROM records").Scan(&tool); err != nil || tool != "latency" {
+		t.Errorf("first dir record = %q, %v, want latency", tool, err)
+	}
+}
+
+func TestInitNotWritable(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Init(filepath.Join(file, "cache"))
+	var notWritable *NotWritableError
+	if !errors.As(err, &notWritable) {
+		t.Fatalf("Init() error = %v, want *NotWritableError", err)
+	}
+	if Enabled() {
+		t.Error("cache is enabled after a failed Init()")
+	}
+}
+
+func TestSaveRecordTruncates(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+	SetMaxRecordSize(8)
+	defer SetMaxRecordSize(0)
+
+	SaveRecord("systemd_logs", "line one\nline two\n")
+	SaveRecord("systemd_logs", "short")
+	Flush()
+
+	records, err := RecentRecords("systemd_logs", 2)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("RecentRecords() = %v, %v", records, err)
+	}
+	if records[0].Output != "short" {
+		t.Errorf("short record = %q, want it unchanged", records[0].Output)
+	}
+	if want := "line one...[truncated 10 bytes]"; records[1].Output != want {
+		t.Errorf("long record = %q, want %q", records[1].Output, want)
+	}
+}
+
+func TestAggregateLatencyDownRuns(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	insertBatch(mustConn(t), []pendingRecord{
+		{timestamp: "20240101100000", toolName: "latency", output: `{"target":"10.0.0.1","avg_latency":"2.000 ms","packet_loss":"0%"}`},
+		{timestamp: "20240101110000", toolName: "latency", output: `{"target":"10.0.0.1","avg_latency":"N/A","packet_loss":"100%"}`},
+		{timestamp: "20240101120000", toolName: "latency", output: `{"target":"10.0.0.1","avg_latency":"4.000 ms","packet_loss":"50%"}`},
+	})
+
+	got, err := AggregateLatency("", "")
+	if err != nil {
+		t.Fatalf("AggregateLatency() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("AggregateLatency() = %+v, want 1 target", got)
+	}
+	a := got[0]
+	if a.Runs != 3 || a.LastRun != "20240101120000" {
+		t.Errorf("aggregate = %+v, want 3 runs up to 20240101120000", a)
+	}
+	if a.MinLatencyMs == nil || *a.MinLatencyMs != 2 || *a.AvgLatencyMs != 3 || *a.MaxLatencyMs != 4 {
+		t.Errorf("latency = %v/%v/%v, want 2/3/4 ms over the runs with replies", a.MinLatencyMs, a.AvgLatencyMs, a.MaxLatencyMs)
+	}
+	if a.AvgPacketLoss == nil || *a.AvgPacketLoss != 50 {
+		t.Errorf("avg packet loss = %v, want 50 including the down run", a.AvgPacketLoss)
+	}
+}
+
+func TestCloseDuringUse(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	// Run with -race: Close must not race the callers still saving and querying
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				SaveRecord("latency", "{}")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				QueryRecords("", "", "", 10, 0)
+				CountRecords("", "", "")
+			}
+		}()
+	}
+	if err := Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	wg.Wait()
+
+	if _, err := QueryRecords("", "", "", 10, 0); err == nil {
+		t.Error("QueryRecords() after Close() error = nil, want database not initialized")
+	}
+	if err := SaveRecord("latency", "{}"); err != nil {
+		t.Errorf("SaveRecord() after Close() error = %v, want the record skipped", err)
+	}
+}
+
+// mustConn returns the database opened by Init
+func mustConn(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := conn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LatencyAggregate summarizes the cached latency runs of one target.
+// Latency figures are taken over each run's average RTT, so runs without replies only count towards
+// Runs and AvgPacketLoss; they are omitted when no run got a reply.
+type LatencyAggregate struct {
+	Target        string   `json:"target"`
+	Runs          int      `json:"runs"`
+	MinLatencyMs  *float64 `json:"min_latency_ms,omitempty"`
+	AvgLatencyMs  *float64 `json:"avg_latency_ms,omitempty"`
+	MaxLatencyMs  *float64 `json:"max_latency_ms,omitempty"`
+	AvgPacketLoss *float64 `json:"avg_packet_loss,omitempty"` // Percent, only standard mode runs report loss
+	FirstRun      string   `json:"first_run"`
+	LastRun       string   `json:"last_run"`
+}
+
+// unknownTarget groups records saved before the target was stored
+const unknownTarget = "unknown"
+
+// latencyRecord is the stored output of the latency tool
+type latencyRecord struct {
+	Target     string `json:"target"`
+	AvgLatency string `json:"avg_latency"`
+	Jitter     string `json:"jitter"`
+	PacketLoss string `json:"packet_loss"`
+}
+
+// AggregateLatency computes per-target latency and packet loss stats from cached latency records.
+// startTime and endTime (YYYYMMDDhhmmss) are optional bounds.
+func AggregateLatency(startTime, endTime string) ([]LatencyAggregate, error) {
+	db, err := conn()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT COALESCE(NULLIF(json_extract(mcp_output, '$.target'), ''), ?) AS target,
+		COUNT(*), MIN(avg_ms), AVG(avg_ms), MAX(avg_ms), AVG(loss_pct), MIN(timestamp), MAX(timestamp)
+		FROM records WHERE tool_name = 'latency' AND (avg_ms IS NOT NULL OR loss_pct IS NOT NULL)`
+	args := []interface{}{unknownTarget}
+	if startTime != "" {
+		query += " AND timestamp >= ?"
+		args = append(args, startTime)
+	}
+	if endTime != "" {
+		query += " AND timestamp <= ?"
+		args = append(args, endTime)
+	}
+	query += " GROUP BY target ORDER BY target"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []LatencyAggregate{}
+	for rows.Next() {
+		var a LatencyAggregate
+		var minMs, avgMs, maxMs, loss sql.NullFloat64
+		if err := rows.Scan(&a.Target, &a.Runs, &minMs, &avgMs, &maxMs, &loss, &a.FirstRun, &a.LastRun); err != nil {
+			return nil, err
+		}
+		a.MinLatencyMs = roundedPtr(minMs)
+		a.AvgLatencyMs = roundedPtr(avgMs)
+		a.MaxLatencyMs = roundedPtr(maxMs)
+		a.AvgPacketLoss = roundedPtr(loss)
+		results = append(results, a)
+	}
+	return results, rows.Err()
+}
+
+// latencyColumns extracts the numeric columns stored alongside a latency record.
+// Other tools, unparseable output and missing values yield NULLs.
+func latencyColumns(toolName, output string) (avg, jitter, loss sql.NullFloat64) {
+	if toolName != "latency" {
+		return
+	}
+	var rec latencyRecord
+	if err := json.Unmarshal([]byte(output), &rec); err != nil {
+		return
+	}
+	if v, ok := parseMs(rec.AvgLatency); ok {
+		avg = sql.NullFloat64{Float64: v, Valid: true}
+	}
+	if v, ok := parseMs(rec.Jitter); ok {
+		jitter = sql.NullFloat64{Float64: v, Valid: true}
+	}
+	if v, ok := parsePercent(rec.PacketLoss); ok {
+		loss = sql.NullFloat64{Float64: v, Valid: true}
+	}
+	return
+}
+
+// migrateLatencyColumns adds the numeric latency columns to databases created before they existed
+// and fills them in for the latency records already stored
+func migrateLatencyColumns(db *sql.DB) error {
+	existing := make(map[string]bool)
+	rows, err := db.Query("PRAGMA table_info(records)")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	added := false
+	for _, col := range []string{"avg_ms", "jitter_ms", "loss_pct"} {
+		if existing[col] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE records ADD COLUMN " + col + " REAL"); err != nil {
+			return err
+		}
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	return backfillLatencyColumns(db)
+}
+
+// backfillLatencyColumns populates the numeric columns of existing latency records
+func backfillLatencyColumns(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, mcp_output FROM records WHERE tool_name = 'latency' AND avg_ms IS NULL")
+	if err != nil {
+		return err
+	}
+	type update struct {
+		id                int64
+		avg, jitter, loss sql.NullFloat64
+	}
+	var updates []update
+	for rows.Next() {
+		var id int64
+		var output string
+		if err := rows.Scan(&id, &output); err != nil {
+			rows.Close()
+			return err
+		}
+		u := update{id: id}
+		u.avg, u.jitter, u.loss = latencyColumns("latency", output)
+		updates = append(updates, u)
+	}
+	rows.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, u := range updates {
+		if _, err := tx.Exec("UPDATE records SET avg_ms = ?, jitter_ms = ?, loss_pct = ? WHERE id = ?", u.avg, u.jitter, u.loss, u.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// parseMs parses "14.567 ms"
+func parseMs(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "ms")), 64)
+	return v, err == nil
+}
+
+// parsePercent parses "2.5%"
+func parsePercent(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	return v, err == nil
+}
+
+// roundedPtr returns v rounded to three decimal places, nil if it is NULL
+func roundedPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	r := round3(v.Float64)
+	return &r
+}
+
+// round3 rounds to three decimal places
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -3,77 +3,285 @@ package diagnostics
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// errProbeTimeout is returned by getCommandOutput when a command exceeds its probe timeout
+var errProbeTimeout = errors.New("timed out")
+
+// oomKilledRegex matches "Out of memory: Killed process 1234 (java)"
+var oomKilledRegex = regexp.MustCompile(`Killed process (\d+) \(([^)]+)\)`)
+
+// oomKillRegex matches "oom-kill:constraint=...,task=java,pid=1234,uid=0"
+var oomKillRegex = regexp.MustCompile(`oom-kill:.*task=([^,]+),pid=(\d+)`)
+
 // DiagnosticsResult holds the result of all diagnostic checks
 type DiagnosticsResult struct {
-	JournalctlErrors []string `json:"journalctl_errors"`
-	SyslogErrors     []string `json:"syslog_errors"`
-	Dmesg            []string `json:"dmesg"`
-	LoginHistory     []string `json:"login_history"`
-	FailedLogins     []string `json:"failed_logins"`
+	JournalctlErrors  []string     `json:"journalctl_errors"`
+	SyslogErrors      []string     `json:"syslog_errors"`
+	SyslogSource      string       `json:"syslog_source"`          // File read for SyslogErrors, or "journald"
+	SyslogFiles       []string     `json:"syslog_files,omitempty"` // Syslog and rotated files read, newest first, when rotations are scanned
+	Dmesg             []string     `json:"dmesg"`
+	LoginHistory      []string     `json:"login_history"` // Raw last output
+	FailedLogins      []string     `json:"failed_logins"` // Raw lastb output
+	LoginEvents       []LoginEvent `json:"login_events"`
+	FailedLoginEvents []LoginEvent `json:"failed_login_events"`
+	OOMEvents         []string     `json:"oom_events"`
+	DiskWarnings      []string     `json:"disk_warnings"`
+	FlappingServices  []string     `json:"flapping_services"` // Crash-looping or failed services with restart count and last result
 }
 
-// RunDiagnostics gathers system diagnostic information
-func RunDiagnostics() (*DiagnosticsResult, error) {
-	res := &DiagnosticsResult{}
-	var err error
+// DiagnosticsOptions sets how many entries each source returns
+// Zero values fall back to the defaults
+type DiagnosticsOptions struct {
+	JournalLines     int           // journalctl error entries (default 100)
+	SyslogLines      int           // syslog error lines (default 100)
+	SyslogRotations  int           // rotated syslog files to scan as well, e.g. syslog.1 and syslog.2.gz (default 0)
+	SyslogPatterns   []string      // keywords a syslog line must contain (default DefaultSyslogPatterns)
+	SyslogSeverity   string        // only report keywords of this severity or worse, 0-7 or a name such as crit (default all)
+	DmesgLines       int           // dmesg entries (default 50)
+	LoginEntries     int           // last / lastb entries (default 10)
+	DiskThreshold    float64       // used-space percentage that triggers a disk warning (default 90)
+	RestartThreshold int           // restart count that marks a service as crash-looping (default 3)
+	ProbeTimeout     time.Duration // time each source may take before it is reported as timed out (default 10s)
+}
 
-	// 1. Journalctl Errors (last 100 entries, priority err(3))
-	res.JournalctlErrors, err = getCommandOutput("journalctl", "-p", "3", "-n", "100", "--no-pager")
+// DefaultProbeTimeout and MaxProbeTimeout bound a single diagnostics source
+const (
+	DefaultProbeTimeout = 10 * time.Second
+	MaxProbeTimeout     = 60 * time.Second
+)
+
+// MaxEntries caps every per-source count in DiagnosticsOptions
+const MaxEntries = 1000
+
+// withDefaults returns a copy of opts with unset counts replaced by defaults and large counts capped
+func (opts DiagnosticsOptions) withDefaults() DiagnosticsOptions {
+	opts.JournalLines = clampCount(opts.JournalLines, 100)
+	opts.SyslogLines = clampCount(opts.SyslogLines, 100)
+	opts.DmesgLines = clampCount(opts.DmesgLines, 50)
+	opts.LoginEntries = clampCount(opts.LoginEntries, 10)
+	if opts.SyslogRotations < 0 {
+		opts.SyslogRotations = 0
+	}
+	if opts.SyslogRotations > MaxSyslogRotations {
+		opts.SyslogRotations = MaxSyslogRotations
+	}
+	if opts.DiskThreshold <= 0 || opts.DiskThreshold > 100 {
+		opts.DiskThreshold = DefaultDiskThreshold
+	}
+	if opts.RestartThreshold <= 0 {
+		opts.RestartThreshold = DefaultRestartThreshold
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = DefaultProbeTimeout
+	}
+	if opts.ProbeTimeout > MaxProbeTimeout {
+		opts.ProbeTimeout = MaxProbeTimeout
+	}
+	return opts
+}
+
+func clampCount(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	if n > MaxEntries {
+		return MaxEntries
+	}
+	return n
+}
+
+// RunDiagnostics gathers system diagnostic information.
+// The probes are independent and run concurrently, each writing only its own fields of the result.
+// Each probe is bounded by opts.ProbeTimeout so a hung command cannot block the others.
+func RunDiagnostics(ctx context.Context, opts DiagnosticsOptions) (*DiagnosticsResult, error) {
+	opts = opts.withDefaults()
+	matcher, err := newLineMatcher(opts.SyslogPatterns, opts.SyslogSeverity)
 	if err != nil {
-		res.JournalctlErrors = []string{fmt.Sprintf("Error running journalctl: %v", err)}
-	} else if len(res.JournalctlErrors) == 0 {
-		res.JournalctlErrors = []string{"No journalctl error logs found."}
+		return nil, err
+	}
+	res := &DiagnosticsResult{}
+	var kernelOOM []string
+
+	var wg sync.WaitGroup
+	probe := func(f func(ctx context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, opts.ProbeTimeout)
+			defer cancel()
+			f(probeCtx)
+		}()
 	}
 
-	// 2. Syslog Errors (read file, grep "error" (insensitive), last 100)
-	// Typical path: /var/log/syslog. On some systems (RHEL/CentOS) it might be /var/log/messages.
-	// We will try /var/log/syslog first.
-	res.SyslogErrors = getSyslogErrors("/var/log/syslog", 100)
+	// 1. Journalctl Errors (last N entries, priority err(3))
+	probe(func(ctx context.Context) {
+		var err error
+		res.JournalctlErrors, err = getCommandOutput(ctx, "journalctl", "-p", "3", "-n", strconv.Itoa(opts.JournalLines), "--no-pager")
+		if err != nil {
+			res.JournalctlErrors = []string{fmt.Sprintf("Error running journalctl: %v", err)}
+		} else if len(res.JournalctlErrors) == 0 {
+			res.JournalctlErrors = []string{"No journalctl error logs found."}
+		}
+	})
+
+	// 2. Syslog Errors (read file and optionally its rotations, keep lines matching the error keywords, last N)
+	// Debian/Ubuntu use /var/log/syslog, RHEL/CentOS/Fedora use /var/log/messages.
+	// If neither exists journald is the only logger, so fall back to journalctl -p err.
+	probe(func(ctx context.Context) {
+		if path := findSyslogPath(); path != "" {
+			var files []string
+			res.SyslogSource = path
+			res.SyslogErrors, files = getSyslogErrors(ctx, path, opts.SyslogLines, opts.SyslogRotations, matcher)
+			if opts.SyslogRotations > 0 {
+				res.SyslogFiles = files
+			}
+			return
+		}
+		var err error
+		priority := "err"
+		if opts.SyslogSeverity != "" {
+			priority = strconv.Itoa(matcher.maxSeverity)
+		}
+		res.SyslogSource = "journald"
+		res.SyslogErrors, err = getCommandOutput(ctx, "journalctl", "-p", priority, "-n", strconv.Itoa(opts.SyslogLines), "--no-pager")
+		if err != nil {
+			res.SyslogErrors = []string{fmt.Sprintf("No syslog file found and journalctl failed: %v", err)}
+		} else if len(res.SyslogErrors) == 0 {
+			res.SyslogErrors = []string{"No syslog file found and no journalctl error logs found."}
+		}
+	})
 
-	// 3. Dmesg (last 50 entries)
+	// 3. Dmesg (last N entries)
 	// dmesg might require root or specific capabilities.
-	// Using "dmesg | tail -n 50" logic
-	dmesgOut, err := getCommandOutput("dmesg")
-	if err != nil {
-		res.Dmesg = []string{fmt.Sprintf("Error running dmesg: %v", err)}
-	} else {
-		if len(dmesgOut) > 50 {
-			res.Dmesg = dmesgOut[len(dmesgOut)-50:]
+	// Using "dmesg | tail -n N" logic
+	probe(func(ctx context.Context) {
+		dmesgOut, err := getCommandOutput(ctx, "dmesg")
+		if err != nil {
+			res.Dmesg = []string{fmt.Sprintf("Error running dmesg: %v", err)}
+			return
+		}
+		// Scan the full buffer for OOM kills before it is truncated
+		res.OOMEvents = parseOOMEvents(dmesgOut)
+		if len(dmesgOut) > opts.DmesgLines {
+			res.Dmesg = dmesgOut[len(dmesgOut)-opts.DmesgLines:]
 		} else {
 			res.Dmesg = dmesgOut
 		}
-	}
+	})
 
-	// 4. Last (Login History), last 10
-	res.LoginHistory, err = getCommandOutput("last", "-n", "10")
-	if err != nil {
-		res.LoginHistory = []string{fmt.Sprintf("Error running last: %v", err)}
-	}
+	// 4. Last (Login History), last N
+	// -w prints full user and host names instead of truncating them
+	probe(func(ctx context.Context) {
+		var err error
+		res.LoginHistory, err = getCommandOutput(ctx, "last", "-w", "-n", strconv.Itoa(opts.LoginEntries))
+		res.LoginEvents = parseLast(res.LoginHistory)
+		if err != nil {
+			res.LoginHistory = []string{fmt.Sprintf("Error running last: %v", err)}
+		}
+	})
 
-	// 5. Lastb (Failed Login Attempts), last 10
+	// 5. Lastb (Failed Login Attempts), last N
 	// This usually requires root reading /var/log/btmp
-	res.FailedLogins, err = getCommandOutput("lastb", "-n", "10")
-	if err != nil {
-		res.FailedLogins = []string{fmt.Sprintf("Error running lastb: %v", err)}
+	probe(func(ctx context.Context) {
+		var err error
+		res.FailedLogins, err = getCommandOutput(ctx, "lastb", "-w", "-n", strconv.Itoa(opts.LoginEntries))
+		res.FailedLoginEvents = parseLast(res.FailedLogins)
+		if err != nil {
+			res.FailedLogins = []string{fmt.Sprintf("Error running lastb: %v", err)}
+		}
+	})
+
+	// 6. OOM Killer events, dmesg may have rotated so the kernel journal is checked too.
+	// They are merged with the dmesg events once every probe is done.
+	probe(func(ctx context.Context) {
+		if kernelOut, err := getCommandOutput(ctx, "journalctl", "-k", "-g", "Out of memory|oom-kill", "-n", "200", "--no-pager"); err == nil {
+			kernelOOM = parseOOMEvents(kernelOut)
+		}
+	})
+
+	// 7. Disk space warnings for filesystems over the threshold
+	probe(func(ctx context.Context) {
+		res.DiskWarnings = getDiskWarnings(ctx, opts.DiskThreshold)
+	})
+
+	// 8. Crash-looping services, restarted often or failed / waiting for the next automatic restart
+	probe(func(ctx context.Context) {
+		res.FlappingServices = getFlappingServices(ctx, opts.RestartThreshold)
+	})
+
+	wg.Wait()
+
+	res.OOMEvents = mergeOOMEvents(res.OOMEvents, kernelOOM)
+	if len(res.OOMEvents) == 0 {
+		res.OOMEvents = []string{"No OOM killer events found."}
 	}
 
 	return res, nil
 }
 
-// getCommandOutput executes a command and returns lines as a slice
-func getCommandOutput(name string, args ...string) ([]string, error) {
-	cmd := exec.Command(name, args...)
+// parseOOMEvents extracts OOM killer victims from kernel log lines.
+// Each event is formatted as "<name> (pid=<pid>): <line>"; one event per victim PID is kept.
+func parseOOMEvents(lines []string) []string {
+	var events []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		var name, pid string
+		if m := oomKilledRegex.FindStringSubmatch(line); m != nil && strings.Contains(line, "Out of memory") {
+			pid, name = m[1], m[2]
+		} else if m := oomKillRegex.FindStringSubmatch(line); m != nil {
+			name, pid = m[1], m[2]
+		} else {
+			continue
+		}
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		events = append(events, fmt.Sprintf("%s (pid=%s): %s", name, pid, strings.TrimSpace(line)))
+	}
+	return events
+}
+
+// mergeOOMEvents appends events from extra whose victim is not already in base
+func mergeOOMEvents(base, extra []string) []string {
+	seen := make(map[string]bool)
+	for _, e := range base {
+		seen[strings.SplitN(e, ":", 2)[0]] = true
+	}
+	for _, e := range extra {
+		key := strings.SplitN(e, ":", 2)[0]
+		if !seen[key] {
+			seen[key] = true
+			base = append(base, e)
+		}
+	}
+	return base
+}
+
+// getCommandOutput executes a command and returns lines as a slice.
+// When ctx expires the command is killed and a "timed out" error is returned.
+func getCommandOutput(ctx context.Context, name string, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	// Do not wait for children of a killed command that keep the output pipes open
+	cmd.WaitDelay = time.Second
 
 	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errProbeTimeout
+		}
 		// return partial output if available? Or just error.
 		// For diagnostics, maybe return error but also check stderr?
 		return nil, fmt.Errorf("%w: %s", err, stderr.String())
@@ -86,30 +294,3 @@ func getCommandOutput(name string, args ...string) ([]string, error) {
 	}
 	return lines, nil
 }
-
-// getSyslogErrors reads the syslog file and filters for "error"
-func getSyslogErrors(path string, count int) []string {
-	file, err := os.Open(path)
-	if err != nil {
-		return []string{fmt.Sprintf("Could not open %s: %v", path, err)}
-	}
-	defer file.Close()
-
-	var matchedLines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(strings.ToLower(line), "error") {
-			matchedLines = append(matchedLines, line)
-		}
-	}
-
-	if len(matchedLines) == 0 {
-		return []string{fmt.Sprintf("No error lines found in %s", path)}
-	}
-
-	if len(matchedLines) > count {
-		return matchedLines[len(matchedLines)-count:]
-	}
-	return matchedLines
-}
@@ -0,0 +1,110 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+func TestParseOOMEvents(t *testing.T) {
+	lines := []string{
+		"[12345.678901] eth0: link up",
+		"[23456.100000] oom-kill:constraint=CONSTRAINT_NONE,nodemask=(null),cpuset=/,mems_allowed=0,global_oom,task_memcg=/system.slice/java.service,task=java,pid=4242,uid=1000",
+		"[23456.100200] Out of memory: Killed process 4242 (java) total-vm:8123456kB, anon-rss:3987654kB, file-rss:0kB, shmem-rss:0kB, UID:1000 pgtables:8000kB oom_score_adj:0",
+		"[30000.000000] Out of memory: Killed process 977 (mysqld) total-vm:2000000kB, anon-rss:1500000kB",
+	}
+
+	expected := []string{
+		"java (pid=4242): " + lines[1],
+		"mysqld (pid=977): " + lines[3],
+	}
+
+	got := parseOOMEvents(lines)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseOOMEvents() = %v, want %v", got, expected)
+	}
+
+	if got := parseOOMEvents([]string{"[1.0] nothing to see"}); got != nil {
+		t.Errorf("parseOOMEvents() = %v, want nil", got)
+	}
+}
+
+func TestParseFlappingServices(t *testing.T) {
+	output := `Id=nginx.service
+NRestarts=12
+ActiveState=activating
+SubState=auto-restart
+Result=exit-code
+
+Id=ssh.service
+NRestarts=0
+ActiveState=active
+SubState=running
+Result=success
+
+Id=backup.service
+NRestarts=0
+ActiveState=failed
+SubState=failed
+Result=timeout
+
+Id=worker.service
+NRestarts=4
+ActiveState=active
+SubState=running
+Result=success
+`
+	got := parseFlappingServices(output, 3)
+	want := []string{
+		"nginx.service: 12 restarts, activating (auto-restart), last result exit-code",
+		"worker.service: 4 restarts, active (running), last result success",
+		"backup.service: 0 restarts, failed (failed), last result timeout",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseFlappingServices() = %+v, want %d services", got, len(want))
+	}
+	for i, svc := range got {
+		if s := describeFlapping(svc); s != want[i] {
+			t.Errorf("service %d = %q, want %q", i, s, want[i])
+		}
+	}
+}
+
+func TestGetCommandOutputTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := getCommandOutput(ctx, "sleep", "5")
+	if !errors.Is(err, errProbeTimeout) {
+		t.Fatalf("getCommandOutput() error = %v, want %v", err, errProbeTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("getCommandOutput() returned after %s, want it to stop at the timeout", elapsed)
+	}
+}
+
+func TestSkipDiskWarning(t *testing.T) {
+	tests := []struct {
+		name string
+		part disk.PartitionStat
+		want bool
+	}{
+		{name: "root", part: disk.PartitionStat{Mountpoint: "/", Fstype: "ext4", Opts: []string{"rw", "relatime"}}},
+		{name: "snap", part: disk.PartitionStat{Mountpoint: "/snap/core22/1380", Fstype: "squashfs", Opts: []string{"ro", "nodev"}}, want: true},
+		{name: "iso", part: disk.PartitionStat{Mountpoint: "/media/cdrom", Fstype: "iso9660"}, want: true},
+		{name: "read-only mount", part: disk.PartitionStat{Mountpoint: "/boot/efi", Fstype: "vfat", Opts: []string{"ro"}}, want: true},
+		{name: "tmpfs can fill up", part: disk.PartitionStat{Mountpoint: "/dev/shm", Fstype: "tmpfs", Opts: []string{"rw"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipDiskWarning(tt.part); got != tt.want {
+				t.Errorf("skipDiskWarning() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashton2914/mcp-netutil/pkg/system"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// DefaultDiskThreshold is the used-space percentage above which a filesystem is reported
+const DefaultDiskThreshold = 90.0
+
+// imageFilesystems are read-only image and pseudo filesystems that always look full, e.g. snap packages on squashfs
+var imageFilesystems = map[string]bool{
+	"squashfs": true, "iso9660": true, "udf": true, "cramfs": true, "erofs": true, "romfs": true,
+	"proc": true, "sysfs": true, "devpts": true, "cgroup": true, "cgroup2": true, "nsfs": true,
+}
+
+// skipDiskWarning reports whether a partition cannot fill up: read-only mounts and image filesystems
+func skipDiskWarning(part disk.PartitionStat) bool {
+	if imageFilesystems[part.Fstype] {
+		return true
+	}
+	for _, opt := range part.Opts {
+		if opt == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// getDiskWarnings lists mounted filesystems whose usage is at or above threshold percent
+func getDiskWarnings(ctx context.Context, threshold float64) []string {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return []string{fmt.Sprintf("Error listing partitions: %v", err)}
+	}
+
+	var warnings []string
+	seen := make(map[string]bool)
+	for _, part := range partitions {
+		if seen[part.Mountpoint] || skipDiskWarning(part) {
+			continue
+		}
+		seen[part.Mountpoint] = true
+
+		usage, err := disk.UsageWithContext(ctx, part.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue // Skip mounts we can't stat
+		}
+		if usage.UsedPercent >= threshold {
+			warnings = append(warnings, fmt.Sprintf("%s at %.0f%% (%s free)", part.Mountpoint, usage.UsedPercent, system.HumanizeBytes(float64(usage.Free))))
+		}
+	}
+
+	if len(warnings) == 0 {
+		return []string{fmt.Sprintf("No filesystems above %.0f%% usage.", threshold)}
+	}
+	return warnings
+}
@@ -0,0 +1,65 @@
+package diagnostics
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LoginEvent is a single session reported by last or a failed attempt reported by lastb
+type LoginEvent struct {
+	User          string `json:"user"`
+	TTY           string `json:"tty"`
+	FromHost      string `json:"from_host,omitempty"`
+	LoginTime     string `json:"login_time"`       // As printed by last, e.g. "Mon Oct 14 09:12"
+	Logout        string `json:"logout,omitempty"` // Logout time, or "down", "crash", "gone - no logout"
+	Duration      string `json:"duration,omitempty"`
+	StillLoggedIn bool   `json:"still_logged_in"`
+}
+
+// lastTimeRegex matches the login time column, e.g. "Mon Oct 14 09:12" or "Tue Oct  1 09:12"
+var lastTimeRegex = regexp.MustCompile(`\b(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun) (?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ \d]\d \d{2}:\d{2}\b`)
+
+// lastDurationRegex matches the session length, e.g. "(00:33)" or "(1+02:00)"
+var lastDurationRegex = regexp.MustCompile(`\(([^)]+)\)\s*$`)
+
+// parseLast parses the default output of last and lastb.
+// The host column may be empty, so columns are located relative to the login time rather than split by position.
+// Lines that do not look like a session, such as the "wtmp begins" footer, are skipped.
+func parseLast(lines []string) []LoginEvent {
+	events := make([]LoginEvent, 0, len(lines))
+	for _, line := range lines {
+		loc := lastTimeRegex.FindStringIndex(line)
+		if loc == nil || strings.HasPrefix(strings.TrimSpace(line), "wtmp begins") || strings.HasPrefix(strings.TrimSpace(line), "btmp begins") {
+			continue
+		}
+
+		fields := strings.Fields(line[:loc[0]])
+		if len(fields) < 2 {
+			continue
+		}
+		ev := LoginEvent{User: fields[0], TTY: fields[1], LoginTime: line[loc[0]:loc[1]]}
+		rest := fields[2:]
+		// Reboot records use "system boot" as the tty and the kernel version as the host
+		if ev.TTY == "system" && len(rest) > 0 && rest[0] == "boot" {
+			ev.TTY = "system boot"
+			rest = rest[1:]
+		}
+		ev.FromHost = strings.Join(rest, " ")
+
+		status := strings.TrimSpace(line[loc[1]:])
+		if m := lastDurationRegex.FindStringSubmatch(status); m != nil {
+			ev.Duration = m[1]
+			status = strings.TrimSpace(status[:len(status)-len(m[0])])
+		}
+		switch {
+		case strings.HasPrefix(status, "still logged in"), strings.HasPrefix(status, "still running"):
+			ev.StillLoggedIn = true
+		case strings.HasPrefix(status, "gone - no logout"):
+			ev.Logout = "gone - no logout"
+		case strings.HasPrefix(status, "- "):
+			ev.Logout = strings.TrimSpace(strings.TrimPrefix(status, "- "))
+		}
+		events = append(events, ev)
+	}
+	return events
+}
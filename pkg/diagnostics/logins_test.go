@@ -0,0 +1,39 @@
+package diagnostics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLast(t *testing.T) {
+	lines := []string{
+		"root     pts/0        192.168.1.10     Mon Oct 14 10:00   still logged in",
+		"alice    pts/1        10.0.0.5         Mon Oct 14 09:12 - 09:45  (00:33)",
+		"bob      tty1                          Sun Oct 13 22:00 - down   (10:00)",
+		"carol    pts/2        host.example.com Sun Oct 13 20:00 - crash  (1+02:00)",
+		"dave     pts/3        198.51.100.7     Tue Oct  1 19:00   gone - no logout",
+		"reboot   system boot  6.8.0-45-generic Mon Oct 14 08:00   still running",
+		"erin     ssh:notty    203.0.113.9      Mon Oct 14 03:12 - 03:12  (00:00)",
+		"",
+		"wtmp begins Mon Sep  8 00:00:00 2025",
+	}
+
+	want := []LoginEvent{
+		{User: "root", TTY: "pts/0", FromHost: "192.168.1.10", LoginTime: "Mon Oct 14 10:00", StillLoggedIn: true},
+		{User: "alice", TTY: "pts/1", FromHost: "10.0.0.5", LoginTime: "Mon Oct 14 09:12", Logout: "09:45", Duration: "00:33"},
+		{User: "bob", TTY: "tty1", LoginTime: "Sun Oct 13 22:00", Logout: "down", Duration: "10:00"},
+		{User: "carol", TTY: "pts/2", FromHost: "host.example.com", LoginTime: "Sun Oct 13 20:00", Logout: "crash", Duration: "1+02:00"},
+		{User: "dave", TTY: "pts/3", FromHost: "198.51.100.7", LoginTime: "Tue Oct  1 19:00", Logout: "gone - no logout"},
+		{User: "reboot", TTY: "system boot", FromHost: "6.8.0-45-generic", LoginTime: "Mon Oct 14 08:00", StillLoggedIn: true},
+		{User: "erin", TTY: "ssh:notty", FromHost: "203.0.113.9", LoginTime: "Mon Oct 14 03:12", Logout: "03:12", Duration: "00:00"},
+	}
+
+	got := parseLast(lines)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLast() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := parseLast(nil); got == nil || len(got) != 0 {
+		t.Errorf("parseLast(nil) = %#v, want an empty slice", got)
+	}
+}
@@ -0,0 +1,93 @@
+package diagnostics
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultSyslogPatterns are the keywords a syslog line must contain to be reported
+var DefaultSyslogPatterns = []string{"error", "failed", "failure", "fatal", "panic", "segfault", "critical", "call trace"}
+
+// keywordSeverities is the syslog severity each default keyword implies, other keywords count as err
+var keywordSeverities = map[string]int{
+	"panic":      2,
+	"fatal":      2,
+	"segfault":   2,
+	"critical":   2,
+	"call trace": 2,
+	"error":      3,
+	"failed":     3,
+	"failure":    3,
+}
+
+// severityLevels maps syslog severity names to their level
+var severityLevels = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3, "warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// benignRegex matches counts of zero errors such as "0 errors", "no failures" or "errors=0",
+// which are removed from a line before it is matched
+var benignRegex = regexp.MustCompile(`(?i)\b(?:0|no|zero|without)\s+(?:errors?|failures?|failed)\b|\b(?:errors?|failures?|failed)\s*[=:]\s*0\b`)
+
+// lineMatcher selects the syslog lines reported by diagnostics
+type lineMatcher struct {
+	keywords    *regexp.Regexp
+	maxSeverity int // Only keywords of this severity or more severe match
+}
+
+// newLineMatcher builds a matcher for case-insensitive keywords that start a word, e.g. "error" matches
+// "Error:" and "errors" but not "stderr". Empty patterns use DefaultSyslogPatterns, an empty severity allows every keyword.
+func newLineMatcher(patterns []string, severity string) (*lineMatcher, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultSyslogPatterns
+	}
+	maxSeverity := 7
+	if severity != "" {
+		level, ok := severityLevels[strings.ToLower(severity)]
+		if n, err := strconv.Atoi(severity); err == nil && n >= 0 && n <= 7 {
+			level, ok = n, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid severity '%s'. Allowed severities: 0-7 or emerg, alert, crit, err, warning, notice, info, debug", severity)
+		}
+		maxSeverity = level
+	}
+
+	var quoted []string
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			quoted = append(quoted, regexp.QuoteMeta(strings.ToLower(p)))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil, fmt.Errorf("no syslog patterns given")
+	}
+	return &lineMatcher{
+		keywords:    regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)`),
+		maxSeverity: maxSeverity,
+	}, nil
+}
+
+// match reports whether line contains a keyword of the allowed severity outside a benign zero count
+func (m *lineMatcher) match(line string) bool {
+	line = strings.ToLower(line)
+	if benignRegex.MatchString(line) {
+		line = benignRegex.ReplaceAllString(line, " ")
+	}
+	for _, kw := range m.keywords.FindAllString(line, -1) {
+		if keywordSeverity(kw) <= m.maxSeverity {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordSeverity returns the severity of a matched keyword
+func keywordSeverity(kw string) int {
+	if level, ok := keywordSeverities[kw]; ok {
+		return level
+	}
+	return severityLevels["err"]
+}
@@ -0,0 +1,48 @@
+package diagnostics
+
+import "testing"
+
+func TestLineMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		severity string
+		line     string
+		want     bool
+	}{
+		{name: "error", line: "Jan 3 app[12]: Error: connection refused", want: true},
+		{name: "failed", line: "systemd[1]: nginx.service: Failed with result 'exit-code'.", want: true},
+		{name: "fatal", line: "postgres[88]: FATAL: password authentication failed", want: true},
+		{name: "kernel panic", line: "kernel: Kernel panic - not syncing: Fatal exception", want: true},
+		{name: "segfault", line: "kernel: app[4242]: segfault at 0 ip 00007f sp 00007ffd error 4", want: true},
+		{name: "call trace", line: "kernel: Call Trace:", want: true},
+		{name: "critical", line: "smartd[601]: Device: /dev/sda, CRITICAL: temperature", want: true},
+		{name: "zero errors", line: "fsck: /dev/sda1: clean, 0 errors", want: false},
+		{name: "no errors", line: "backup: finished with no errors", want: false},
+		{name: "errors=0", line: "rsync: sent 12 files errors=0", want: false},
+		{name: "zero count next to a real error", line: "sync: 0 errors on disk1, error on disk2", want: true},
+		{name: "stderr is not an error", line: "app: redirecting stderr to journal", want: false},
+		{name: "unrelated", line: "CRON[100]: (root) CMD (run-parts /etc/cron.hourly)", want: false},
+		{name: "custom pattern", patterns: []string{"denied"}, line: "audit: AVC apparmor=\"DENIED\" operation=\"open\"", want: true},
+		{name: "custom pattern replaces defaults", patterns: []string{"denied"}, line: "app: Error: timeout", want: false},
+		{name: "crit severity keeps segfault", severity: "crit", line: "kernel: app[1]: segfault at 0", want: true},
+		{name: "crit severity drops error", severity: "crit", line: "app: Error: timeout", want: false},
+		{name: "numeric severity", severity: "3", line: "app: Error: timeout", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newLineMatcher(tt.patterns, tt.severity)
+			if err != nil {
+				t.Fatalf("newLineMatcher() error = %v", err)
+			}
+			if got := m.match(tt.line); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newLineMatcher(nil, "severe"); err == nil {
+		t.Error("newLineMatcher() accepted an unknown severity")
+	}
+}
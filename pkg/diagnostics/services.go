@@ -0,0 +1,90 @@
+package diagnostics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultRestartThreshold is the restart count from which a service is reported as crash-looping
+const DefaultRestartThreshold = 3
+
+// flappingService is a service that keeps restarting or has failed
+type flappingService struct {
+	Unit        string `json:"unit"`
+	Restarts    int    `json:"restarts"` // Automatic restarts since the unit was last started manually
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	Result      string `json:"result"` // Result of the last run, e.g. exit-code, signal, core-dump
+}
+
+// flappingProperties are the properties requested from systemctl show
+const flappingProperties = "Id,NRestarts,ActiveState,SubState,Result"
+
+// getFlappingServices lists loaded services with at least threshold restarts or in a failed or auto-restart state
+// Wraps: systemctl show '*.service' --property=...
+func getFlappingServices(ctx context.Context, threshold int) []string {
+	lines, err := getCommandOutput(ctx, "systemctl", "show", "*.service", "--property="+flappingProperties, "--no-pager")
+	if err != nil {
+		return []string{fmt.Sprintf("Error running systemctl show: %v", err)}
+	}
+
+	var flapping []string
+	for _, svc := range parseFlappingServices(strings.Join(lines, "\n"), threshold) {
+		flapping = append(flapping, describeFlapping(svc))
+	}
+	if len(flapping) == 0 {
+		return []string{fmt.Sprintf("No services with %d or more restarts or in a failed state.", threshold)}
+	}
+	return flapping
+}
+
+// parseFlappingServices parses systemctl show output, one blank-line separated block per unit.
+// The services are sorted by restart count, most restarts first.
+func parseFlappingServices(output string, threshold int) []flappingService {
+	services := []flappingService{}
+	var svc flappingService
+	flush := func() {
+		if svc.Unit != "" && (svc.Restarts >= threshold || svc.ActiveState == "failed" || svc.SubState == "auto-restart") {
+			services = append(services, svc)
+		}
+		svc = flappingService{}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Id":
+			svc.Unit = value
+		case "NRestarts":
+			svc.Restarts, _ = strconv.Atoi(value)
+		case "ActiveState":
+			svc.ActiveState = value
+		case "SubState":
+			svc.SubState = value
+		case "Result":
+			svc.Result = value
+		}
+	}
+	flush()
+
+	sort.SliceStable(services, func(i, j int) bool { return services[i].Restarts > services[j].Restarts })
+	return services
+}
+
+// describeFlapping renders a flapping service for the summary, e.g. "nginx.service: 12 restarts, activating (auto-restart), last result exit-code"
+func describeFlapping(svc flappingService) string {
+	return fmt.Sprintf("%s: %d restarts, %s (%s), last result %s", svc.Unit, svc.Restarts, svc.ActiveState, svc.SubState, svc.Result)
+}
@@ -0,0 +1,171 @@
+package diagnostics
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxSyslogRotations caps how many rotated syslog files are scanned
+const MaxSyslogRotations = 10
+
+// maxSyslogBytes bounds the bytes read from the syslog and its rotated files, after decompression
+var maxSyslogBytes int64 = 256 * 1024 * 1024
+
+// syslogCandidates are the syslog file locations tried in order
+var syslogCandidates = []string{"/var/log/syslog", "/var/log/messages"}
+
+// findSyslogPath returns the first existing syslog file, or "" if none exists
+func findSyslogPath() string {
+	for _, path := range syslogCandidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// rotatedSyslogFiles returns up to depth rotated copies of path, newest first.
+// Both numbered (syslog.1, syslog.2.gz) and dated (messages-20240101[.gz]) rotation is recognized.
+func rotatedSyslogFiles(path string, depth int) []string {
+	var files []string
+	for i := 1; len(files) < depth; i++ {
+		name := path + "." + strconv.Itoa(i)
+		if _, err := os.Stat(name); err == nil {
+			files = append(files, name)
+		} else if _, err := os.Stat(name + ".gz"); err == nil {
+			files = append(files, name+".gz")
+		} else {
+			break
+		}
+	}
+	if len(files) > 0 {
+		return files
+	}
+
+	dated, _ := filepath.Glob(path + "-[0-9]*")
+	sort.Sort(sort.Reverse(sort.StringSlice(dated)))
+	if len(dated) > depth {
+		dated = dated[:depth]
+	}
+	return dated
+}
+
+// getSyslogErrors reads the syslog file and up to rotations rotated files and filters the lines with m.
+// Files are read newest first until count lines matched or maxSyslogBytes were read, the matches
+// are returned in chronological order together with the files that were read. A file cut short by the
+// byte limit is noted after the matches.
+func getSyslogErrors(ctx context.Context, path string, count, rotations int, m *lineMatcher) ([]string, []string) {
+	files := append([]string{path}, rotatedSyslogFiles(path, rotations)...)
+
+	var perFile [][]string // Matches of each file read, newest file first
+	var read []string
+	var notes []string
+	matched := 0
+	budget := maxSyslogBytes
+	for i, name := range files {
+		if matched >= count {
+			break
+		}
+		if budget <= 0 {
+			notes = append(notes, fmt.Sprintf("...[older syslog files not read: %d byte read limit reached]", maxSyslogBytes))
+			break
+		}
+		lines, n, cut, err := scanSyslogFile(ctx, name, budget, m)
+		if err != nil && i == 0 && n == 0 {
+			return []string{fmt.Sprintf("Could not open %s: %v", path, err)}, nil
+		}
+		if cut != "" {
+			notes = append(notes, cut)
+		}
+		budget -= n
+		read = append(read, name)
+		perFile = append(perFile, lines)
+		matched += len(lines)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("Could not read %s: %v", name, err))
+			break
+		}
+	}
+
+	var matchedLines []string
+	for i := len(perFile) - 1; i >= 0; i-- {
+		matchedLines = append(matchedLines, perFile[i]...)
+	}
+
+	if len(matchedLines) == 0 {
+		return append([]string{fmt.Sprintf("No matching error lines found in %s", strings.Join(read, ", "))}, notes...), read
+	}
+
+	if len(matchedLines) > count {
+		matchedLines = matchedLines[len(matchedLines)-count:]
+	}
+	return append(matchedLines, notes...), read
+}
+
+// scanSyslogFile returns the matching lines of a syslog file, decompressing .gz files, and how many bytes it read.
+// At most limit bytes are read: the last ones of a plain file, so its newest lines are kept, and the first ones
+// of a compressed file. cut describes the part left out, if any. When ctx is done or the file is corrupt the
+// lines matched so far are returned with the error.
+func scanSyslogFile(ctx context.Context, name string, limit int64, m *lineMatcher) (lines []string, n int64, cut string, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	skipPartial := false
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		defer gz.Close()
+		r = gz
+	} else if info, err := file.Stat(); err == nil && info.Size() > limit {
+		start := info.Size() - limit
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, "", err
+		}
+		// Drop the first line unless the read starts right after a newline
+		prev := make([]byte, 1)
+		skipPartial = true
+		if _, err := file.ReadAt(prev, start-1); err == nil && prev[0] == '\n' {
+			skipPartial = false
+		}
+		cut = fmt.Sprintf("...[%s: only the last %d bytes were read]", name, limit)
+	}
+	limited := &io.LimitedReader{R: r, N: limit}
+
+	scanner := bufio.NewScanner(limited)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for i := 0; scanner.Scan(); i++ {
+		if i%4096 == 0 && ctx.Err() != nil {
+			return lines, limit - limited.N, cut, errProbeTimeout
+		}
+		if skipPartial {
+			skipPartial = false
+			continue
+		}
+		if line := scanner.Text(); m.match(line) {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return lines, limit - limited.N, cut, err
+	}
+	if limited.N == 0 && cut == "" {
+		if extra, _ := r.Read(make([]byte, 1)); extra > 0 {
+			cut = fmt.Sprintf("...[%s: only the first %d bytes were read]", name, limit)
+		}
+	}
+	return lines, limit - limited.N, cut, nil
+}
@@ -0,0 +1,142 @@
+package diagnostics
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSyslog(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	data := []byte(strings.Join(lines, "\n") + "\n")
+	if strings.HasSuffix(path, ".gz") {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz := gzip.NewWriter(f)
+		if _, err := gz.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func defaultMatcher(t *testing.T) *lineMatcher {
+	t.Helper()
+	m, err := newLineMatcher(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestGetSyslogErrorsRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "syslog")
+	writeSyslog(t, path, "Jan 3 sshd: error today", "Jan 3 cron: ok")
+	writeSyslog(t, path+".1", "Jan 2 nginx: error yesterday")
+	writeSyslog(t, path+".2.gz", "Jan 1 kernel: error compressed", "Jan 1 kernel: fine")
+	writeSyslog(t, path+".3.gz", "Dec 31 app: error oldest")
+
+	tests := []struct {
+		name      string
+		count     int
+		rotations int
+		want      []string
+		wantFiles []string
+	}{
+		{
+			name:      "current file only",
+			count:     100,
+			want:      []string{"Jan 3 sshd: error today"},
+			wantFiles: []string{path},
+		},
+		{
+			name:      "two rotations in chronological order",
+			count:     100,
+			rotations: 2,
+			want:      []string{"Jan 1 kernel: error compressed", "Jan 2 nginx: error yesterday", "Jan 3 sshd: error today"},
+			wantFiles: []string{path, path + ".1", path + ".2.gz"},
+		},
+		{
+			name:      "stops once enough lines matched",
+			count:     2,
+			rotations: 10,
+			want:      []string{"Jan 2 nginx: error yesterday", "Jan 3 sshd: error today"},
+			wantFiles: []string{path, path + ".1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, files := getSyslogErrors(context.Background(), path, tt.count, tt.rotations, defaultMatcher(t))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getSyslogErrors() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("files = %q, want %q", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestGetSyslogErrorsByteLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "messages")
+	writeSyslog(t, path, "error new")
+	writeSyslog(t, path+"-20240102", "error older")
+	writeSyslog(t, path+"-20240101.gz", "error oldest")
+
+	defer func(n int64) { maxSyslogBytes = n }(maxSyslogBytes)
+	maxSyslogBytes = 22 // Both uncompressed files, 10 + 12 bytes
+
+	got, files := getSyslogErrors(context.Background(), path, 100, 5, defaultMatcher(t))
+	want := []string{"error older", "error new", "...[older syslog files not read: 22 byte read limit reached]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getSyslogErrors() = %q, want %q", got, want)
+	}
+	if wantFiles := []string{path, path + "-20240102"}; !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("files = %q, want %q", files, wantFiles)
+	}
+}
+
+func TestGetSyslogErrorsLargeCurrentFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "syslog")
+	writeSyslog(t, path, "error first", "error second", "error third", "error newest")
+	writeSyslog(t, path+".1.gz", "error rotated one", "error rotated two")
+
+	defer func(n int64) { maxSyslogBytes = n }(maxSyslogBytes)
+	maxSyslogBytes = 30 // The current file holds 50 bytes
+
+	got, files := getSyslogErrors(context.Background(), path, 100, 1, defaultMatcher(t))
+	want := []string{
+		"error third", "error newest",
+		"...[" + path + ": only the last 30 bytes were read]",
+		"...[older syslog files not read: 30 byte read limit reached]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getSyslogErrors() = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(files, []string{path}) {
+		t.Errorf("files = %q, want only the current file", files)
+	}
+
+	maxSyslogBytes = 60 // 10 bytes left for the compressed file
+	got, _ = getSyslogErrors(context.Background(), path, 100, 1, defaultMatcher(t))
+	if note := got[len(got)-1]; note != "...["+path+".1.gz: only the first 10 bytes were read]" {
+		t.Errorf("last line = %q, want the compressed file noted as cut short", note)
+	}
+}
@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+)
+
+// DefaultResolvers are queried when no resolvers are given, "system" is the host's own resolver
+var DefaultResolvers = []string{"8.8.8.8", "1.1.1.1", "9.9.9.9", "system"}
+
+// MaxResolvers caps how many resolvers a single comparison queries
+const MaxResolvers = 10
+
+// ResolverAnswer is one resolver's response
+type ResolverAnswer struct {
+	Resolver string   `json:"resolver"`
+	Answers  []string `json:"answers"`
+	Error    string   `json:"error,omitempty"`
+	RTTMs    float64  `json:"rtt_ms"`
+}
+
+// CompareResult holds the answers of every resolver and whether they agree
+type CompareResult struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Consistent bool   `json:"consistent"`
+	// Summary states the outcome in one line, starting with MISMATCH when resolvers disagree
+	Summary string `json:"summary"`
+	// Discrepancies groups resolvers by the answer they returned, only set when they disagree
+	Discrepancies []AnswerGroup    `json:"discrepancies,omitempty"`
+	Resolvers     []ResolverAnswer `json:"resolvers"`
+}
+
+// AnswerGroup lists the resolvers that returned the same answer
+type AnswerGroup struct {
+	Answer    string   `json:"answer"`
+	Resolvers []string `json:"resolvers"`
+}
+
+// recordTypes are the supported query types
+var recordTypes = map[string]bool{"A": true, "AAAA": true, "CNAME": true, "MX": true, "TXT": true, "NS": true}
+
+// Compare resolves name against each resolver concurrently and reports whether the answers agree.
+// Resolvers are IP addresses with an optional port, or "system".
+func Compare(ctx context.Context, name, recordType string, resolvers []string) (*CompareResult, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if name == "" || len(name) > 253 || strings.ContainsAny(name, " \t/\\") {
+		return nil, fmt.Errorf("invalid name '%s'", name)
+	}
+	recordType = strings.ToUpper(recordType)
+	if recordType == "" {
+		recordType = "A"
+	}
+	if !recordTypes[recordType] {
+		return nil, fmt.Errorf("invalid type '%s'. Allowed types: A, AAAA, CNAME, MX, TXT, NS", recordType)
+	}
+	if len(resolvers) == 0 {
+		resolvers = DefaultResolvers
+	}
+	if len(resolvers) > MaxResolvers {
+		return nil, fmt.Errorf("too many resolvers: %d (max %d)", len(resolvers), MaxResolvers)
+	}
+	for _, r := range resolvers {
+		if _, err := resolverAddress(r); err != nil {
+			return nil, err
+		}
+	}
+
+	answers := make([]ResolverAnswer, len(resolvers))
+	var wg sync.WaitGroup
+	for i, r := range resolvers {
+		wg.Add(1)
+		go func(i int, r string) {
+			defer wg.Done()
+			answers[i] = query(ctx, r, name, recordType)
+		}(i, r)
+	}
+	wg.Wait()
+
+	res := &CompareResult{Name: name, Type: recordType, Resolvers: answers}
+	summarize(res)
+	return res, nil
+}
+
+// resolverAddress turns "8.8.8.8" or "[2001:4860::8888]:53" into a dial address, "" for the system resolver
+func resolverAddress(r string) (string, error) {
+	if r == "system" {
+		return "", nil
+	}
+	if ip := net.ParseIP(r); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+	host, port, err := net.SplitHostPort(r)
+	if err == nil && net.ParseIP(host) != nil && port != "" {
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid resolver '%s': must be an IP address, IP:port or \"system\"", r)
+}
+
+// query runs a single lookup and normalizes the answers so they can be compared.
+// Resolvers given by address are queried directly, only "system" goes through the host's resolver
+// and so sees /etc/hosts.
+func query(ctx context.Context, resolver, name, recordType string) ResolverAnswer {
+	ans := ResolverAnswer{Resolver: resolver, Answers: []string{}}
+	addr, _ := resolverAddress(resolver)
+
+	ctx, cancel := context.WithTimeout(ctx, netdial.LookupTimeout())
+	defer cancel()
+
+	start := time.Now()
+	var answers []string
+	var err error
+	if addr == "" {
+		answers, err = lookupSystem(ctx, name, recordType)
+	} else {
+		answers, err = exchange(ctx, addr, name, recordType)
+	}
+	ans.RTTMs = float64(time.Since(start).Microseconds()) / 1000
+
+	for _, a := range answers {
+		a = strings.TrimSuffix(a, ".")
+		if recordType == "NS" {
+			a = strings.ToLower(a)
+		}
+		ans.Answers = append(ans.Answers, a)
+	}
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.Is(err, errNameNotFound) || (errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			// NXDOMAIN / no data is a valid answer that other resolvers should agree on
+			ans.Answers = []string{}
+		} else {
+			ans.Error = err.Error()
+		}
+	}
+	sort.Strings(ans.Answers)
+	return ans
+}
+
+// lookupSystem resolves name with the host's resolver
+func lookupSystem(ctx context.Context, name, recordType string) ([]string, error) {
+	r := netdial.Resolver()
+	var answers []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+		return answers, err
+	case "CNAME":
+		// LookupCNAME returns the name itself when there is no CNAME record, a direct query returns nothing
+		cname, err := r.LookupCNAME(ctx, name)
+		if cname != "" && !strings.EqualFold(strings.TrimSuffix(cname, "."), name) {
+			answers = append(answers, cname)
+		}
+		return answers, err
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, strings.TrimSuffix(mx.Host, ".")))
+		}
+		return answers, err
+	case "TXT":
+		return r.LookupTXT(ctx, name)
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		for _, ns := range nss {
+			answers = append(answers, ns.Host)
+		}
+		return answers, err
+	}
+	return nil, fmt.Errorf("unsupported type '%s'", recordType)
+}
+
+// answerKey renders an answer set for grouping
+func answerKey(a ResolverAnswer) string {
+	if a.Error != "" {
+		return "error"
+	}
+	if len(a.Answers) == 0 {
+		return "no records"
+	}
+	return strings.Join(a.Answers, ", ")
+}
+
+// summarize fills in Consistent, Summary and Discrepancies from the resolver answers.
+// Resolvers that failed are reported but do not count as a disagreement.
+func summarize(res *CompareResult) {
+	groups := make(map[string][]string)
+	var order []string
+	failed := 0
+	for _, a := range res.Resolvers {
+		if a.Error != "" {
+			failed++
+			continue
+		}
+		key := answerKey(a)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], a.Resolver)
+	}
+
+	answered := len(res.Resolvers) - failed
+	switch {
+	case answered == 0:
+		res.Consistent = false
+		res.Summary = fmt.Sprintf("FAILED: none of the %d resolvers answered", len(res.Resolvers))
+	case len(groups) == 1:
+		res.Consistent = true
+		res.Summary = fmt.Sprintf("OK: %d resolvers agree on %s", answered, order[0])
+	default:
+		res.Consistent = false
+		res.Summary = fmt.Sprintf("MISMATCH: %d different answers across %d resolvers, propagation is incomplete", len(groups), answered)
+		for _, key := range order {
+			res.Discrepancies = append(res.Discrepancies, AnswerGroup{Answer: key, Resolvers: groups[key]})
+		}
+	}
+	if failed > 0 && answered > 0 {
+		res.Summary += fmt.Sprintf(" (%d resolvers failed)", failed)
+	}
+}
@@ -0,0 +1,107 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name           string
+		answers        []ResolverAnswer
+		wantConsistent bool
+		wantPrefix     string
+		wantGroups     int
+	}{
+		{
+			name: "all agree",
+			answers: []ResolverAnswer{
+				{Resolver: "8.8.8.8", Answers: []string{"192.0.2.1"}},
+				{Resolver: "1.1.1.1", Answers: []string{"192.0.2.1"}},
+			},
+			wantConsistent: true,
+			wantPrefix:     "OK",
+		},
+		{
+			name: "stale resolver",
+			answers: []ResolverAnswer{
+				{Resolver: "8.8.8.8", Answers: []string{"192.0.2.1"}},
+				{Resolver: "1.1.1.1", Answers: []string{"192.0.2.1"}},
+				{Resolver: "system", Answers: []string{"198.51.100.7"}},
+			},
+			wantConsistent: false,
+			wantPrefix:     "MISMATCH",
+			wantGroups:     2,
+		},
+		{
+			name: "not yet created on one resolver",
+			answers: []ResolverAnswer{
+				{Resolver: "8.8.8.8", Answers: []string{"192.0.2.1"}},
+				{Resolver: "9.9.9.9", Answers: []string{}},
+			},
+			wantConsistent: false,
+			wantPrefix:     "MISMATCH",
+			wantGroups:     2,
+		},
+		{
+			name: "failure does not count as disagreement",
+			answers: []ResolverAnswer{
+				{Resolver: "8.8.8.8", Answers: []string{"192.0.2.1"}},
+				{Resolver: "9.9.9.9", Answers: []string{}, Error: "i/o timeout"},
+			},
+			wantConsistent: true,
+			wantPrefix:     "OK",
+		},
+		{
+			name: "all failed",
+			answers: []ResolverAnswer{
+				{Resolver: "8.8.8.8", Answers: []string{}, Error: "i/o timeout"},
+			},
+			wantConsistent: false,
+			wantPrefix:     "FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &CompareResult{Resolvers: tt.answers}
+			summarize(res)
+			if res.Consistent != tt.wantConsistent {
+				t.Errorf("Consistent = %v, want %v", res.Consistent, tt.wantConsistent)
+			}
+			if !strings.HasPrefix(res.Summary, tt.wantPrefix) {
+				t.Errorf("Summary = %q, want prefix %q", res.Summary, tt.wantPrefix)
+			}
+			if len(res.Discrepancies) != tt.wantGroups {
+				t.Errorf("Discrepancies = %+v, want %d groups", res.Discrepancies, tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestResolverAddress(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "8.8.8.8", want: "8.8.8.8:53"},
+		{in: "2001:4860:4860::8888", want: "[2001:4860:4860::8888]:53"},
+		{in: "127.0.0.1:5353", want: "127.0.0.1:5353"},
+		{in: "system", want: ""},
+		{in: "dns.google", wantErr: true},
+		{in: "-x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := resolverAddress(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolverAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolverAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
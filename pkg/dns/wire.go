@@ -0,0 +1,273 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+)
+
+// DNS wire format constants (RFC 1035, RFC 6891)
+const (
+	classIN     = 1
+	typeOPT     = 41
+	ednsUDPSize = 1232 // Avoids IP fragmentation, see dnsflagday.net 2020
+	maxPointers = 32   // Compression pointers followed in one name before it is considered a loop
+)
+
+// queryTypes maps the supported record types to their wire values
+var queryTypes = map[string]uint16{"A": 1, "NS": 2, "CNAME": 5, "MX": 15, "TXT": 16, "AAAA": 28}
+
+// rcodeNames names the response codes reported as errors
+var rcodeNames = map[int]string{1: "FORMERR", 2: "SERVFAIL", 4: "NOTIMP", 5: "REFUSED"}
+
+// errNameNotFound is returned by exchange for NXDOMAIN
+var errNameNotFound = errors.New("no such host")
+
+// exchange sends a query for name straight to the server at addr and returns the records of the
+// requested type. Unlike net.Resolver it never consults /etc/hosts or the search domains.
+// UDP is tried first and TCP when the answer is truncated.
+func exchange(ctx context.Context, addr, name, recordType string) ([]string, error) {
+	qtype := queryTypes[recordType]
+	id := uint16(rand.Uint32())
+	msg, err := buildQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := roundTrip(ctx, "udp", addr, msg, id)
+	if err != nil {
+		return nil, err
+	}
+	answers, truncated, err := parseResponse(resp, id, qtype)
+	if truncated {
+		if resp, err = roundTrip(ctx, "tcp", addr, msg, id); err != nil {
+			return nil, err
+		}
+		answers, _, err = parseResponse(resp, id, qtype)
+	}
+	return answers, err
+}
+
+// roundTrip sends msg over the shared dialer and reads the response with the given ID
+func roundTrip(ctx context.Context, network, addr string, msg []byte, id uint16) ([]byte, error) {
+	conn, err := netdial.Dialer().DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(netdial.LookupTimeout()))
+	}
+
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+		if _, err := conn.Write(append(framed, msg...)); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray datagrams, e.g. late answers to an earlier query on the same port
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// buildQuery encodes a recursive query for name with an EDNS0 OPT record
+func buildQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 64)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)      // QDCOUNT
+	binary.BigEndian.PutUint16(msg[10:], 1)     // ARCOUNT, the OPT record
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid name '%s'", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+
+	// OPT: root name, type, UDP payload size as class, extended RCODE/flags as TTL, no options
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, typeOPT)
+	msg = binary.BigEndian.AppendUint16(msg, ednsUDPSize)
+	msg = append(msg, 0, 0, 0, 0, 0, 0)
+	return msg, nil
+}
+
+// parseResponse decodes the answer records of type qtype. NXDOMAIN is reported as errNameNotFound,
+// truncated is set when the server asks to retry over TCP.
+func parseResponse(msg []byte, id, qtype uint16) (answers []string, truncated bool, err error) {
+	if len(msg) < 12 {
+		return nil, false, fmt.Errorf("short DNS response")
+	}
+	if binary.BigEndian.Uint16(msg) != id {
+		return nil, false, fmt.Errorf("DNS response ID mismatch")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return nil, false, fmt.Errorf("DNS message is not a response")
+	}
+	if flags&0x0200 != 0 {
+		return nil, true, nil
+	}
+	switch rcode := int(flags & 0x000f); rcode {
+	case 0:
+	case 3:
+		return nil, false, errNameNotFound
+	default:
+		name := rcodeNames[rcode]
+		if name == "" {
+			name = fmt.Sprintf("RCODE %d", rcode)
+		}
+		return nil, false, fmt.Errorf("server returned %s", name)
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		if _, off, err = readName(msg, off); err != nil {
+			return nil, false, err
+		}
+		off += 4 // QTYPE, QCLASS
+	}
+
+	answers = []string{}
+	for i := 0; i < ancount; i++ {
+		if _, off, err = readName(msg, off); err != nil {
+			return nil, false, err
+		}
+		if off+10 > len(msg) {
+			return nil, false, fmt.Errorf("truncated resource record")
+		}
+		rrType := binary.BigEndian.Uint16(msg[off:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		rdata := off + 10
+		off = rdata + rdlen
+		if off > len(msg) {
+			return nil, false, fmt.Errorf("truncated resource record")
+		}
+		// A CNAME chain precedes the records of the queried type, only those are the answer
+		if rrType != qtype {
+			continue
+		}
+		value, err := decodeRData(msg, rdata, rdlen, qtype)
+		if err != nil {
+			return nil, false, err
+		}
+		answers = append(answers, value)
+	}
+	return answers, false, nil
+}
+
+// decodeRData renders the record data at msg[off:off+length] like the net package lookups do
+func decodeRData(msg []byte, off, length int, qtype uint16) (string, error) {
+	data := msg[off : off+length]
+	switch qtype {
+	case queryTypes["A"]:
+		if length != net.IPv4len {
+			return "", fmt.Errorf("malformed A record")
+		}
+		return net.IP(data).String(), nil
+	case queryTypes["AAAA"]:
+		if length != net.IPv6len {
+			return "", fmt.Errorf("malformed AAAA record")
+		}
+		return net.IP(data).String(), nil
+	case queryTypes["CNAME"], queryTypes["NS"]:
+		name, _, err := readName(msg, off)
+		return name, err
+	case queryTypes["MX"]:
+		if length < 3 {
+			return "", fmt.Errorf("malformed MX record")
+		}
+		host, _, err := readName(msg, off+2)
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(data), host), err
+	case queryTypes["TXT"]:
+		// One or more length-prefixed strings, joined like net.LookupTXT does
+		var sb strings.Builder
+		for i := 0; i < len(data); {
+			n := int(data[i])
+			if i+1+n > len(data) {
+				return "", fmt.Errorf("malformed TXT record")
+			}
+			sb.Write(data[i+1 : i+1+n])
+			i += 1 + n
+		}
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("unsupported record type %d", qtype)
+}
+
+// readName decodes the possibly compressed domain name at off and returns it without the trailing dot
+// together with the offset following it
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1 // Offset after the name, set at the first compression pointer
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("truncated domain name")
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, fmt.Errorf("truncated domain name")
+			}
+			if jumps++; jumps > maxPointers {
+				return "", 0, fmt.Errorf("domain name compression loop")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		case n&0xc0 != 0:
+			return "", 0, fmt.Errorf("unsupported domain name label")
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, fmt.Errorf("truncated domain name")
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// response builds a reply to query with the given RCODE and answer records.
+// Each record is owned by the question name through a compression pointer.
+func response(query []byte, rcode uint16, records ...[]byte) []byte {
+	msg := append([]byte{}, query...)
+	// Drop the OPT record, keep header and question
+	msg = msg[:len(msg)-11]
+	binary.BigEndian.PutUint16(msg[2:], 0x8180|rcode)
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(records)))
+	binary.BigEndian.PutUint16(msg[10:], 0)
+	for _, rr := range records {
+		msg = append(msg, rr...)
+	}
+	return msg
+}
+
+// rr encodes a record of rrType owned by the name at offset 12 (the question)
+func rr(rrType uint16, rdata []byte) []byte {
+	b := []byte{0xc0, 12}
+	b = binary.BigEndian.AppendUint16(b, rrType)
+	b = binary.BigEndian.AppendUint16(b, classIN)
+	b = append(b, 0, 0, 0x0e, 0x10) // TTL 3600
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// wireName encodes a name without compression
+func wireName(labels ...string) []byte {
+	var b []byte
+	for _, l := range labels {
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, 0)
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		qtype   string
+		records [][]byte
+		rcode   uint16
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "A",
+			qtype:   "A",
+			records: [][]byte{rr(1, []byte{192, 0, 2, 1}), rr(1, []byte{192, 0, 2, 2})},
+			want:    []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:    "CNAME chain before A",
+			qtype:   "A",
+			records: [][]byte{rr(5, wireName("edge", "example", "net")), rr(1, []byte{198, 51, 100, 7})},
+			want:    []string{"198.51.100.7"},
+		},
+		{
+			name:    "CNAME with compressed target",
+			qtype:   "CNAME",
+			records: [][]byte{rr(5, append([]byte{3, 'w', 'w', 'w'}, 0xc0, 12))},
+			want:    []string{"www.example.com"},
+		},
+		{
+			name:    "MX",
+			qtype:   "MX",
+			records: [][]byte{rr(15, append([]byte{0, 10}, wireName("mail", "example", "com")...))},
+			want:    []string{"10 mail.example.com"},
+		},
+		{
+			name:    "TXT joins its strings",
+			qtype:   "TXT",
+			records: [][]byte{rr(16, []byte{5, 'v', '=', 's', 'p', 'f', 3, '1', ' ', 'a'})},
+			want:    []string{"v=spf1 a"},
+		},
+		{
+			name:  "no data",
+			qtype: "AAAA",
+			want:  []string{},
+		},
+		{
+			name:    "NXDOMAIN",
+			qtype:   "A",
+			rcode:   3,
+			wantErr: errNameNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qtype := queryTypes[tt.qtype]
+			q, err := buildQuery(0x1234, "example.com", qtype)
+			if err != nil {
+				t.Fatalf("buildQuery() error = %v", err)
+			}
+			got, truncated, err := parseResponse(response(q, tt.rcode, tt.records...), 0x1234, qtype)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseResponse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || truncated {
+				t.Fatalf("parseResponse() error = %v, truncated = %v", err, truncated)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResponseErrors(t *testing.T) {
+	q, _ := buildQuery(7, "example.com", queryTypes["A"])
+
+	if _, _, err := parseResponse(response(q, 2), 7, queryTypes["A"]); err == nil || err.Error() != "server returned SERVFAIL" {
+		t.Errorf("SERVFAIL error = %v", err)
+	}
+	if _, _, err := parseResponse(response(q, 0), 8, queryTypes["A"]); err == nil {
+		t.Error("accepted a response with a different ID")
+	}
+	owner := len(response(q, 0)) // The answer starts after header and question
+	loop := response(q, 0, rr(5, []byte{0xc0, 0}))
+	binary.BigEndian.PutUint16(loop[owner:], 0xc000|uint16(owner)) // Owner name points at itself
+	if _, _, err := parseResponse(loop, 7, queryTypes["A"]); err == nil {
+		t.Error("accepted a compression loop")
+	}
+	tc := response(q, 0)
+	tc[2] |= 0x02
+	if _, truncated, _ := parseResponse(tc, 7, queryTypes["A"]); !truncated {
+		t.Error("truncated response not reported")
+	}
+}
+
+// TestQueryBypassesHosts queries "localhost", which /etc/hosts pins, from a local server answering
+// with a different address: the direct query must return the server's answer.
+func TestQueryBypassesHosts(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(response(buf[:n], 0, rr(1, []byte{192, 0, 2, 53})), addr)
+		}
+	}()
+
+	ans := query(context.Background(), conn.LocalAddr().String(), "localhost", "A")
+	if ans.Error != "" {
+		t.Fatalf("query() error = %s", ans.Error)
+	}
+	if !reflect.DeepEqual(ans.Answers, []string{"192.0.2.53"}) {
+		t.Errorf("query() = %q, want the server's answer 192.0.2.53", ans.Answers)
+	}
+}
@@ -0,0 +1,171 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/latency"
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+	"github.com/ashton2914/mcp-netutil/pkg/route"
+)
+
+const (
+	// DefaultAnchor is the public address pinged to check external reachability
+	DefaultAnchor = "1.1.1.1"
+	// DefaultDNSName is resolved to check that name resolution works
+	DefaultDNSName = "one.one.one.one"
+)
+
+// Verdicts of a health check
+const (
+	Healthy  = "healthy"
+	Degraded = "degraded"
+	Down     = "down"
+)
+
+// Check names
+const (
+	CheckGateway = "gateway"
+	CheckAnchor  = "anchor"
+	CheckDNS     = "dns"
+)
+
+// Options configures a health check
+type Options struct {
+	Anchor  string // default 1.1.1.1
+	DNSName string // default one.one.one.one
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name        string   `json:"name"`   // gateway, anchor or dns
+	Target      string   `json:"target"` // Address pinged or name resolved
+	OK          bool     `json:"ok"`
+	AvgRTTMs    float64  `json:"avg_rtt_ms,omitempty"`
+	LossPercent *float64 `json:"loss_percent,omitempty"` // Ping checks only
+	Addresses   []string `json:"addresses,omitempty"`    // DNS check only
+	Error       string   `json:"error,omitempty"`
+}
+
+// Result holds the verdict and the individual checks
+type Result struct {
+	Verdict string        `json:"verdict"` // healthy, degraded or down
+	Checks  []CheckResult `json:"checks"`
+}
+
+// Check pings the default gateway and a public anchor and resolves a known name, concurrently
+func Check(ctx context.Context, opts Options) (*Result, error) {
+	if opts.Anchor == "" {
+		opts.Anchor = DefaultAnchor
+	}
+	if opts.DNSName == "" {
+		opts.DNSName = DefaultDNSName
+	}
+
+	checks := make([]CheckResult, 3)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		checks[0] = checkGateway(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		checks[1] = checkPing(ctx, CheckAnchor, opts.Anchor)
+	}()
+	go func() {
+		defer wg.Done()
+		checks[2] = checkDNS(ctx, opts.DNSName)
+	}()
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return &Result{Verdict: verdict(checks), Checks: checks}, nil
+}
+
+// checkGateway pings the gateway of the default route
+func checkGateway(ctx context.Context) CheckResult {
+	routes, err := route.GetRoutes()
+	if err != nil {
+		return CheckResult{Name: CheckGateway, Error: err.Error()}
+	}
+	def, ok := route.DefaultRoute(routes)
+	if !ok {
+		return CheckResult{Name: CheckGateway, Error: "no default route"}
+	}
+	target := def.Gateway
+	// Link-local IPv6 gateways are only reachable through their interface
+	if ip := net.ParseIP(target); ip != nil && ip.To4() == nil && ip.IsLinkLocalUnicast() {
+		target += "%" + def.Interface
+	}
+	return checkPing(ctx, CheckGateway, target)
+}
+
+// checkPing pings target, the check passes when at least one reply came back
+func checkPing(ctx context.Context, name, target string) CheckResult {
+	res := CheckResult{Name: name, Target: target}
+	sample, err := latency.Sample(ctx, target)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	loss := sample.LossPercent
+	res.LossPercent = &loss
+	res.AvgRTTMs = sample.AvgRTTMs
+	res.Error = sample.Error
+	res.OK = sample.Error == "" && loss < 100
+	if !res.OK && res.Error == "" {
+		res.Error = "no reply"
+	}
+	return res
+}
+
+// checkDNS resolves name with the system resolver
+func checkDNS(ctx context.Context, name string) CheckResult {
+	res := CheckResult{Name: CheckDNS, Target: name}
+	ctx, cancel := context.WithTimeout(ctx, netdial.LookupTimeout())
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := netdial.Resolver().LookupHost(ctx, name)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(addrs) == 0 {
+		res.Error = fmt.Sprintf("no addresses for %s", name)
+		return res
+	}
+	res.OK = true
+	res.AvgRTTMs = float64(time.Since(start).Microseconds()) / 1000
+	res.Addresses = addrs
+	return res
+}
+
+// verdict is down when nothing outside the host answers (anchor and DNS both fail),
+// degraded when any check fails or loses packets, and healthy otherwise
+func verdict(checks []CheckResult) string {
+	failed := make(map[string]bool)
+	lossy := false
+	for _, c := range checks {
+		if !c.OK {
+			failed[c.Name] = true
+		}
+		if c.LossPercent != nil && *c.LossPercent > 0 {
+			lossy = true
+		}
+	}
+	switch {
+	case failed[CheckAnchor] && failed[CheckDNS]:
+		return Down
+	case len(failed) > 0 || lossy:
+		return Degraded
+	default:
+		return Healthy
+	}
+}
@@ -0,0 +1,51 @@
+package health
+
+import "testing"
+
+func TestVerdict(t *testing.T) {
+	loss := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		checks []CheckResult
+		want   string
+	}{
+		{"all pass", []CheckResult{
+			{Name: CheckGateway, OK: true, LossPercent: loss(0)},
+			{Name: CheckAnchor, OK: true, LossPercent: loss(0)},
+			{Name: CheckDNS, OK: true},
+		}, Healthy},
+		{"anchor packet loss", []CheckResult{
+			{Name: CheckGateway, OK: true, LossPercent: loss(0)},
+			{Name: CheckAnchor, OK: true, LossPercent: loss(33.3)},
+			{Name: CheckDNS, OK: true},
+		}, Degraded},
+		{"dns fails", []CheckResult{
+			{Name: CheckGateway, OK: true, LossPercent: loss(0)},
+			{Name: CheckAnchor, OK: true, LossPercent: loss(0)},
+			{Name: CheckDNS, OK: false},
+		}, Degraded},
+		{"gateway drops ICMP", []CheckResult{
+			{Name: CheckGateway, OK: false, LossPercent: loss(100)},
+			{Name: CheckAnchor, OK: true, LossPercent: loss(0)},
+			{Name: CheckDNS, OK: true},
+		}, Degraded},
+		{"no external reachability", []CheckResult{
+			{Name: CheckGateway, OK: true, LossPercent: loss(0)},
+			{Name: CheckAnchor, OK: false, LossPercent: loss(100)},
+			{Name: CheckDNS, OK: false},
+		}, Down},
+		{"no default route", []CheckResult{
+			{Name: CheckGateway, OK: false},
+			{Name: CheckAnchor, OK: false},
+			{Name: CheckDNS, OK: false},
+		}, Down},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verdict(tt.checks); got != tt.want {
+				t.Errorf("verdict() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package httpcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+	"github.com/ashton2914/mcp-netutil/pkg/target"
+)
+
+const (
+	// DefaultTimeout bounds the whole check including redirects and the body read
+	DefaultTimeout = 10 * time.Second
+	// MaxTimeout is the largest accepted timeout
+	MaxTimeout = 60 * time.Second
+	// MaxRedirects is how many redirects are followed before giving up
+	MaxRedirects = 10
+	// bodySnippetSize is how much of the response body is returned
+	bodySnippetSize = 512
+)
+
+// allowedMethods are the side-effect free methods a probe may use
+var allowedMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}
+
+// Options configures an HTTP check
+type Options struct {
+	URL            string
+	Method         string        // GET (default), HEAD or OPTIONS
+	ExpectedStatus int           // 0 accepts any 2xx/3xx status, a 3xx status stops at the first redirect
+	Timeout        time.Duration // default 10s, at most 60s
+}
+
+// Timings breaks down where the time of the final request went, in milliseconds.
+// DNS, connect and TLS are zero when a connection was reused.
+type Timings struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TTFBMs    float64 `json:"ttfb_ms"`  // Request start to first response byte
+	TotalMs   float64 `json:"total_ms"` // Whole check including redirects and the body read
+}
+
+// CertInfo describes the leaf certificate of an HTTPS endpoint
+type CertInfo struct {
+	Subject         string    `json:"subject"`
+	Issuer          string    `json:"issuer"`
+	NotAfter        time.Time `json:"not_after"`
+	DaysUntilExpiry int       `json:"days_until_expiry"` // Negative once expired
+	TLSVersion      string    `json:"tls_version"`
+	Valid           bool      `json:"valid"`
+	Error           string    `json:"error,omitempty"` // Why verification failed, e.g. expired or unknown authority
+}
+
+// Result holds the outcome of an HTTP check
+type Result struct {
+	URL           string    `json:"url"`
+	Method        string    `json:"method"`
+	FinalURL      string    `json:"final_url"`
+	StatusCode    int       `json:"status_code"`
+	Status        string    `json:"status"`
+	Healthy       bool      `json:"healthy"`
+	RedirectChain []string  `json:"redirect_chain,omitempty"`
+	Timings       Timings   `json:"timings"`
+	Certificate   *CertInfo `json:"certificate,omitempty"`
+	BodySnippet   string    `json:"body_snippet,omitempty"`
+}
+
+// withDefaults validates opts and fills in unset values
+func (opts Options) withDefaults() (Options, error) {
+	// Accept a bare host or host:port, using plain HTTP only for port 80
+	if opts.URL != "" && !strings.Contains(opts.URL, "://") {
+		t, err := target.Parse(opts.URL)
+		if err != nil {
+			return opts, fmt.Errorf("invalid url '%s': %w", opts.URL, err)
+		}
+		scheme := "https"
+		if t.Port == 80 {
+			scheme = "http"
+		}
+		opts.URL = scheme + "://" + opts.URL
+	}
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return opts, fmt.Errorf("invalid url '%s': must be an absolute http:// or https:// URL", opts.URL)
+	}
+	opts.Method = strings.ToUpper(opts.Method)
+	if opts.Method == "" {
+		opts.Method = "GET"
+	}
+	if !allowedMethods[opts.Method] {
+		return opts, fmt.Errorf("invalid method '%s'. Allowed methods: GET, HEAD, OPTIONS", opts.Method)
+	}
+	if opts.ExpectedStatus != 0 && (opts.ExpectedStatus < 100 || opts.ExpectedStatus > 599) {
+		return opts, fmt.Errorf("invalid expected_status %d: must be between 100 and 599", opts.ExpectedStatus)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Timeout > MaxTimeout {
+		return opts, fmt.Errorf("invalid timeout %s: must be at most %s", opts.Timeout, MaxTimeout)
+	}
+	return opts, nil
+}
+
+// Check requests a URL and reports its status, timing breakdown, redirects and certificate expiry.
+// Certificates are verified after the handshake instead of aborting it, so an expired or self-signed
+// certificate is still reported, marked invalid, and makes the check unhealthy.
+func Check(ctx context.Context, opts Options) (*Result, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	res := &Result{URL: opts.URL, Method: opts.Method}
+
+	// Trace hooks fire for every request in the redirect chain, each new request resets the marks
+	var reqStart, dnsStart, connStart, tlsStart, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			reqStart = time.Now()
+			res.Timings.DNSMs, res.Timings.ConnectMs, res.Timings.TLSMs = 0, 0, 0
+		},
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { res.Timings.DNSMs = msSince(dnsStart) },
+		ConnectStart:      func(string, string) { connStart = time.Now() },
+		ConnectDone:       func(string, string, error) { res.Timings.ConnectMs = msSince(connStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			res.Timings.TLSMs = msSince(tlsStart)
+		},
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), opts.Method, opts.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("User-Agent", "mcp-netutil-httpcheck")
+
+	// certErr is the verification result of the latest handshake, chainCertErr the first failure in the redirect chain
+	var certErr, chainCertErr error
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // Verified in VerifyConnection, which records instead of failing
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			certErr = verifyPeer(cs)
+			if certErr != nil && chainCertErr == nil {
+				chainCertErr = certErr
+			}
+			return nil
+		},
+	}
+
+	client := &http.Client{
+		// A fresh transport so every check measures DNS, connect and TLS instead of reusing a pooled connection
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DialContext:       netdial.Dialer().DialContext,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			// An expected redirect status can only match the response that redirects
+			if opts.ExpectedStatus >= 300 && opts.ExpectedStatus < 400 {
+				return http.ErrUseLastResponse
+			}
+			res.RedirectChain = append(res.RedirectChain, r.URL.String())
+			if len(via) >= MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", MaxRedirects)
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, bodySnippetSize))
+	// Drain a little more so the total covers most of the transfer without reading huge bodies
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	res.Timings.TotalMs = msSince(start)
+	if !firstByte.IsZero() && !reqStart.IsZero() {
+		res.Timings.TTFBMs = round2(float64(firstByte.Sub(reqStart).Microseconds()) / 1000)
+	}
+
+	res.FinalURL = resp.Request.URL.String()
+	res.StatusCode = resp.StatusCode
+	res.Status = resp.Status
+	if opts.ExpectedStatus != 0 {
+		res.Healthy = resp.StatusCode == opts.ExpectedStatus
+	} else {
+		res.Healthy = resp.StatusCode >= 200 && resp.StatusCode < 400
+	}
+	res.BodySnippet = textSnippet(snippet)
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		res.Certificate = &CertInfo{
+			Subject:         cert.Subject.String(),
+			Issuer:          cert.Issuer.String(),
+			NotAfter:        cert.NotAfter,
+			DaysUntilExpiry: int(time.Until(cert.NotAfter).Hours() / 24),
+			TLSVersion:      tls.VersionName(resp.TLS.Version),
+			Valid:           certErr == nil,
+		}
+		if certErr != nil {
+			res.Certificate.Error = certErr.Error()
+		}
+	}
+	// Any hop of the chain presenting an invalid certificate fails the check
+	if chainCertErr != nil {
+		res.Healthy = false
+	}
+
+	return res, nil
+}
+
+// verifyPeer checks the presented chain against the system roots and the requested host name,
+// like the default TLS verification does
+func verifyPeer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	opts := x509.VerifyOptions{DNSName: cs.ServerName, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// textSnippet returns body as text, or a placeholder for binary content
+func textSnippet(body []byte) string {
+	text := body
+	// The snippet may end in the middle of a multi-byte rune
+	for i := 0; i < utf8.UTFMax-1 && len(text) > 0 && !utf8.Valid(text); i++ {
+		text = text[:len(text)-1]
+	}
+	if !utf8.Valid(text) || bytes.IndexByte(text, 0) >= 0 {
+		return fmt.Sprintf("<%d bytes of binary data>", len(body))
+	}
+	return string(text)
+}
+
+// msSince returns the milliseconds elapsed since t
+func msSince(t time.Time) float64 {
+	return round2(float64(time.Since(t).Microseconds()) / 1000)
+}
+
+// round2 rounds to two decimal places
+func round2(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
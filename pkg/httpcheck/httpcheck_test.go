@@ -0,0 +1,131 @@
+package httpcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("healthy"))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name          string
+		opts          Options
+		wantStatus    int
+		wantHealthy   bool
+		wantRedirects int
+		wantBody      string
+	}{
+		{name: "ok", opts: Options{URL: ts.URL + "/ok"}, wantStatus: 200, wantHealthy: true, wantBody: "healthy"},
+		{name: "redirect", opts: Options{URL: ts.URL + "/old"}, wantStatus: 200, wantHealthy: true, wantRedirects: 1, wantBody: "healthy"},
+		{name: "server error", opts: Options{URL: ts.URL + "/down"}, wantStatus: 503, wantHealthy: false, wantBody: "unavailable"},
+		{name: "expected status", opts: Options{URL: ts.URL + "/down", ExpectedStatus: 503}, wantStatus: 503, wantHealthy: true},
+		{name: "expected redirect", opts: Options{URL: ts.URL + "/old", ExpectedStatus: 301}, wantStatus: 301, wantHealthy: true},
+		{name: "head", opts: Options{URL: ts.URL + "/ok", Method: "head"}, wantStatus: 200, wantHealthy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Check(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if res.StatusCode != tt.wantStatus || res.Healthy != tt.wantHealthy {
+				t.Errorf("Check() status = %d healthy = %v, want %d %v", res.StatusCode, res.Healthy, tt.wantStatus, tt.wantHealthy)
+			}
+			if len(res.RedirectChain) != tt.wantRedirects {
+				t.Errorf("Check() redirects = %v, want %d", res.RedirectChain, tt.wantRedirects)
+			}
+			if tt.wantBody != "" && !strings.Contains(res.BodySnippet, tt.wantBody) {
+				t.Errorf("Check() body = %q, want %q", res.BodySnippet, tt.wantBody)
+			}
+			if res.Timings.TotalMs <= 0 {
+				t.Errorf("Check() total = %v, want > 0", res.Timings.TotalMs)
+			}
+		})
+	}
+}
+
+func TestCheckInvalidCertificate(t *testing.T) {
+	// httptest signs with its own CA, which the system roots do not trust
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	res, err := Check(context.Background(), Options{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if res.StatusCode != 200 || res.Healthy {
+		t.Errorf("Check() status = %d healthy = %v, want 200 false", res.StatusCode, res.Healthy)
+	}
+	cert := res.Certificate
+	if cert == nil {
+		t.Fatal("Check() certificate = nil, want the untrusted certificate")
+	}
+	if cert.Valid || cert.Error == "" || cert.NotAfter.IsZero() {
+		t.Errorf("Check() certificate = %+v, want invalid with an error and expiry", cert)
+	}
+}
+
+func TestCheckInvalidOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{URL: "ftp://example.com"},
+		{URL: "example.com:99999"},
+		{URL: "http://example.com", Method: "DELETE"},
+		{URL: "http://example.com", ExpectedStatus: 42},
+		{URL: "http://example.com", Timeout: 2 * MaxTimeout},
+	} {
+		if _, err := Check(context.Background(), opts); err == nil {
+			t.Errorf("Check(%+v) error = nil, want validation error", opts)
+		}
+	}
+}
+
+func TestOptionsHostOnlyURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"example.com", "https://example.com"},
+		{"example.com:443", "https://example.com:443"},
+		{"example.com:80", "http://example.com:80"},
+		{"example.com:8080/health", "https://example.com:8080/health"},
+		{"http://example.com", "http://example.com"},
+	}
+
+	for _, tt := range tests {
+		opts, err := Options{URL: tt.url}.withDefaults()
+		if err != nil {
+			t.Errorf("withDefaults(%q) error = %v", tt.url, err)
+			continue
+		}
+		if opts.URL != tt.want {
+			t.Errorf("withDefaults(%q).URL = %q, want %q", tt.url, opts.URL, tt.want)
+		}
+	}
+}
+
+func TestTextSnippet(t *testing.T) {
+	if got := textSnippet([]byte("caf\xc3")); got != "caf" {
+		t.Errorf("textSnippet() = %q, want truncated rune dropped", got)
+	}
+	if got := textSnippet([]byte{0x89, 'P', 'N', 'G', 0, 0}); !strings.HasPrefix(got, "<6 bytes") {
+		t.Errorf("textSnippet() = %q, want binary placeholder", got)
+	}
+}
@@ -3,49 +3,116 @@ package latency
 import (
 	"context"
 	"fmt"
+	"math"
 	"os/exec"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+
+	"github.com/ashton2914/mcp-netutil/pkg/target"
 )
 
+// Linux/macOS: "X% packet loss"
+var lossRegexUnix = regexp.MustCompile(`(\d+(?:\.\d+)?)% packet loss`)
+
+// Windows: "Lost = X (Y% loss)"
+var lossRegexWin = regexp.MustCompile(`\((\d+)% loss\)`)
+
+// Unified Unix Regex (handles 'rtt' and 'round-trip', 'mdev' and 'stddev')
+var rttRegexUnix = regexp.MustCompile(`(?:rtt|round-trip) min/avg/max/(?:mdev|stddev) = ([0-9.]+)/([0-9.]+)/([0-9.]+)/([0-9.]+) ms`)
+
+// Windows: "Minimum = 14ms, Maximum = 16ms, Average = 15ms"
+var rttRegexWin = regexp.MustCompile(`Minimum = (\d+)ms, Maximum = (\d+)ms, Average = (\d+)ms`)
+
+// Per-reply RTT, Linux/macOS "time=14.1 ms", Windows "time=14ms" or "time<1ms"
+var replyRTTRegex = regexp.MustCompile(`time([=<])([0-9.]+) ?ms`)
+
+// Per-reply TTL, Linux/macOS "ttl=115", Windows "TTL=115"
+var replyTTLRegex = regexp.MustCompile(`(?i)\bttl=(\d+)`)
+
+// initialTTLs are the initial TTLs common operating systems send with, smallest first
+var initialTTLs = []int{32, 64, 128, 255}
+
+// resolveFailureRegex matches ping's name resolution errors:
+// iputils "Name or service not known" / "Temporary failure in name resolution" / "unknown host",
+// macOS "cannot resolve", Windows "could not find host" and busybox "bad address"
+var resolveFailureRegex = regexp.MustCompile(`(?i)name or service not known|temporary failure in name resolution|no address associated with hostname|unknown host|cannot resolve|could not find host|bad address`)
+
+// ResolveError is returned when the target hostname does not resolve, which points at DNS or a typo
+// rather than packet loss
+type ResolveError struct {
+	Target string
+	Output string // ping's error message
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("could not resolve target '%s', check the hostname and DNS: %s", e.Target, e.Output)
+}
+
+// checkResolveFailure returns a ResolveError if ping's output reports a name resolution failure
+func checkResolveFailure(output, host string) error {
+	for _, line := range strings.Split(output, "\n") {
+		if resolveFailureRegex.MatchString(line) {
+			return &ResolveError{Target: host, Output: strings.TrimSpace(line)}
+		}
+	}
+	return nil
+}
+
 type LatencyResult struct {
+	Target     string `json:"target,omitempty"`
 	AvgLatency string `json:"avg_latency"` // string to preserve unit or format
 	Jitter     string `json:"jitter,omitempty"`
 	PacketLoss string `json:"packet_loss,omitempty"`
+	TTL        int    `json:"ttl,omitempty"`         // Most common TTL of the replies
+	TTLRange   string `json:"ttl_range,omitempty"`   // e.g. "52-55" when replies arrived with different TTLs, a sign of a path change
+	InitialTTL int    `json:"initial_ttl,omitempty"` // Inferred TTL the target sent with (32, 64, 128 or 255)
+	Hops       *int   `json:"hops,omitempty"`        // Routers on the return path, InitialTTL - TTL
 }
 
 // Run executes the ping command based on the specified mode.
-func Run(ctx context.Context, target string, mode string) (interface{}, error) {
+// A non-empty source (interface name or local address) sends the pings from it instead of the default route.
+func Run(ctx context.Context, host string, mode string, source string) (interface{}, error) {
 	if mode == "" {
 		return "Please specify the test mode: 'quick' (10 packets) or 'standard' (100 packets).", nil
 	}
 
-	var count string
-	switch strings.ToLower(mode) {
-	case "quick":
-		count = "10"
-	case "standard":
-		count = "100"
-	default:
+	mode, count, ok := pingMode(mode)
+	if !ok {
 		return "Invalid mode. Please specify: 'quick' or 'standard'.", nil
 	}
 
+	host, err := target.Host(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+
+	if source != "" {
+		if err := validateSource(source); err != nil {
+			return nil, fmt.Errorf("invalid source: %w", err)
+		}
+	}
+
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
 		// Windows: -n count, -w timeout (ms)
 		// We'll set a reasonable timeout per reply, e.g., 1000ms
-		args := []string{"-n", count, "-w", "1000", target}
-		cmd = exec.CommandContext(ctx, "ping", args...)
+		args := append([]string{"-n", count, "-w", "1000"}, sourceArgs(source)...)
+		cmd = exec.CommandContext(ctx, "ping", append(args, host)...)
 	} else {
-		// Linux/macOS: -c count, -i interval (0.2s), -q quiet
-		args := []string{"-c", count, "-i", "0.2", "-q", target}
-		cmd = exec.CommandContext(ctx, "ping", args...)
+		// Linux/macOS: -c count, -i interval (0.2s)
+		// Not quiet (-q), the per-reply lines are needed to compute jitter
+		args := append([]string{"-c", count, "-i", "0.2"}, sourceArgs(source)...)
+		cmd = exec.CommandContext(ctx, "ping", append(args, host)...)
 	}
 
 	outputBytes, err := cmd.CombinedOutput()
 	output := string(outputBytes)
 	if err != nil {
+		if resolveErr := checkResolveFailure(output, host); resolveErr != nil {
+			return nil, resolveErr
+		}
 		// ping returns non-zero if there is any packet loss or timeout.
 		// We still try to parse statistics if some packets were received.
 		if len(output) == 0 {
@@ -53,7 +120,23 @@ func Run(ctx context.Context, target string, mode string) (interface{}, error) {
 		}
 	}
 
-	return parsePingOutput(output, mode)
+	res, err := parsePingOutput(output, mode)
+	// Keep the target in the result so cached records can be grouped per target
+	res.Target = host
+	return res, err
+}
+
+// pingMode lowercases mode and returns it with its ping count, ok is false for an unknown mode.
+// The parser compares the normalized mode, so "Standard" keeps its packet loss and jitter.
+func pingMode(mode string) (normalized, count string, ok bool) {
+	normalized = strings.ToLower(mode)
+	switch normalized {
+	case "quick":
+		return normalized, "10", true
+	case "standard":
+		return normalized, "100", true
+	}
+	return normalized, "", false
 }
 
 func parsePingOutput(output string, mode string) (LatencyResult, error) {
@@ -62,9 +145,6 @@ func parsePingOutput(output string, mode string) (LatencyResult, error) {
 	// --- Packet Loss Parsing ---
 	// Linux/macOS: "X% packet loss"
 	// Windows: "Lost = X (Y% loss)"
-	lossRegexUnix := regexp.MustCompile(`(\d+(?:\.\d+)?)% packet loss`)
-	lossRegexWin := regexp.MustCompile(`\((\d+)% loss\)`)
-
 	if match := lossRegexUnix.FindStringSubmatch(output); len(match) > 1 {
 		result.PacketLoss = match[1] + "%"
 	} else if match := lossRegexWin.FindStringSubmatch(output); len(match) > 1 {
@@ -76,25 +156,13 @@ func parsePingOutput(output string, mode string) (LatencyResult, error) {
 	// macOS: "round-trip min/avg/max/stddev = 14.123/14.567/15.890/0.987 ms"
 	// Windows: "Minimum = 14ms, Maximum = 16ms, Average = 15ms"
 
-	// Unified Unix Regex (handles 'rtt' and 'round-trip', 'mdev' and 'stddev')
-	rttRegexUnix := regexp.MustCompile(`(?:rtt|round-trip) min/avg/max/(?:mdev|stddev) = ([0-9.]+)/([0-9.]+)/([0-9.]+)/([0-9.]+) ms`)
-	// Windows Regex
-	rttRegexWin := regexp.MustCompile(`Minimum = (\d+)ms, Maximum = (\d+)ms, Average = (\d+)ms`)
-
 	if match := rttRegexUnix.FindStringSubmatch(output); len(match) > 4 {
 		// match[1]=min, match[2]=avg, match[3]=max, match[4]=dev
 		result.AvgLatency = match[2] + " ms"
-		if mode != "quick" {
-			result.Jitter = match[4] + " ms"
-		}
+		result.Jitter = match[4] + " ms"
 	} else if match := rttRegexWin.FindStringSubmatch(output); len(match) > 3 {
 		// match[1]=min, match[2]=max, match[3]=avg
 		result.AvgLatency = match[3] + " ms"
-		if mode != "quick" {
-			// Windows ping doesn't provide jitter (stddev) directly.
-			// We could convert min/max to integers and estimate, but better to leave empty or "N/A"
-			result.Jitter = "N/A"
-		}
 	} else {
 		// Failure to parse latency
 		// If 100% packet loss, AvgLatency might not be present.
@@ -104,15 +172,95 @@ func parsePingOutput(output string, mode string) (LatencyResult, error) {
 		return result, fmt.Errorf("could not parse ping statistics")
 	}
 
+	// Jitter from the individual replies works in every mode and on Windows, whose summary has no
+	// deviation. The summary mdev/stddev is the fallback when fewer than three replies were printed.
+	if jitter, ok := replyJitter(output); ok {
+		result.Jitter = fmt.Sprintf("%.3f ms", jitter)
+	} else if result.Jitter == "" && mode == "standard" {
+		result.Jitter = "N/A"
+	}
+
 	// Filter based on mode
 	finalResult := LatencyResult{
 		AvgLatency: result.AvgLatency,
+		Jitter:     result.Jitter,
 	}
+	replyTTLs(output, &finalResult)
 
 	if mode == "standard" {
-		finalResult.Jitter = result.Jitter
 		finalResult.PacketLoss = result.PacketLoss
 	}
 
 	return finalResult, nil
 }
+
+// replyTTLs sets the TTL fields from the per-reply TTLs, they stay empty when no reply was printed.
+// The initial TTL is the smallest common default at or above the observed TTL, which holds as long as
+// the path is shorter than the gap between two defaults.
+func replyTTLs(output string, res *LatencyResult) {
+	counts := make(map[int]int)
+	min, max := 0, 0
+	for _, m := range replyTTLRegex.FindAllStringSubmatch(output, -1) {
+		ttl, err := strconv.Atoi(m[1])
+		if err != nil || ttl <= 0 || ttl > 255 {
+			continue
+		}
+		counts[ttl]++
+		if min == 0 || ttl < min {
+			min = ttl
+		}
+		if ttl > max {
+			max = ttl
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+	for ttl, n := range counts {
+		// Ties go to the larger TTL so the result does not depend on map order
+		if n > counts[res.TTL] || (n == counts[res.TTL] && ttl > res.TTL) {
+			res.TTL = ttl
+		}
+	}
+	if min != max {
+		res.TTLRange = fmt.Sprintf("%d-%d", min, max)
+	}
+	for _, initial := range initialTTLs {
+		if initial >= res.TTL {
+			res.InitialTTL = initial
+			hops := initial - res.TTL
+			res.Hops = &hops
+			break
+		}
+	}
+}
+
+// replyJitter computes jitter from the per-reply RTTs as the standard deviation of the RTT
+// differences between consecutive replies (the inter-packet delay variation of RFC 3550).
+// Windows "time<1ms" replies count as 0 ms. ok is false when fewer than three replies are in
+// the output, a single difference has no spread.
+func replyJitter(output string) (float64, bool) {
+	var rtts []float64
+	for _, m := range replyRTTRegex.FindAllStringSubmatch(output, -1) {
+		if m[1] == "<" {
+			rtts = append(rtts, 0)
+		} else if v, err := strconv.ParseFloat(m[2], 64); err == nil {
+			rtts = append(rtts, v)
+		}
+	}
+	if len(rtts) < 3 {
+		return 0, false
+	}
+	diffs := make([]float64, len(rtts)-1)
+	var mean float64
+	for i := range diffs {
+		diffs[i] = rtts[i+1] - rtts[i]
+		mean += diffs[i]
+	}
+	mean /= float64(len(diffs))
+	var variance float64
+	for _, d := range diffs {
+		variance += (d - mean) * (d - mean)
+	}
+	return math.Sqrt(variance / float64(len(diffs))), true
+}
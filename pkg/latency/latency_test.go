@@ -1,9 +1,13 @@
 package latency
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 )
 
+func intPtr(n int) *int { return &n }
+
 func TestParsePingOutput(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -25,6 +29,9 @@ rtt min/avg/max/mdev = 14.123/14.567/15.890/0.987 ms`,
 				AvgLatency: "14.567 ms",
 				Jitter:     "0.987 ms",
 				PacketLoss: "0%",
+				TTL:        115,
+				InitialTTL: 128,
+				Hops:       intPtr(13),
 			},
 			wantErr: false,
 		},
@@ -41,6 +48,9 @@ round-trip min/avg/max/stddev = 14.123/14.567/15.890/0.987 ms`,
 				AvgLatency: "14.567 ms",
 				Jitter:     "0.987 ms",
 				PacketLoss: "0.0%",
+				TTL:        58,
+				InitialTTL: 64,
+				Hops:       intPtr(6),
 			},
 			wantErr: false,
 		},
@@ -50,6 +60,7 @@ round-trip min/avg/max/stddev = 14.123/14.567/15.890/0.987 ms`,
 Pinging 8.8.8.8 with 32 bytes of data:
 Reply from 8.8.8.8: bytes=32 time=14ms TTL=115
 Reply from 8.8.8.8: bytes=32 time=15ms TTL=115
+Reply from 8.8.8.8: bytes=32 time=17ms TTL=115
 
 Ping statistics for 8.8.8.8:
     Packets: Sent = 4, Received = 4, Lost = 0 (0% loss),
@@ -58,8 +69,11 @@ Approximate round trip times in milli-seconds:
 			mode: "standard",
 			expected: LatencyResult{
 				AvgLatency: "15 ms",
-				Jitter:     "N/A",
+				Jitter:     "0.500 ms", // Stddev of the differences 1 and 2 between the replies
 				PacketLoss: "0%",
+				TTL:        115,
+				InitialTTL: 128,
+				Hops:       intPtr(13),
 			},
 			wantErr: false,
 		},
@@ -72,6 +86,70 @@ rtt min/avg/max/mdev = 14.123/14.567/15.890/0.987 ms`,
 			mode: "quick",
 			expected: LatencyResult{
 				AvgLatency: "14.567 ms",
+				Jitter:     "0.987 ms", // No replies printed, falls back to mdev
+			},
+			wantErr: false,
+		},
+		{
+			name: "Quick Mode Per-Reply Jitter (Linux)",
+			output: `PING 10.0.0.1 (10.0.0.1) 56(84) bytes of data.
+64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=10.0 ms
+64 bytes from 10.0.0.1: icmp_seq=2 ttl=64 time=12.0 ms
+64 bytes from 10.0.0.1: icmp_seq=3 ttl=64 time=11.0 ms
+
+--- 10.0.0.1 ping statistics ---
+3 packets transmitted, 3 received, 0% packet loss, time 402ms
+rtt min/avg/max/mdev = 10.000/11.000/12.000/0.816 ms`,
+			mode: "quick",
+			expected: LatencyResult{
+				AvgLatency: "11.000 ms",
+				Jitter:     "1.500 ms", // Stddev of the differences 2 and -1
+				TTL:        64,
+				InitialTTL: 64,
+				Hops:       intPtr(0),
+			},
+			wantErr: false,
+		},
+		{
+			name: "TTL Change During The Run",
+			output: `PING 203.0.113.7 (203.0.113.7) 56(84) bytes of data.
+64 bytes from 203.0.113.7: icmp_seq=1 ttl=52 time=30.0 ms
+64 bytes from 203.0.113.7: icmp_seq=2 ttl=52 time=30.0 ms
+64 bytes from 203.0.113.7: icmp_seq=3 ttl=55 time=20.0 ms
+
+--- 203.0.113.7 ping statistics ---
+3 packets transmitted, 3 received, 0% packet loss, time 402ms
+rtt min/avg/max/mdev = 20.000/26.667/30.000/4.714 ms`,
+			mode: "quick",
+			expected: LatencyResult{
+				AvgLatency: "26.667 ms",
+				Jitter:     "5.000 ms",
+				TTL:        52,
+				TTLRange:   "52-55",
+				InitialTTL: 64,
+				Hops:       intPtr(12),
+			},
+			wantErr: false,
+		},
+		{
+			name: "Sub-Millisecond Replies (Windows)",
+			output: `
+Pinging 192.168.1.1 with 32 bytes of data:
+Reply from 192.168.1.1: bytes=32 time<1ms TTL=64
+Reply from 192.168.1.1: bytes=32 time<1ms TTL=64
+Reply from 192.168.1.1: bytes=32 time=2ms TTL=64
+
+Ping statistics for 192.168.1.1:
+    Packets: Sent = 3, Received = 3, Lost = 0 (0% loss),
+Approximate round trip times in milli-seconds:
+    Minimum = 0ms, Maximum = 2ms, Average = 0ms`,
+			mode: "quick",
+			expected: LatencyResult{
+				AvgLatency: "0 ms",
+				Jitter:     "1.000 ms", // Stddev of the differences 0 and 2, "<1ms" counts as 0
+				TTL:        64,
+				InitialTTL: 64,
+				Hops:       intPtr(0),
 			},
 			wantErr: false,
 		},
@@ -111,18 +189,67 @@ rtt min/avg/max/mdev = 14.123/14.567/15.890/0.987 ms`,
 			},
 			wantErr: false,
 		},
+		{
+			name: "Mixed Case Standard Mode",
+			output: `
+Ping statistics for 8.8.8.8:
+    Packets: Sent = 100, Received = 98, Lost = 2 (2% loss),
+Approximate round trip times in milli-seconds:
+    Minimum = 14ms, Maximum = 16ms, Average = 15ms`,
+			mode: "Standard",
+			expected: LatencyResult{
+				AvgLatency: "15 ms",
+				Jitter:     "N/A",
+				PacketLoss: "2%",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parsePingOutput(tt.output, tt.mode)
+			// Run normalizes the mode before parsing
+			mode, _, ok := pingMode(tt.mode)
+			if !ok {
+				t.Fatalf("pingMode(%q) rejected the mode", tt.mode)
+			}
+			got, err := parsePingOutput(tt.output, mode)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parsePingOutput() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.expected {
+			if !reflect.DeepEqual(got, tt.expected) {
 				t.Errorf("parsePingOutput() = %v, want %v", got, tt.expected)
 			}
 		})
 	}
 }
+
+func TestCheckResolveFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		wantErr bool
+	}{
+		{"iputils", "ping: nosuch.example: Name or service not known\n", true},
+		{"iputils no network", "ping: example.com: Temporary failure in name resolution\n", true},
+		{"old iputils", "ping: unknown host nosuch.example\n", true},
+		{"macOS", "ping: cannot resolve nosuch.example: Unknown host\n", true},
+		{"Windows", "Ping request could not find host nosuch.example. Please check the name and try again.\r\n", true},
+		{"busybox", "ping: bad address 'nosuch.example'\n", true},
+		{"packet loss", "--- 10.0.0.1 ping statistics ---\n10 packets transmitted, 0 received, 100% packet loss, time 9014ms\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResolveFailure(tt.output, "nosuch.example")
+			var resolveErr *ResolveError
+			if got := errors.As(err, &resolveErr); got != tt.wantErr {
+				t.Fatalf("checkResolveFailure() error = %v, want ResolveError %v", err, tt.wantErr)
+			}
+			if tt.wantErr && resolveErr.Target != "nosuch.example" {
+				t.Errorf("ResolveError.Target = %q, want nosuch.example", resolveErr.Target)
+			}
+		})
+	}
+}
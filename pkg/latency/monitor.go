@@ -0,0 +1,119 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/target"
+)
+
+// MonitorSample is a single latency measurement taken during Monitor
+type MonitorSample struct {
+	Timestamp   string  `json:"timestamp"` // RFC3339
+	AvgRTTMs    float64 `json:"avg_rtt_ms,omitempty"`
+	LossPercent float64 `json:"loss_percent"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// MonitorEvent records a sample that crossed a threshold
+type MonitorEvent struct {
+	Timestamp string  `json:"timestamp"`
+	Metric    string  `json:"metric"` // loss or rtt
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// MonitorResult holds the time series and threshold violations of a Monitor run
+type MonitorResult struct {
+	Target  string          `json:"target"`
+	Samples []MonitorSample `json:"samples"`
+	Events  []MonitorEvent  `json:"events"`
+}
+
+// monitorPacketsPerSample is the number of pings sent for every sample
+const monitorPacketsPerSample = 3
+
+// sampleHost takes one sample, replaced in tests
+var sampleHost = takeSample
+
+// Monitor pings target every interval for duration and flags samples whose packet loss (percent)
+// or average RTT (ms) exceeds the given thresholds. A threshold of 0 disables that check.
+func Monitor(ctx context.Context, host string, interval, duration time.Duration, lossThreshold, rttThreshold float64) (*MonitorResult, error) {
+	host, err := target.Host(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+	if interval <= 0 || duration <= 0 {
+		return nil, fmt.Errorf("interval and duration must be positive")
+	}
+	if interval > duration {
+		return nil, fmt.Errorf("interval must not exceed duration")
+	}
+
+	res := &MonitorResult{Target: host, Samples: []MonitorSample{}, Events: []MonitorEvent{}}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.Now().Add(duration)
+
+	for {
+		sample := sampleHost(ctx, host)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		res.Samples = append(res.Samples, sample)
+
+		if lossThreshold > 0 && sample.LossPercent > lossThreshold {
+			res.Events = append(res.Events, MonitorEvent{Timestamp: sample.Timestamp, Metric: "loss", Value: sample.LossPercent, Threshold: lossThreshold})
+		}
+		if rttThreshold > 0 && sample.AvgRTTMs > rttThreshold {
+			res.Events = append(res.Events, MonitorEvent{Timestamp: sample.Timestamp, Metric: "rtt", Value: sample.AvgRTTMs, Threshold: rttThreshold})
+		}
+
+		if !time.Now().Add(interval).Before(deadline.Add(time.Millisecond)) {
+			return res, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Sample sends a single short burst of pings to target, as taken by Monitor at every interval
+func Sample(ctx context.Context, host string) (MonitorSample, error) {
+	host, err := target.Host(host)
+	if err != nil {
+		return MonitorSample{}, fmt.Errorf("invalid target: %w", err)
+	}
+	return sampleHost(ctx, host), nil
+}
+
+// takeSample sends a short burst of pings and records the average RTT and loss
+func takeSample(ctx context.Context, host string) MonitorSample {
+	sample := MonitorSample{Timestamp: time.Now().Format(time.RFC3339)}
+
+	cmd := exec.CommandContext(ctx, "ping", "-c", strconv.Itoa(monitorPacketsPerSample), "-i", "0.2", "-W", "1", "-q", "-n", host)
+	outputBytes, err := cmd.CombinedOutput()
+	output := string(outputBytes)
+	if err != nil && len(output) == 0 {
+		sample.LossPercent = 100
+		sample.Error = fmt.Sprintf("ping failed: %v", err)
+		return sample
+	}
+
+	if match := lossRegexUnix.FindStringSubmatch(output); len(match) > 1 {
+		sample.LossPercent, _ = strconv.ParseFloat(match[1], 64)
+	} else {
+		sample.LossPercent = 100
+		sample.Error = "could not parse ping statistics"
+	}
+	if match := rttRegexUnix.FindStringSubmatch(output); len(match) > 4 {
+		sample.AvgRTTMs, _ = strconv.ParseFloat(match[2], 64)
+	}
+	return sample
+}
@@ -0,0 +1,62 @@
+package latency
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMonitorThresholds(t *testing.T) {
+	orig := sampleHost
+	defer func() { sampleHost = orig }()
+
+	// The first sample crosses both thresholds, the second neither, the third only the RTT one
+	series := []MonitorSample{
+		{AvgRTTMs: 300, LossPercent: 50},
+		{AvgRTTMs: 10, LossPercent: 0},
+		{AvgRTTMs: 150, LossPercent: 20},
+	}
+	calls := 0
+	sampleHost = func(ctx context.Context, host string) MonitorSample {
+		s := series[calls%len(series)]
+		s.Timestamp = time.Unix(int64(calls), 0).UTC().Format(time.RFC3339)
+		calls++
+		return s
+	}
+
+	res, err := Monitor(context.Background(), "192.0.2.1", 5*time.Millisecond, 15*time.Millisecond, 20, 100)
+	if err != nil {
+		t.Fatalf("Monitor() error = %v", err)
+	}
+	if res.Target != "192.0.2.1" || len(res.Samples) != calls {
+		t.Fatalf("Monitor() = %d samples for %q, want %d for 192.0.2.1", len(res.Samples), res.Target, calls)
+	}
+
+	// Sample timing varies, so derive the expected events from the samples that were taken
+	var want []MonitorEvent
+	for _, s := range res.Samples {
+		if s.LossPercent > 20 {
+			want = append(want, MonitorEvent{Timestamp: s.Timestamp, Metric: "loss", Value: s.LossPercent, Threshold: 20})
+		}
+		if s.AvgRTTMs > 100 {
+			want = append(want, MonitorEvent{Timestamp: s.Timestamp, Metric: "rtt", Value: s.AvgRTTMs, Threshold: 100})
+		}
+	}
+	if !reflect.DeepEqual(res.Events, want) {
+		t.Errorf("Monitor() events = %+v, want %+v", res.Events, want)
+	}
+	if len(res.Events) < 2 || res.Events[0].Metric != "loss" || res.Events[1].Metric != "rtt" {
+		t.Errorf("Monitor() events = %+v, want loss and rtt events for the first sample", res.Events)
+	}
+
+	// Thresholds of 0 disable the checks
+	calls = 0
+	res, err = Monitor(context.Background(), "192.0.2.1", 5*time.Millisecond, 5*time.Millisecond, 0, 0)
+	if err != nil {
+		t.Fatalf("Monitor() error = %v", err)
+	}
+	if len(res.Events) != 0 {
+		t.Errorf("Monitor() with disabled thresholds events = %+v, want none", res.Events)
+	}
+}
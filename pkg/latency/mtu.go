@@ -0,0 +1,110 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ashton2914/mcp-netutil/pkg/target"
+)
+
+// MTUResult holds the outcome of a path MTU discovery
+type MTUResult struct {
+	Target      string `json:"target"`
+	MTU         int    `json:"mtu"`          // Largest unfragmented packet in bytes, including IP/ICMP headers
+	PayloadSize int    `json:"payload_size"` // Matching ICMP payload size (ping -s)
+	Probes      int    `json:"probes"`
+}
+
+const (
+	// minMTU is the minimum MTU every IPv4 host must accept
+	minMTU = 576
+	// minIPv6MTU is the minimum MTU of every IPv6 link (RFC 8200)
+	minIPv6MTU = 1280
+	// maxMTU covers jumbo frames
+	maxMTU = 9000
+	// ipv4Overhead is the IPv4 (20) + ICMP (8) header size
+	ipv4Overhead = 28
+	// ipv6Overhead is the IPv6 (40) + ICMPv6 (8) header size
+	ipv6Overhead = 48
+	// mtuProbeCount is how many pings are sent per size, a size only counts as too large when all are lost
+	mtuProbeCount = 3
+)
+
+// probe is replaced in tests
+var probe = probeSize
+
+// DiscoverMTU finds the path MTU to target by binary searching ping -M do -s <size>.
+// A size is treated as too large when it is rejected with "Frag needed" / "message too long"
+// or none of its mtuProbeCount pings is answered, so a single lost packet does not shrink the result.
+func DiscoverMTU(ctx context.Context, host string) (*MTUResult, error) {
+	host, err := target.Host(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+
+	floor, overhead := minMTU, ipv4Overhead
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		floor, overhead = minIPv6MTU, ipv6Overhead
+	}
+
+	res := &MTUResult{Target: host}
+	lo, hi := floor-overhead, maxMTU-overhead
+
+	// The smallest size must get through, otherwise the host is simply unreachable
+	res.Probes++
+	ok, err := probe(ctx, host, lo)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no reply from %s at minimum packet size %d, target unreachable", host, floor)
+	}
+
+	for lo < hi {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mid := (lo + hi + 1) / 2
+		res.Probes++
+		ok, err := probe(ctx, host, mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	res.PayloadSize = lo
+	res.MTU = lo + overhead
+	return res, nil
+}
+
+// probeSize sends mtuProbeCount don't-fragment pings with the given payload size.
+// It returns true if any reply was received.
+func probeSize(ctx context.Context, host string, size int) (bool, error) {
+	cmd := exec.CommandContext(ctx, "ping", "-M", "do", "-s", strconv.Itoa(size), "-c", strconv.Itoa(mtuProbeCount), "-i", "0.2", "-W", "1", "-n", host)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	out := string(output)
+	if strings.Contains(out, "Frag needed") || strings.Contains(out, "message too long") {
+		return false, nil
+	}
+	// ping exits with 1 when no reply was received and 2 on other errors
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("ping failed: %w, output: %s", err, strings.TrimSpace(out))
+}
@@ -0,0 +1,66 @@
+package latency
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiscoverMTU(t *testing.T) {
+	orig := probe
+	defer func() { probe = orig }()
+
+	tests := []struct {
+		name        string
+		target      string
+		pathMTU     int // Largest packet the stubbed path carries, 0 drops everything
+		wantMTU     int
+		wantPayload int
+		wantErr     bool
+	}{
+		{name: "ethernet", target: "192.0.2.1", pathMTU: 1500, wantMTU: 1500, wantPayload: 1472},
+		{name: "pppoe", target: "192.0.2.1", pathMTU: 1492, wantMTU: 1492, wantPayload: 1464},
+		{name: "ipv4 minimum", target: "192.0.2.1", pathMTU: 576, wantMTU: 576, wantPayload: 548},
+		{name: "jumbo", target: "192.0.2.1", pathMTU: 9000, wantMTU: 9000, wantPayload: 8972},
+		{name: "ipv6 ethernet", target: "2001:db8::1", pathMTU: 1500, wantMTU: 1500, wantPayload: 1452},
+		{name: "ipv6 minimum", target: "2001:db8::1", pathMTU: 1280, wantMTU: 1280, wantPayload: 1232},
+		{name: "unreachable", target: "192.0.2.1", pathMTU: 0, wantErr: true},
+		{name: "ipv6 below minimum", target: "2001:db8::1", pathMTU: 1000, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			floor, overhead := minMTU, ipv4Overhead
+			if tt.target == "2001:db8::1" {
+				floor, overhead = minIPv6MTU, ipv6Overhead
+			}
+			var smallest int
+			probe = func(ctx context.Context, target string, size int) (bool, error) {
+				if smallest == 0 || size < smallest {
+					smallest = size
+				}
+				return size+overhead <= tt.pathMTU, nil
+			}
+
+			res, err := DiscoverMTU(context.Background(), tt.target)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("DiscoverMTU() = %+v, want error", res)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DiscoverMTU() error = %v", err)
+			}
+			if res.MTU != tt.wantMTU || res.PayloadSize != tt.wantPayload {
+				t.Errorf("DiscoverMTU() mtu = %d payload = %d, want %d %d", res.MTU, res.PayloadSize, tt.wantMTU, tt.wantPayload)
+			}
+			// Binary search over at most 9000 sizes plus the initial probe
+			if res.Probes > 15 {
+				t.Errorf("DiscoverMTU() probes = %d, want at most 15", res.Probes)
+			}
+			if smallest+overhead != floor {
+				t.Errorf("smallest probed packet = %d, want the family minimum %d", smallest+overhead, floor)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxMultiTargets caps the targets of a single RunMulti call
+	MaxMultiTargets = 20
+	// multiConcurrency bounds the pings running at once
+	multiConcurrency = 5
+)
+
+// modeRunTime is roughly how long one ping run takes in each mode, the packet count at a 0.2s interval
+var modeRunTime = map[string]time.Duration{"quick": 2 * time.Second, "standard": 20 * time.Second}
+
+// TargetResult is the outcome of pinging one target of RunMulti, either a result or an error
+type TargetResult struct {
+	Result *LatencyResult `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// runPing runs a single target, replaced in tests
+var runPing = Run
+
+// RunMulti pings several targets concurrently with the same mode and source and returns the outcome per target.
+// A failing target does not affect the others; duplicate targets are pinged once.
+// When ctx has a deadline the call is rejected upfront if the runs cannot finish before it.
+func RunMulti(ctx context.Context, targets []string, mode string, source string) (map[string]TargetResult, error) {
+	runTime, ok := modeRunTime[strings.ToLower(mode)]
+	if !ok {
+		return nil, fmt.Errorf("invalid mode '%s'. Allowed modes: quick, standard", mode)
+	}
+	mode = strings.ToLower(mode)
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		unique = append(unique, t)
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("no targets given")
+	}
+	if len(unique) > MaxMultiTargets {
+		return nil, fmt.Errorf("too many targets: %d (max %d)", len(unique), MaxMultiTargets)
+	}
+	// Targets run in rounds of multiConcurrency, each round taking about one run
+	rounds := (len(unique) + multiConcurrency - 1) / multiConcurrency
+	if deadline, ok := ctx.Deadline(); ok {
+		if need, left := time.Duration(rounds)*runTime, time.Until(deadline); need > left {
+			return nil, fmt.Errorf("%s mode for %d targets takes about %s, more than the %s left before the tool timeout; use quick mode or fewer targets",
+				mode, len(unique), need, left.Round(time.Second))
+		}
+	}
+
+	results := make(map[string]TargetResult, len(unique))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, multiConcurrency)
+	for _, t := range unique {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var tr TargetResult
+			res, err := runPing(ctx, target, mode, source)
+			if r, ok := res.(LatencyResult); ok && err == nil {
+				tr.Result = &r
+			} else if err != nil {
+				tr.Error = err.Error()
+			} else {
+				tr.Error = fmt.Sprint(res)
+			}
+
+			mu.Lock()
+			results[target] = tr
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+	return results, nil
+}
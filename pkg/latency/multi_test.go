@@ -0,0 +1,95 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunMulti(t *testing.T) {
+	orig := runPing
+	defer func() { runPing = orig }()
+	var calls atomic.Int32
+	runPing = func(ctx context.Context, target, mode, source string) (interface{}, error) {
+		calls.Add(1)
+		if target == "bad.invalid" {
+			return nil, &ResolveError{Target: target, Output: "Name or service not known"}
+		}
+		return LatencyResult{Target: target, AvgLatency: "1.0 ms"}, nil
+	}
+
+	got, err := RunMulti(context.Background(), []string{"10.0.0.1", "bad.invalid", "10.0.0.1", " "}, "quick", "")
+	if err != nil {
+		t.Fatalf("RunMulti() error = %v", err)
+	}
+	want := map[string]TargetResult{
+		"10.0.0.1":    {Result: &LatencyResult{Target: "10.0.0.1", AvgLatency: "1.0 ms"}},
+		"bad.invalid": {Error: "could not resolve target 'bad.invalid', check the hostname and DNS: Name or service not known"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RunMulti() = %+v, want %+v", got, want)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("pinged %d times, want 2 (duplicates and blanks skipped)", calls.Load())
+	}
+
+	tooMany := make([]string, MaxMultiTargets+1)
+	for i := range tooMany {
+		tooMany[i] = string(rune('a'+i)) + ".example.com"
+	}
+	for name, tc := range map[string]struct {
+		targets []string
+		mode    string
+	}{
+		"invalid mode": {[]string{"10.0.0.1"}, "slow"},
+		"no targets":   {nil, "quick"},
+		"too many":     {tooMany, "quick"},
+	} {
+		if _, err := RunMulti(context.Background(), tc.targets, tc.mode, ""); err == nil {
+			t.Errorf("%s: RunMulti() should fail", name)
+		}
+	}
+}
+
+func TestRunMultiDeadline(t *testing.T) {
+	orig := runPing
+	defer func() { runPing = orig }()
+	runPing = func(ctx context.Context, target, mode, source string) (interface{}, error) {
+		if mode != strings.ToLower(mode) {
+			return nil, fmt.Errorf("mode %q not lowercased", mode)
+		}
+		return LatencyResult{Target: target}, nil
+	}
+
+	targets := func(n int) []string {
+		t := make([]string, n)
+		for i := range t {
+			t[i] = fmt.Sprintf("10.0.0.%d", i+1)
+		}
+		return t
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Five targets run in one 20s round, six need two
+	got, err := RunMulti(ctx, targets(5), "Standard", "")
+	if err != nil {
+		t.Fatalf("RunMulti() error = %v", err)
+	}
+	for target, tr := range got {
+		if tr.Error != "" {
+			t.Errorf("%s: %s", target, tr.Error)
+		}
+	}
+	if _, err := RunMulti(ctx, targets(6), "standard", ""); err == nil || !strings.Contains(err.Error(), "tool timeout") {
+		t.Errorf("RunMulti() error = %v, want the run rejected for exceeding the deadline", err)
+	}
+	// Quick mode takes about 8s for 20 targets
+	if _, err := RunMulti(ctx, targets(20), "quick", ""); err != nil {
+		t.Errorf("RunMulti() quick error = %v, want it to fit", err)
+	}
+}
@@ -0,0 +1,44 @@
+package latency
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// validateSource checks that source is the name of a local interface or an address assigned to one,
+// so ping is never given an arbitrary string
+func validateSource(source string) error {
+	if ip := net.ParseIP(source); ip != nil {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return fmt.Errorf("failed to list local addresses: %w", err)
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return nil
+			}
+		}
+		return fmt.Errorf("source address %s is not assigned to a local interface", source)
+	}
+
+	// macOS and Windows ping can only bind to an address
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("source must be a local IP address on %s", runtime.GOOS)
+	}
+	if _, err := net.InterfaceByName(source); err != nil {
+		return fmt.Errorf("unknown source interface '%s'", source)
+	}
+	return nil
+}
+
+// sourceArgs returns the ping arguments that bind it to source
+func sourceArgs(source string) []string {
+	if source == "" {
+		return nil
+	}
+	if runtime.GOOS == "linux" {
+		return []string{"-I", source}
+	}
+	return []string{"-S", source}
+}
@@ -0,0 +1,39 @@
+package latency
+
+import (
+	"net"
+	"runtime"
+	"testing"
+)
+
+func TestValidateSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{"loopback address", "127.0.0.1", false},
+		{"address not assigned locally", "192.0.2.123", true},
+		{"unknown interface", "nosuchif0", true},
+		{"flag injection", "-f", true},
+	}
+
+	if runtime.GOOS == "linux" {
+		if ifaces, err := net.Interfaces(); err == nil && len(ifaces) > 0 {
+			tests = append(tests, struct {
+				name    string
+				source  string
+				wantErr bool
+			}{"local interface", ifaces[0].Name, false})
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSource(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSource(%q) error = %v, wantErr %v", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
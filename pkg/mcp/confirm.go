@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ConfirmationTokenArg is the argument a confirmed call echoes the token back in
+const ConfirmationTokenArg = "confirmation_token"
+
+// DefaultConfirmationTTL is how long a confirmation token stays valid
+const DefaultConfirmationTTL = 2 * time.Minute
+
+// ConfirmationSummary describes in plain words what a call with args would do.
+// required is false for calls that need no confirmation, e.g. a read-only action of a guarded tool.
+type ConfirmationSummary func(args map[string]interface{}) (summary string, required bool)
+
+// ConfirmationRequest is returned instead of running a guarded tool on the first call
+type ConfirmationRequest struct {
+	ConfirmationRequired bool   `json:"confirmation_required"`
+	Summary              string `json:"summary"`
+	Token                string `json:"confirmation_token"`
+	ExpiresAt            string `json:"expires_at"` // RFC3339
+	Instructions         string `json:"instructions"`
+}
+
+// pendingConfirmation is an issued token waiting to be echoed back
+type pendingConfirmation struct {
+	tool    string
+	args    string // Canonical JSON of the arguments the token was issued for
+	expires time.Time
+}
+
+// RequireConfirmation makes calls to a tool two-step: the first call returns a token and a summary
+// of what will happen, and the tool only runs when called again with the same arguments plus the
+// token within the confirmation TTL. Tokens are single-use.
+func (s *Server) RequireConfirmation(name string, summarize ConfirmationSummary) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.confirmations == nil {
+		s.confirmations = make(map[string]ConfirmationSummary)
+	}
+	s.confirmations[name] = summarize
+}
+
+// SetConfirmationTTL sets how long confirmation tokens stay valid
+func (s *Server) SetConfirmationTTL(d time.Duration) {
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	s.confirmationTTL = d
+}
+
+// checkConfirmation decides whether a guarded call may run.
+// It returns the arguments to run the tool with, or the result to send back instead.
+func (s *Server) checkConfirmation(name string, args map[string]interface{}) (map[string]interface{}, *CallToolResult) {
+	s.lock.RLock()
+	summarize, guarded := s.confirmations[name]
+	s.lock.RUnlock()
+	if !guarded {
+		return args, nil
+	}
+
+	token, _ := args[ConfirmationTokenArg].(string)
+	callArgs := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k != ConfirmationTokenArg {
+			callArgs[k] = v
+		}
+	}
+
+	summary, required := summarize(callArgs)
+	if !required {
+		return callArgs, nil
+	}
+	canonical, _ := json.Marshal(callArgs) // Map keys are sorted, so equal arguments encode identically
+
+	if token == "" {
+		req, err := s.issueToken(name, string(canonical), summary)
+		if err != nil {
+			return nil, &CallToolResult{IsError: true, Content: []ToolContent{{Type: "text", Text: err.Error()}}}
+		}
+		text, _ := json.MarshalIndent(req, "", "  ")
+		return nil, &CallToolResult{Content: []ToolContent{{Type: "text", Text: string(text)}}}
+	}
+
+	if err := s.redeemToken(token, name, string(canonical)); err != nil {
+		msg := fmt.Sprintf("%v. Call %s again without %s to get a new token.", err, name, ConfirmationTokenArg)
+		return nil, &CallToolResult{IsError: true, Content: []ToolContent{{Type: "text", Text: msg}}}
+	}
+	return callArgs, nil
+}
+
+// issueToken stores a new single-use token for the call
+func (s *Server) issueToken(name, args, summary string) (*ConfirmationRequest, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	now := time.Now()
+	// Drop expired tokens so unconfirmed calls do not accumulate
+	for t, p := range s.tokens {
+		if now.After(p.expires) {
+			delete(s.tokens, t)
+		}
+	}
+	ttl := s.confirmationTTL
+	if ttl <= 0 {
+		ttl = DefaultConfirmationTTL
+	}
+	if s.tokens == nil {
+		s.tokens = make(map[string]pendingConfirmation)
+	}
+	expires := now.Add(ttl)
+	s.tokens[token] = pendingConfirmation{tool: name, args: args, expires: expires}
+
+	return &ConfirmationRequest{
+		ConfirmationRequired: true,
+		Summary:              summary,
+		Token:                token,
+		ExpiresAt:            expires.Format(time.RFC3339),
+		Instructions:         fmt.Sprintf("Nothing has been done yet. To proceed, call %s again with the same arguments and %s set to this token before it expires.", name, ConfirmationTokenArg),
+	}, nil
+}
+
+// redeemToken consumes a token, which must match the tool and arguments it was issued for
+func (s *Server) redeemToken(token, name, args string) error {
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	p, ok := s.tokens[token]
+	if !ok {
+		return fmt.Errorf("unknown or already used confirmation token")
+	}
+	if time.Now().After(p.expires) {
+		delete(s.tokens, token)
+		return fmt.Errorf("confirmation token expired")
+	}
+	if p.tool != name || p.args != args {
+		// Not consumed, the original call can still be confirmed
+		return fmt.Errorf("confirmation token was issued for a different call")
+	}
+	delete(s.tokens, token)
+	return nil
+}
+
+// withConfirmationArg adds the token argument to a guarded tool's input schema
+func withConfirmationArg(schema json.RawMessage) json.RawMessage {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(schema, &obj); err != nil {
+		return schema
+	}
+	props, _ := obj["properties"].(map[string]interface{})
+	if props == nil {
+		props = make(map[string]interface{})
+	}
+	props[ConfirmationTokenArg] = map[string]interface{}{
+		"type":        "string",
+		"description": "Token from a previous call that returned confirmation_required, echo it back to carry out the action",
+	}
+	obj["properties"] = props
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return schema
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxConcurrent is how many expensive tool calls may run at once
+const DefaultMaxConcurrent = 4
+
+// DefaultConcurrencyWait is how long an expensive call waits for a free slot before the server reports busy
+const DefaultConcurrencyWait = 10 * time.Second
+
+// LimitConcurrency marks a tool as expensive: calls to any of the marked tools share the slots set by
+// SetMaxConcurrent, so an eager client cannot run many process scans or traces at once
+func (s *Server) LimitConcurrency(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.limited == nil {
+		s.limited = make(map[string]bool)
+	}
+	s.limited[name] = true
+}
+
+// SetMaxConcurrent sets how many expensive calls run at once and how long excess calls queue for a slot.
+// A wait of zero rejects excess calls immediately, a non-positive n removes the limit.
+// It must be called before the server handles requests.
+func (s *Server) SetMaxConcurrent(n int, wait time.Duration) {
+	s.slots = nil
+	if n > 0 {
+		s.slots = make(chan struct{}, n)
+	}
+	s.slotWait = wait
+}
+
+// acquireSlot blocks until an expensive call may run and returns the function releasing its slot.
+// busy is set when every slot stayed taken for the wait time, release is nil when ctx ended while queued.
+func (s *Server) acquireSlot(ctx context.Context, name string) (release func(), busy *CallToolResult) {
+	s.lock.RLock()
+	limited := s.limited[name]
+	s.lock.RUnlock()
+	if !limited || s.slots == nil {
+		return func() {}, nil
+	}
+	release = func() { <-s.slots }
+
+	select {
+	case s.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if s.slotWait > 0 {
+		timer := time.NewTimer(s.slotWait)
+		defer timer.Stop()
+		select {
+		case s.slots <- struct{}{}:
+			return release, nil
+		case <-ctx.Done():
+			return nil, nil
+		case <-timer.C:
+		}
+	}
+
+	msg := fmt.Sprintf("Server busy: %d expensive tool calls (process scans, traces, monitors) are already running. Retry %s later.", cap(s.slots), name)
+	return nil, &CallToolResult{IsError: true, Content: []ToolContent{{Type: "text", Text: msg}}}
+}
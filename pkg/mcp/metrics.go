@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds in seconds of the tool duration histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Tool call outcomes used for the status label
+const (
+	StatusSuccess   = "success"
+	StatusError     = "error"
+	StatusTimeout   = "timeout"
+	StatusCancelled = "cancelled"
+)
+
+// Metrics collects tool call counters and durations and renders them in the Prometheus text format
+type Metrics struct {
+	calls     map[[2]string]uint64 // {tool, status} -> count
+	durations map[string]*histogram
+	gauges    []gauge
+	lock      sync.Mutex
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+type gauge struct {
+	name, help string
+	value      func() float64
+}
+
+// NewMetrics returns an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		calls:     make(map[[2]string]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+// ObserveToolCall records one tool call with its outcome and duration
+func (m *Metrics) ObserveToolCall(tool, status string, d time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.calls[[2]string{tool, status}]++
+
+	h, ok := m.durations[tool]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(durationBuckets))}
+		m.durations[tool] = h
+	}
+	secs := d.Seconds()
+	for i, le := range durationBuckets {
+		if secs <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// RegisterGauge adds a gauge whose value is read from fn at scrape time
+func (m *Metrics) RegisterGauge(name, help string, fn func() float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.gauges = append(m.gauges, gauge{name: name, help: help, value: fn})
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.lock.Lock()
+	var b strings.Builder
+
+	b.WriteString("# HELP netutil_tool_calls_total Total number of MCP tool calls.\n")
+	b.WriteString("# TYPE netutil_tool_calls_total counter\n")
+	keys := make([][2]string, 0, len(m.calls))
+	for k := range m.calls {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&b, "netutil_tool_calls_total{tool=%q,status=%q} %d\n", k[0], k[1], m.calls[k])
+	}
+
+	b.WriteString("# HELP netutil_tool_duration_seconds Duration of MCP tool calls in seconds.\n")
+	b.WriteString("# TYPE netutil_tool_duration_seconds histogram\n")
+	tools := make([]string, 0, len(m.durations))
+	for t := range m.durations {
+		tools = append(tools, t)
+	}
+	sort.Strings(tools)
+	for _, t := range tools {
+		h := m.durations[t]
+		var cumulative uint64
+		for i, le := range durationBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "netutil_tool_duration_seconds_bucket{tool=%q,le=\"%g\"} %d\n", t, le, cumulative)
+		}
+		fmt.Fprintf(&b, "netutil_tool_duration_seconds_bucket{tool=%q,le=\"+Inf\"} %d\n", t, h.count)
+		fmt.Fprintf(&b, "netutil_tool_duration_seconds_sum{tool=%q} %g\n", t, h.sum)
+		fmt.Fprintf(&b, "netutil_tool_duration_seconds_count{tool=%q} %d\n", t, h.count)
+	}
+
+	gauges := append([]gauge(nil), m.gauges...)
+	m.lock.Unlock()
+
+	// Gauge callbacks may take their own locks, so they run outside ours
+	for _, g := range gauges {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.value())
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics for a Prometheus scrape
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
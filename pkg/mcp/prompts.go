@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Prompt describes a prompt template exposed via prompts/list
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is a single message of a rendered prompt
+type PromptMessage struct {
+	Role    string      `json:"role"` // user or assistant
+	Content ToolContent `json:"content"`
+}
+
+// PromptRenderer builds the messages of a prompt from its arguments
+type PromptRenderer func(arguments map[string]string) ([]PromptMessage, error)
+
+type registeredPrompt struct {
+	Definition Prompt
+	Render     PromptRenderer
+}
+
+// RegisterPrompt adds a prompt template and enables the prompts capability
+func (s *Server) RegisterPrompt(prompt Prompt, render PromptRenderer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.prompts == nil {
+		s.prompts = make(map[string]registeredPrompt)
+	}
+	s.prompts[prompt.Name] = registeredPrompt{Definition: prompt, Render: render}
+}
+
+func (s *Server) hasPrompts() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.prompts) > 0
+}
+
+func (s *Server) handleListPrompts(id interface{}) *JSONRPCResponse {
+	s.lock.RLock()
+	prompts := make([]Prompt, 0, len(s.prompts))
+	for _, p := range s.prompts {
+		prompts = append(prompts, p.Definition)
+	}
+	s.lock.RUnlock()
+
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result: map[string]interface{}{
+			"prompts": prompts,
+		},
+	}
+}
+
+func (s *Server) handleGetPrompt(id interface{}, params json.RawMessage) *JSONRPCResponse {
+	var getParams struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	// The request itself parsed, so malformed params are Invalid params rather than a Parse error
+	if err := json.Unmarshal(params, &getParams); err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32602, Message: "Invalid params: " + err.Error()},
+		}
+	}
+	if getParams.Name == "" {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32602, Message: "Invalid params: name is required"},
+		}
+	}
+
+	s.lock.RLock()
+	prompt, ok := s.prompts[getParams.Name]
+	s.lock.RUnlock()
+	if !ok {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32602, Message: fmt.Sprintf("Prompt %s not found", getParams.Name)},
+		}
+	}
+
+	for _, arg := range prompt.Definition.Arguments {
+		if arg.Required && getParams.Arguments[arg.Name] == "" {
+			return &JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      id,
+				Error:   &JSONRPCError{Code: -32602, Message: fmt.Sprintf("Missing required argument: %s", arg.Name)},
+			}
+		}
+	}
+
+	messages, err := prompt.Render(getParams.Arguments)
+	if err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32602, Message: err.Error()},
+		}
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result: map[string]interface{}{
+			"description": prompt.Definition.Description,
+			"messages":    messages,
+		},
+	}
+}
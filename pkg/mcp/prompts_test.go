@@ -0,0 +1,42 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHandleGetPromptErrors(t *testing.T) {
+	s := NewServer()
+	s.RegisterPrompt(Prompt{Name: "check_host", Arguments: []PromptArgument{{Name: "host", Required: true}}},
+		func(arguments map[string]string) ([]PromptMessage, error) {
+			return []PromptMessage{{Role: "user", Content: ToolContent{Type: "text", Text: "check " + arguments["host"]}}}, nil
+		})
+
+	tests := []struct {
+		name     string
+		params   string
+		wantCode int
+	}{
+		{name: "params not an object", params: `"check_host"`, wantCode: -32602},
+		{name: "argument not a string", params: `{"name":"check_host","arguments":{"host":1}}`, wantCode: -32602},
+		{name: "missing name", params: `{}`, wantCode: -32602},
+		{name: "unknown prompt", params: `{"name":"nope"}`, wantCode: -32602},
+		{name: "missing argument", params: `{"name":"check_host"}`, wantCode: -32602},
+		{name: "valid", params: `{"name":"check_host","arguments":{"host":"example.com"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.HandleRequest(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "prompts/get", Params: json.RawMessage(tt.params)})
+			if tt.wantCode == 0 {
+				if resp.Error != nil {
+					t.Errorf("prompts/get error = %+v, want success", resp.Error)
+				}
+				return
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("prompts/get error = %+v, want code %d", resp.Error, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Resource describes a readable item exposed via resources/list
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is the body of a resource returned by resources/read
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// ResourceProvider supplies resources to the server
+type ResourceProvider interface {
+	ListResources() ([]Resource, error)
+	ReadResource(uri string) ([]ResourceContents, error)
+}
+
+// ErrResourceNotFound should be returned (or wrapped) by ReadResource for unknown URIs
+var ErrResourceNotFound = errors.New("resource not found")
+
+// SetResourceProvider enables the resources capability backed by p
+func (s *Server) SetResourceProvider(p ResourceProvider) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.resources = p
+}
+
+func (s *Server) resourceProvider() ResourceProvider {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.resources
+}
+
+func (s *Server) handleListResources(id interface{}) *JSONRPCResponse {
+	resources := []Resource{}
+	if p := s.resourceProvider(); p != nil {
+		list, err := p.ListResources()
+		if err != nil {
+			return &JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      id,
+				Error:   &JSONRPCError{Code: -32603, Message: err.Error()},
+			}
+		}
+		resources = append(resources, list...)
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result: map[string]interface{}{
+			"resources": resources,
+		},
+	}
+}
+
+func (s *Server) handleReadResource(id interface{}, params json.RawMessage) *JSONRPCResponse {
+	var readParams struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &readParams); err != nil || readParams.URI == "" {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32602, Message: "Invalid params: uri is required"},
+		}
+	}
+
+	p := s.resourceProvider()
+	if p == nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32002, Message: "Resource not found", Data: map[string]string{"uri": readParams.URI}},
+		}
+	}
+
+	contents, err := p.ReadResource(readParams.URI)
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return &JSONRPCResponse{
+				JSONRPC: "2.0",
+				ID:      id,
+				Error:   &JSONRPCError{Code: -32002, Message: "Resource not found", Data: map[string]string{"uri": readParams.URI}},
+			}
+		}
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32603, Message: err.Error()},
+		}
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result: map[string]interface{}{
+			"contents": contents,
+		},
+	}
+}
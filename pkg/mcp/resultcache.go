@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// cachedResult is a successful tool result kept for reuse
+type cachedResult struct {
+	result  CallToolResult
+	created time.Time
+}
+
+// CacheResults marks a read-only tool whose successful results are reused for identical calls
+// within the TTL set by SetResultCacheTTL. Never use it for tools that change state.
+func (s *Server) CacheResults(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.cacheable == nil {
+		s.cacheable = make(map[string]bool)
+	}
+	s.cacheable[name] = true
+}
+
+// SetResultCacheTTL sets how long a result is reused, a non-positive value disables the result cache
+func (s *Server) SetResultCacheTTL(d time.Duration) {
+	s.resultLock.Lock()
+	defer s.resultLock.Unlock()
+	s.resultTTL = d
+	s.results = nil
+}
+
+// resultKey identifies a call by tool and arguments, it is "" for calls that are not cached
+func (s *Server) resultKey(name string, args map[string]interface{}) string {
+	s.lock.RLock()
+	cacheable := s.cacheable[name]
+	s.lock.RUnlock()
+	if !cacheable {
+		return ""
+	}
+	canonical, err := json.Marshal(args) // Map keys are sorted, so equal arguments encode identically
+	if err != nil {
+		return ""
+	}
+	return name + "\x00" + string(canonical)
+}
+
+// lookupResult returns a fresh cached result for key, marked with _meta.cached
+func (s *Server) lookupResult(key string) (CallToolResult, bool) {
+	if key == "" {
+		return CallToolResult{}, false
+	}
+	s.resultLock.Lock()
+	defer s.resultLock.Unlock()
+	if s.resultTTL <= 0 {
+		return CallToolResult{}, false
+	}
+	c, ok := s.results[key]
+	if !ok || time.Since(c.created) > s.resultTTL {
+		return CallToolResult{}, false
+	}
+	result := CallToolResult{
+		Content: append([]ToolContent(nil), c.result.Content...),
+		Meta: map[string]interface{}{
+			"cached":    true,
+			"cached_at": c.created.Format(time.RFC3339Nano),
+		},
+	}
+	return result, true
+}
+
+// storeResult keeps a successful result for key
+func (s *Server) storeResult(key string, result CallToolResult) {
+	if key == "" || result.IsError {
+		return
+	}
+	s.resultLock.Lock()
+	defer s.resultLock.Unlock()
+	if s.resultTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	// Drop expired results so distinct arguments do not accumulate
+	for k, c := range s.results {
+		if now.Sub(c.created) > s.resultTTL {
+			delete(s.results, k)
+		}
+	}
+	if s.results == nil {
+		s.results = make(map[string]cachedResult)
+	}
+	s.results[key] = cachedResult{result: result, created: now}
+}
@@ -1,8 +1,16 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashton2914/mcp-netutil/pkg/version"
 )
 
 // JSON-RPC Request/Response structures
@@ -17,7 +25,14 @@ type JSONRPCResponse struct {
 	JSONRPC string        `json:"jsonrpc"`
 	Result  interface{}   `json:"result,omitempty"`
 	Error   *JSONRPCError `json:"error,omitempty"`
-	ID      interface{}   `json:"id,omitempty"`
+	ID      interface{}   `json:"id"` // null when the request ID could not be determined
+}
+
+// JSONRPCNotification is a server-initiated message that expects no reply
+type JSONRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
 type JSONRPCError struct {
@@ -26,6 +41,40 @@ type JSONRPCError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// ParseRequest decodes a JSON-RPC request.
+// On failure it returns a spec-compliant error response that carries the request ID
+// when it can still be recovered from the body, and null otherwise.
+func ParseRequest(data []byte) (JSONRPCRequest, *JSONRPCResponse) {
+	var req JSONRPCRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		// The body may be valid JSON with a malformed field, in which case the id is still usable
+		var partial struct {
+			ID interface{} `json:"id"`
+		}
+		_ = json.Unmarshal(data, &partial)
+
+		code, message := -32700, "Parse error"
+		if json.Valid(data) {
+			code, message = -32600, "Invalid Request"
+		}
+		return req, &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      partial.ID,
+			Error:   &JSONRPCError{Code: code, Message: message, Data: err.Error()},
+		}
+	}
+
+	if req.Method == "" {
+		return req, &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &JSONRPCError{Code: -32600, Message: "Invalid Request", Data: "method is required"},
+		}
+	}
+
+	return req, nil
+}
+
 // MCP Specific Structures
 type Tool struct {
 	Name        string          `json:"name"`
@@ -34,8 +83,9 @@ type Tool struct {
 }
 
 type CallToolResult struct {
-	Content []ToolContent `json:"content"`
-	IsError bool          `json:"isError,omitempty"`
+	Content []ToolContent          `json:"content"`
+	IsError bool                   `json:"isError,omitempty"`
+	Meta    map[string]interface{} `json:"_meta,omitempty"` // e.g. cached: true for a reused result
 }
 
 type ToolContent struct {
@@ -43,25 +93,109 @@ type ToolContent struct {
 	Text string `json:"text"`
 }
 
+// LatestProtocolVersion is the newest MCP protocol version the server implements
+const LatestProtocolVersion = "2025-03-26"
+
+// SupportedProtocolVersions lists every MCP protocol version the server accepts, newest first
+var SupportedProtocolVersions = []string{LatestProtocolVersion, "2024-11-05"}
+
+// DefaultToolTimeout bounds how long a single tool call may run
+const DefaultToolTimeout = 30 * time.Second
+
+// DefaultMaxResultSize bounds the text of a single tool result in bytes
+const DefaultMaxResultSize = 64 * 1024
+
 // Server logic
 type Server struct {
-	tools map[string]RegisteredTool
+	tools         map[string]RegisteredTool
+	toolTimeout   time.Duration
+	maxResultSize int
+	notify        NotificationHandler
+	resources     ResourceProvider
+	prompts       map[string]registeredPrompt
+	logger        *slog.Logger
+	metrics       *Metrics
+	lock          sync.RWMutex
+
+	// inFlight maps the request ID of running tool calls to their cancel function
+	inFlight     map[string]context.CancelCauseFunc
+	inFlightLock sync.Mutex
+
+	// confirmations maps guarded tools to their summary, guarded by lock
+	confirmations   map[string]ConfirmationSummary
+	tokens          map[string]pendingConfirmation
+	confirmationTTL time.Duration
+	tokenLock       sync.Mutex
+
+	// limited marks expensive tools sharing the slots semaphore, guarded by lock
+	limited  map[string]bool
+	slots    chan struct{}
+	slotWait time.Duration
+
+	// cacheable marks read-only tools whose results are reused within resultTTL, guarded by lock
+	cacheable  map[string]bool
+	results    map[string]cachedResult
+	resultTTL  time.Duration
+	resultLock sync.Mutex
 }
 
+// NotificationHandler delivers server-initiated notifications to connected clients
+type NotificationHandler func(n JSONRPCNotification)
+
 type RegisteredTool struct {
 	Definition Tool
 	Handler    ToolHandler
 }
 
-type ToolHandler func(arguments map[string]interface{}) (CallToolResult, error)
+// ToolHandler runs a tool. ctx is cancelled when the call times out.
+type ToolHandler func(ctx context.Context, arguments map[string]interface{}) (CallToolResult, error)
 
 func NewServer() *Server {
 	return &Server{
-		tools: make(map[string]RegisteredTool),
+		tools:         make(map[string]RegisteredTool),
+		toolTimeout:   DefaultToolTimeout,
+		maxResultSize: DefaultMaxResultSize,
+		logger:        slog.Default(),
+		inFlight:      make(map[string]context.CancelCauseFunc),
+		slots:         make(chan struct{}, DefaultMaxConcurrent),
+		slotWait:      DefaultConcurrencyWait,
+	}
+}
+
+// EnableMetrics starts collecting tool call metrics and returns the registry
+func (s *Server) EnableMetrics() *Metrics {
+	if s.metrics == nil {
+		s.metrics = NewMetrics()
 	}
+	return s.metrics
+}
+
+// SetLogger sets the logger used for request and tool call logging
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// SetToolTimeout sets the per-call tool timeout, a non-positive value disables it
+func (s *Server) SetToolTimeout(d time.Duration) {
+	s.toolTimeout = d
+}
+
+// SetMaxResultSize sets the largest text content a tool result may return in bytes, longer text is
+// truncated with a marker. A non-positive value disables the limit.
+func (s *Server) SetMaxResultSize(n int) {
+	s.maxResultSize = n
+}
+
+// SetNotificationHandler sets the callback used to emit notifications.
+// Tool registrations made after it is set emit notifications/tools/list_changed.
+func (s *Server) SetNotificationHandler(fn NotificationHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.notify = fn
 }
 
 func (s *Server) RegisterTool(name string, description string, schema json.RawMessage, handler ToolHandler) {
+	s.lock.Lock()
 	s.tools[name] = RegisteredTool{
 		Definition: Tool{
 			Name:        name,
@@ -70,21 +204,71 @@ func (s *Server) RegisterTool(name string, description string, schema json.RawMe
 		},
 		Handler: handler,
 	}
+	notify := s.notify
+	s.lock.Unlock()
+
+	notifyToolsChanged(notify)
+}
+
+// UnregisterTool removes a tool, it reports whether the tool existed
+func (s *Server) UnregisterTool(name string) bool {
+	s.lock.Lock()
+	_, ok := s.tools[name]
+	delete(s.tools, name)
+	notify := s.notify
+	s.lock.Unlock()
+
+	if ok {
+		notifyToolsChanged(notify)
+	}
+	return ok
+}
+
+// notifyToolsChanged emits notifications/tools/list_changed if a handler is set
+func notifyToolsChanged(notify NotificationHandler) {
+	if notify == nil {
+		return
+	}
+	notify(JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/tools/list_changed",
+	})
 }
 
+// HandleRequest handles a request from the single client of a connection, such as stdio
 func (s *Server) HandleRequest(req JSONRPCRequest) *JSONRPCResponse {
+	return s.HandleSessionRequest("", req)
+}
+
+// HandleSessionRequest handles a request from the client identified by session.
+// Request IDs are only unique per client, so cancellations and the in-flight check are scoped to it.
+func (s *Server) HandleSessionRequest(session string, req JSONRPCRequest) *JSONRPCResponse {
 	// 1. Handle Notifications (no ID) - JSON-RPC 2.0 says do not reply
 	if req.ID == nil {
+		s.logger.Debug("notification received", "method", req.Method)
+		if req.Method == "notifications/cancelled" {
+			s.handleCancelled(session, req.Params)
+		}
 		return nil
 	}
 
+	s.logger.Debug("request received", "method", req.Method, "id", req.ID)
+
 	switch req.Method {
 	case "tools/list":
 		return s.handleListTools(req.ID)
 	case "tools/call":
-		return s.handleCallTool(req.ID, req.Params)
+		return s.handleCallTool(session, req.ID, req.Params)
+	case "resources/list":
+		return s.handleListResources(req.ID)
+	case "resources/read":
+		return s.handleReadResource(req.ID, req.Params)
+	case "prompts/list":
+		return s.handleListPrompts(req.ID)
+	case "prompts/get":
+		return s.handleGetPrompt(req.ID, req.Params)
 	case "initialize":
-		return s.handleInitialize(req.ID)
+		return s.handleInitialize(req.ID, req.Params)
 	default:
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
@@ -97,28 +281,62 @@ func (s *Server) HandleRequest(req JSONRPCRequest) *JSONRPCResponse {
 	}
 }
 
-func (s *Server) handleInitialize(id interface{}) *JSONRPCResponse {
+// negotiateProtocolVersion echoes the client's version if supported, otherwise returns the latest
+func negotiateProtocolVersion(requested string) string {
+	for _, v := range SupportedProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return LatestProtocolVersion
+}
+
+func (s *Server) handleInitialize(id interface{}, params json.RawMessage) *JSONRPCResponse {
+	var initParams struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		// An unparseable params object just falls back to the latest version
+		_ = json.Unmarshal(params, &initParams)
+	}
+
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{
+			"listChanged": true,
+		},
+	}
+	if s.resourceProvider() != nil {
+		capabilities["resources"] = map[string]interface{}{}
+	}
+	if s.hasPrompts() {
+		capabilities["prompts"] = map[string]interface{}{}
+	}
+
 	return &JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      id,
 		Result: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
-			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
-			},
+			"protocolVersion": negotiateProtocolVersion(initParams.ProtocolVersion),
+			"capabilities":    capabilities,
 			"serverInfo": map[string]string{
 				"name":    "mcp-netutil",
-				"version": "0.2.0",
+				"version": version.Version,
 			},
 		},
 	}
 }
 
 func (s *Server) handleListTools(id interface{}) *JSONRPCResponse {
+	s.lock.RLock()
 	var toolsList []Tool
-	for _, t := range s.tools {
-		toolsList = append(toolsList, t.Definition)
+	for name, t := range s.tools {
+		def := t.Definition
+		if _, guarded := s.confirmations[name]; guarded {
+			def.InputSchema = withConfirmationArg(def.InputSchema)
+		}
+		toolsList = append(toolsList, def)
 	}
+	s.lock.RUnlock()
 
 	return &JSONRPCResponse{
 		JSONRPC: "2.0",
@@ -129,7 +347,7 @@ func (s *Server) handleListTools(id interface{}) *JSONRPCResponse {
 	}
 }
 
-func (s *Server) handleCallTool(id interface{}, params json.RawMessage) *JSONRPCResponse {
+func (s *Server) handleCallTool(session string, id interface{}, params json.RawMessage) *JSONRPCResponse {
 	var callParams struct {
 		Name      string                 `json:"name"`
 		Arguments map[string]interface{} `json:"arguments"`
@@ -142,7 +360,9 @@ func (s *Server) handleCallTool(id interface{}, params json.RawMessage) *JSONRPC
 		}
 	}
 
+	s.lock.RLock()
 	tool, ok := s.tools[callParams.Name]
+	s.lock.RUnlock()
 	if !ok {
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
@@ -151,7 +371,59 @@ func (s *Server) handleCallTool(id interface{}, params json.RawMessage) *JSONRPC
 		}
 	}
 
-	result, err := tool.Handler(callParams.Arguments)
+	args, pending := s.checkConfirmation(callParams.Name, callParams.Arguments)
+	if pending != nil {
+		s.logger.Info("tool call awaiting confirmation", "method", "tools/call", "tool", callParams.Name, "is_error", pending.IsError)
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Result:  *pending,
+		}
+	}
+
+	key := s.resultKey(callParams.Name, args)
+	if result, ok := s.lookupResult(key); ok {
+		s.logger.Info("tool call served from result cache", "method", "tools/call", "tool", callParams.Name)
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Result:  result,
+		}
+	}
+
+	start := time.Now()
+	result, err := s.runTool(session, id, tool, args)
+	duration := time.Since(start)
+	if err == nil {
+		s.storeResult(key, result)
+	}
+	if s.metrics != nil {
+		s.metrics.ObserveToolCall(callParams.Name, callStatus(result, err), duration)
+	}
+	switch {
+	case errors.Is(err, errRequestCancelled):
+		s.logger.Info("tool call cancelled", "method", "tools/call", "tool", callParams.Name, "duration", duration)
+	case err != nil:
+		s.logger.Error("tool call failed", "method", "tools/call", "tool", callParams.Name, "duration", duration, "error", err)
+	case result.IsError:
+		s.logger.Warn("tool call returned an error", "method", "tools/call", "tool", callParams.Name, "duration", duration, "error", toolErrorText(result))
+	default:
+		s.logger.Info("tool call", "method", "tools/call", "tool", callParams.Name, "duration", duration)
+	}
+	if errors.Is(err, errRequestInFlight) {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32600, Message: "Invalid Request: request ID already in use"},
+		}
+	}
+	if errors.Is(err, errRequestCancelled) {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error:   &JSONRPCError{Code: -32800, Message: "Request cancelled"},
+		}
+	}
 	if err != nil {
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
@@ -166,3 +438,139 @@ func (s *Server) handleCallTool(id interface{}, params json.RawMessage) *JSONRPC
 		Result:  result,
 	}
 }
+
+// errRequestCancelled is the cancel cause of a tool call aborted by notifications/cancelled
+var errRequestCancelled = errors.New("request cancelled")
+
+// errRequestInFlight is returned for a tool call whose ID is still used by a running call of the same session
+var errRequestInFlight = errors.New("request ID already in flight")
+
+// requestKey identifies a request ID of a session in the in-flight map, keeping 1 and "1" apart
+func requestKey(session string, id interface{}) string {
+	b, _ := json.Marshal(id)
+	return session + " " + string(b)
+}
+
+// handleCancelled aborts the in-flight tool call named by a notifications/cancelled message
+func (s *Server) handleCancelled(session string, params json.RawMessage) {
+	var cancelParams struct {
+		RequestID interface{} `json:"requestId"`
+		Reason    string      `json:"reason"`
+	}
+	if err := json.Unmarshal(params, &cancelParams); err != nil || cancelParams.RequestID == nil {
+		s.logger.Warn("invalid cancellation", "params", string(params))
+		return
+	}
+
+	s.inFlightLock.Lock()
+	cancel, ok := s.inFlight[requestKey(session, cancelParams.RequestID)]
+	s.inFlightLock.Unlock()
+	if !ok {
+		// The call may already have finished, which the protocol allows
+		s.logger.Debug("cancellation for unknown request", "id", cancelParams.RequestID)
+		return
+	}
+	s.logger.Debug("cancelling request", "id", cancelParams.RequestID, "reason", cancelParams.Reason)
+	cancel(errRequestCancelled)
+}
+
+// callStatus classifies a tool call outcome for the status metric label
+func callStatus(result CallToolResult, err error) string {
+	switch {
+	case errors.Is(err, errToolTimeout):
+		return StatusTimeout
+	case errors.Is(err, errRequestCancelled):
+		return StatusCancelled
+	case err != nil || result.IsError:
+		return StatusError
+	default:
+		return StatusSuccess
+	}
+}
+
+// toolErrorText returns the text content of a tool error result for logging
+func toolErrorText(result CallToolResult) string {
+	for _, c := range result.Content {
+		if c.Type == "text" {
+			return c.Text
+		}
+	}
+	return ""
+}
+
+// errToolTimeout is returned when a tool does not finish within the server's tool timeout
+var errToolTimeout = errors.New("tool timed out")
+
+// runTool invokes the tool handler with a per-call context bounded by the tool timeout.
+// The context is also cancelled by a notifications/cancelled message for id from the same session.
+func (s *Server) runTool(session string, id interface{}, tool RegisteredTool, args map[string]interface{}) (CallToolResult, error) {
+	ctx, cancelCall := context.WithCancelCause(context.Background())
+	defer cancelCall(nil)
+
+	key := requestKey(session, id)
+	s.inFlightLock.Lock()
+	if _, ok := s.inFlight[key]; ok {
+		// Replacing the entry would leave the running call impossible to cancel
+		s.inFlightLock.Unlock()
+		return CallToolResult{}, fmt.Errorf("%w: %v", errRequestInFlight, id)
+	}
+	s.inFlight[key] = cancelCall
+	s.inFlightLock.Unlock()
+	defer func() {
+		s.inFlightLock.Lock()
+		delete(s.inFlight, key)
+		s.inFlightLock.Unlock()
+	}()
+
+	cancel := context.CancelFunc(func() {})
+	if s.toolTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.toolTimeout)
+	}
+	defer cancel()
+
+	type outcome struct {
+		result CallToolResult
+		err    error
+	}
+	release, busy := s.acquireSlot(ctx, tool.Definition.Name)
+	if busy != nil {
+		return *busy, nil
+	}
+	done := make(chan outcome, 1)
+	if release != nil {
+		go func() {
+			// The slot is held until the handler returns, even if the call already timed out
+			defer release()
+			result, err := tool.Handler(ctx, args)
+			done <- outcome{result, err}
+		}()
+	}
+
+	select {
+	case o := <-done:
+		if s.maxResultSize > 0 {
+			for i, c := range o.result.Content {
+				o.result.Content[i].Text = TruncateText(c.Text, s.maxResultSize)
+			}
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		// Cancelling the context kills any child process started with exec.CommandContext
+		if errors.Is(context.Cause(ctx), errRequestCancelled) {
+			return CallToolResult{}, fmt.Errorf("%w: %s", errRequestCancelled, tool.Definition.Name)
+		}
+		return CallToolResult{}, fmt.Errorf("%w: %s did not finish within %s", errToolTimeout, tool.Definition.Name, s.toolTimeout)
+	}
+}
+
+// TruncateText cuts s to at most max bytes plus a "...[truncated N bytes]" marker, on a UTF-8 boundary
+func TruncateText(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...[truncated %d bytes]", s[:cut], len(s)-cut)
+}
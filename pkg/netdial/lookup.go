@@ -0,0 +1,35 @@
+package netdial
+
+import "sync"
+
+// LookupEach calls lookup once for every distinct non-empty key, running at most limit calls at a time,
+// and returns the results by key. Tools use it to resolve many addresses without one slow lookup
+// holding up the others or an unbounded number of queries in flight.
+func LookupEach[T any](keys []string, limit int, lookup func(key string) T) map[string]T {
+	results := make(map[string]T)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(limit, 1))
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			v := lookup(key)
+			lock.Lock()
+			results[key] = v
+			lock.Unlock()
+		}(key)
+	}
+	wg.Wait()
+	return results
+}
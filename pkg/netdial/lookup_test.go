@@ -0,0 +1,43 @@
+package netdial
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLookupEach(t *testing.T) {
+	var calls, running, peak atomic.Int32
+	lookup := func(key string) string {
+		calls.Add(1)
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return strings.ToUpper(key)
+	}
+
+	keys := []string{"a", "b", "", "a", "c", "d", "e", "b"}
+	got := LookupEach(keys, 2, lookup)
+	want := map[string]string{"a": "A", "b": "B", "c": "C", "d": "D", "e": "E"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LookupEach() = %v, want %v", got, want)
+	}
+	if calls.Load() != 5 {
+		t.Errorf("lookup called %d times, want 5 (duplicates and blanks skipped)", calls.Load())
+	}
+	if peak.Load() > 2 {
+		t.Errorf("%d lookups ran at once, want at most 2", peak.Load())
+	}
+
+	if got := LookupEach(nil, 0, lookup); len(got) != 0 {
+		t.Errorf("LookupEach(nil) = %v, want empty", got)
+	}
+}
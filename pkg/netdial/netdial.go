@@ -0,0 +1,68 @@
+// Package netdial holds the DNS resolver and TCP dialer shared by the tools that look up names or open connections,
+// so repeated probes reuse them instead of allocating their own
+package netdial
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout bounds establishing a TCP connection
+	DefaultDialTimeout = 5 * time.Second
+	// DefaultLookupTimeout bounds a single DNS lookup of the DNS tools
+	DefaultLookupTimeout = 5 * time.Second
+)
+
+var (
+	mu            sync.RWMutex
+	dialer        = newDialer(DefaultDialTimeout)
+	lookupTimeout = DefaultLookupTimeout
+
+	// system is the host's resolver, it dials through the shared dialer when the Go resolver is used
+	system = &net.Resolver{Dial: dial}
+)
+
+func newDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+}
+
+// SetTimeouts changes the dial and lookup timeouts, zero keeps the default
+func SetTimeouts(dialTimeout, lookup time.Duration) {
+	if dialTimeout <= 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+	if lookup <= 0 {
+		lookup = DefaultLookupTimeout
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	dialer = newDialer(dialTimeout)
+	lookupTimeout = lookup
+}
+
+// Dialer returns the shared dialer, callers must not modify it
+func Dialer() *net.Dialer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dialer
+}
+
+// LookupTimeout returns the timeout for a single DNS lookup
+func LookupTimeout() time.Duration {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lookupTimeout
+}
+
+// Resolver returns the shared system resolver
+func Resolver() *net.Resolver {
+	return system
+}
+
+// dial connects through the shared dialer
+func dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	return Dialer().DialContext(ctx, network, addr)
+}
@@ -0,0 +1,26 @@
+package netdial
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetTimeouts(t *testing.T) {
+	defer SetTimeouts(0, 0)
+
+	SetTimeouts(2*time.Second, 3*time.Second)
+	if got := Dialer().Timeout; got != 2*time.Second {
+		t.Errorf("dial timeout = %v, want 2s", got)
+	}
+	if got := LookupTimeout(); got != 3*time.Second {
+		t.Errorf("lookup timeout = %v, want 3s", got)
+	}
+
+	SetTimeouts(0, -time.Second)
+	if got := Dialer().Timeout; got != DefaultDialTimeout {
+		t.Errorf("dial timeout = %v, want default %v", got, DefaultDialTimeout)
+	}
+	if got := LookupTimeout(); got != DefaultLookupTimeout {
+		t.Errorf("lookup timeout = %v, want default %v", got, DefaultLookupTimeout)
+	}
+}
@@ -0,0 +1,42 @@
+package oui
+
+// builtinVendors is a trimmed set of prefixes commonly seen on servers and home networks.
+// Load a full IEEE dataset with -oui-file for complete coverage.
+func builtinVendors() map[string]string {
+	return map[string]string{
+		// Virtualization
+		"000569": "VMware, Inc.",
+		"000C29": "VMware, Inc.",
+		"001C14": "VMware, Inc.",
+		"005056": "VMware, Inc.",
+		"080027": "PCS Systemtechnik GmbH (VirtualBox)",
+		"00155D": "Microsoft Corporation (Hyper-V)",
+		"000D3A": "Microsoft Corporation",
+		"00163E": "Xensource, Inc.",
+		"001C42": "Parallels, Inc.",
+		"525400": "QEMU/KVM virtual NIC",
+		// Single-board computers
+		"B827EB": "Raspberry Pi Foundation",
+		"DCA632": "Raspberry Pi Trading Ltd",
+		"E45F01": "Raspberry Pi Trading Ltd",
+		"28CDC1": "Raspberry Pi Trading Ltd",
+		"D83ADD": "Raspberry Pi Trading Ltd",
+		// Server and network hardware
+		"00000C": "Cisco Systems, Inc",
+		"00180A": "Cisco Meraki",
+		"001B21": "Intel Corporate",
+		"3CFDFE": "Intel Corporate",
+		"A0369F": "Intel Corporate",
+		"002590": "Super Micro Computer, Inc.",
+		"0CC47A": "Super Micro Computer, Inc.",
+		"AC1F6B": "Super Micro Computer, Inc.",
+		"001422": "Dell Inc.",
+		"0002C9": "Mellanox Technologies, Inc.",
+		"001018": "Broadcom",
+		"00E04C": "Realtek Semiconductor Corp.",
+		"24A43C": "Ubiquiti Networks Inc.",
+		"001A11": "Google, Inc.",
+		"000393": "Apple, Inc.",
+		"000A95": "Apple, Inc.",
+	}
+}
@@ -0,0 +1,109 @@
+package oui
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// registry maps 24-bit OUI prefixes ("B827EB") to vendor names.
+// It starts with the small built-in table and grows when a full dataset is loaded.
+var registry = struct {
+	vendors map[string]string
+	sync.RWMutex
+}{vendors: builtinVendors()}
+
+// Lookup returns the vendor owning the OUI of mac, or an empty string when it is unknown.
+// MACs may use ':', '-' or '.' separators in any case.
+func Lookup(mac string) string {
+	prefix, ok := normalizePrefix(mac)
+	if !ok {
+		return ""
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.vendors[prefix]
+}
+
+// Load reads an IEEE MA-L dataset and adds it to the registry, overriding built-in names.
+// Both oui.txt ("B8-27-EB   (hex)		Raspberry Pi Foundation") and oui.csv are accepted.
+// It returns the number of prefixes loaded.
+func Load(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open OUI dataset: %w", err)
+	}
+	defer f.Close()
+
+	vendors, err := parse(f, strings.HasSuffix(strings.ToLower(path), ".csv"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse OUI dataset %s: %w", path, err)
+	}
+	if len(vendors) == 0 {
+		return 0, fmt.Errorf("no OUI entries found in %s", path)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	for prefix, vendor := range vendors {
+		registry.vendors[prefix] = vendor
+	}
+	return len(vendors), nil
+}
+
+// parse reads oui.csv (Registry,Assignment,Organization Name,Organization Address) or oui.txt
+func parse(r io.Reader, isCSV bool) (map[string]string, error) {
+	vendors := make(map[string]string)
+	if isCSV {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			if len(rec) < 3 || rec[0] != "MA-L" {
+				continue
+			}
+			if prefix, ok := normalizePrefix(rec[1]); ok {
+				vendors[prefix] = strings.TrimSpace(rec[2])
+			}
+		}
+		return vendors, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		prefix, vendor, ok := strings.Cut(scanner.Text(), "(hex)")
+		if !ok {
+			continue
+		}
+		if p, ok := normalizePrefix(strings.TrimSpace(prefix)); ok {
+			vendors[p] = strings.TrimSpace(vendor)
+		}
+	}
+	return vendors, scanner.Err()
+}
+
+// normalizePrefix returns the first 24 bits of a MAC as six upper-case hex digits
+func normalizePrefix(mac string) (string, bool) {
+	var b strings.Builder
+	for _, c := range mac {
+		switch {
+		case c == ':' || c == '-' || c == '.':
+			continue
+		case (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+			b.WriteRune(c)
+		default:
+			return "", false
+		}
+		if b.Len() == 6 {
+			return strings.ToUpper(b.String()), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package oui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"b8:27:eb:12:34:56", "Raspberry Pi Foundation"},
+		{"B8-27-EB-12-34-56", "Raspberry Pi Foundation"},
+		{"b827.eb12.3456", "Raspberry Pi Foundation"},
+		{"52:54:00:ab:cd:ef", "QEMU/KVM virtual NIC"},
+		{"aa:bb:cc:dd:ee:ff", ""},
+		{"b8:27", ""},
+		{"zz:27:eb:12:34:56", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Lookup(tt.mac); got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	txt := filepath.Join(dir, "oui.txt")
+	os.WriteFile(txt, []byte(`OUI/MA-L                                                    Organization
+company_id                                                  Organization
+                                                            Address
+
+00-22-72   (hex)		American Micro-Fuel Device Corp.
+002272     (base 16)		American Micro-Fuel Device Corp.
+				2181 Buchanan Loop
+`), 0o644)
+	csvPath := filepath.Join(dir, "oui.csv")
+	os.WriteFile(csvPath, []byte(`Registry,Assignment,Organization Name,Organization Address
+MA-L,00D0EF,IGT,"9295 PROTOTYPE DRIVE RENO NV US 89511"
+MA-M,70B3D5123,Ignored Corp,Somewhere
+`), 0o644)
+
+	tests := []struct {
+		path string
+		mac  string
+		want string
+	}{
+		{txt, "00:22:72:00:00:01", "American Micro-Fuel Device Corp."},
+		{csvPath, "00:d0:ef:00:00:01", "IGT"},
+	}
+	for _, tt := range tests {
+		n, err := Load(tt.path)
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", tt.path, err)
+		}
+		if n != 1 {
+			t.Errorf("Load(%s) = %d entries, want 1", tt.path, n)
+		}
+		if got := Lookup(tt.mac); got != tt.want {
+			t.Errorf("Lookup(%q) after Load = %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+	// Built-in entries survive a load
+	if got := Lookup("b8:27:eb:00:00:00"); got != "Raspberry Pi Foundation" {
+		t.Errorf("built-in entry lost after Load: %q", got)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	os.WriteFile(empty, []byte("nothing here\n"), 0o644)
+	if _, err := Load(empty); err == nil {
+		t.Error("Load() of a file without entries should fail")
+	}
+}
@@ -3,26 +3,71 @@ package port
 import (
 	"context"
 	"fmt"
+	"net"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+	"github.com/shirou/gopsutil/v4/process"
 )
 
 type PortStatus struct {
-	Port     int    `json:"port"`
-	Protocol string `json:"protocol"`
-	State    string `json:"state"`
-	Process  string `json:"process"` // e.g., "nginx (pid=1234)"
+	Port         int    `json:"port"`
+	Protocol     string `json:"protocol"`
+	Family       string `json:"family"` // ipv4, ipv6 or any (wildcard "*")
+	LocalAddress string `json:"local_address"`
+	State        string `json:"state"`
+	PeerAddress  string `json:"peer_address,omitempty"`
+	PeerHost     string `json:"peer_host,omitempty"` // Reverse DNS name of the peer, with Options.Resolve
+	Process      string `json:"process"`             // e.g., "nginx (pid=1234)"
+	PID          int32  `json:"pid,omitempty"`
+	User         string `json:"user,omitempty"` // Owner of the process holding the socket
+}
+
+// Options controls which sockets GetPortStatus reports
+type Options struct {
+	Port               int    // Specific port to report, 0 for all
+	Protocol           string // tcp, udp or all (default all)
+	IncludeEstablished bool   // Include non-listening sockets such as established connections
+	Resolve            bool   // Reverse-resolve peer addresses into PeerHost
 }
 
-// GetPortStatus returns the status of a specific port.
-// If port is 0, it returns all listening ports.
-func GetPortStatus(ctx context.Context, port int) ([]PortStatus, error) {
-	// Use ss -tulnpr to list tcp/udp, listening, numeric, processes
-	// -t: tcp, -u: udp, -l: listening, -n: numeric, -p: processes, -H: no header
+const (
+	// resolveConcurrency bounds parallel reverse lookups of peers
+	resolveConcurrency = 8
+	// resolveTimeout bounds each reverse lookup so an unresponsive resolver cannot stall the call
+	resolveTimeout = time.Second
+)
+
+// lookupAddr performs reverse lookups, replaced in tests
+var lookupAddr = netdial.Resolver().LookupAddr
+
+// processRegex extracts the name and pid from users:(("nginx",pid=1234,fd=6))
+var processRegex = regexp.MustCompile(`users:\(\("([^"]+)",pid=(\d+),`)
+
+// GetPortStatus returns the status of sockets matching opts.
+// By default only listening sockets are returned.
+func GetPortStatus(ctx context.Context, opts Options) ([]PortStatus, error) {
+	protocol := strings.ToLower(opts.Protocol)
+	switch protocol {
+	case "", "all":
+		protocol = "all"
+	case "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("invalid protocol '%s'. Allowed protocols: tcp, udp, all", opts.Protocol)
+	}
+
+	// Use ss to list tcp/udp sockets with processes
+	// -t: tcp, -u: udp, -l: listening, -a: all, -n: numeric, -p: processes, -H: no header
+	// Both -t and -u are always passed so the Netid column is present; protocol is filtered below.
 	// Note: -H might not be available on all ss versions, so we'll parse carefully.
 	args := []string{"-tulnpH"}
+	if opts.IncludeEstablished {
+		args = []string{"-tuanpH"}
+	}
 
 	cmd := exec.CommandContext(ctx, "ss", args...)
 	outputBytes, err := cmd.CombinedOutput()
@@ -30,7 +75,83 @@ func GetPortStatus(ctx context.Context, port int) ([]PortStatus, error) {
 		return nil, fmt.Errorf("ss command failed: %w", err)
 	}
 
-	output := string(outputBytes)
+	results := parseSSOutput(string(outputBytes), opts.Port, protocol)
+	resolveOwners(results)
+	if opts.Resolve {
+		resolvePeers(ctx, results)
+	}
+	return results, nil
+}
+
+// resolvePeers fills in PeerHost with the reverse DNS name of each peer.
+// Every distinct address is looked up once, concurrently; failed lookups leave PeerHost empty.
+func resolvePeers(ctx context.Context, results []PortStatus) {
+	ips := make([]string, len(results))
+	for i, r := range results {
+		ips[i] = peerIP(r.PeerAddress)
+	}
+	hosts := netdial.LookupEach(ips, resolveConcurrency, func(ip string) string {
+		lookupCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+		defer cancel()
+		names, err := lookupAddr(lookupCtx, ip)
+		if err != nil || len(names) == 0 {
+			return ""
+		}
+		return strings.TrimSuffix(names[0], ".")
+	})
+
+	for i := range results {
+		if ip := peerIP(results[i].PeerAddress); ip != "" {
+			results[i].PeerHost = hosts[ip]
+		}
+	}
+}
+
+// peerIP returns the IP of a peer address, or an empty string when there is none to resolve
+func peerIP(peer string) string {
+	if peer == "" {
+		return ""
+	}
+	host, _, err := splitAddress(peer)
+	if err != nil {
+		return ""
+	}
+	// Drop the zone of link-local addresses such as fe80::1%eth0
+	host, _, _ = strings.Cut(host, "%")
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return ""
+	}
+	return ip.String()
+}
+
+// resolveOwners fills in the User field from the owning process of each socket.
+// Processes that exited between the ss call and the lookup are left without a user.
+func resolveOwners(results []PortStatus) {
+	users := make(map[int32]string)
+	for i := range results {
+		pid := results[i].PID
+		if pid == 0 {
+			continue
+		}
+		if u, ok := users[pid]; ok {
+			results[i].User = u
+			continue
+		}
+
+		u := ""
+		if p, err := process.NewProcess(pid); err == nil {
+			if name, err := p.Username(); err == nil {
+				u = name
+			}
+		}
+		users[pid] = u
+		results[i].User = u
+	}
+}
+
+// parseSSOutput parses ss output lines, keeping those that match port and protocol
+func parseSSOutput(output string, port int, protocol string) []PortStatus {
 	lines := strings.Split(output, "\n")
 	var results []PortStatus
 
@@ -48,19 +169,15 @@ func GetPortStatus(ctx context.Context, port int) ([]PortStatus, error) {
 			continue // skip invalid lines
 		}
 
-		protocol := fields[0]
+		proto := fields[0]
 		state := fields[1]
 		localAddr := fields[4]
 
-		// Parse Local Address to get Port
-		// Format could be 127.0.0.1:80 or [::]:80
-		lastColon := strings.LastIndex(localAddr, ":")
-		if lastColon == -1 {
+		if protocol != "" && protocol != "all" && proto != protocol {
 			continue
 		}
 
-		portStr := localAddr[lastColon+1:]
-		p, err := strconv.Atoi(portStr)
+		host, p, err := splitAddress(localAddr)
 		if err != nil {
 			continue
 		}
@@ -70,29 +187,80 @@ func GetPortStatus(ctx context.Context, port int) ([]PortStatus, error) {
 			continue
 		}
 
+		// Peer address is only meaningful for connected sockets
+		peerAddr := ""
+		if len(fields) > 5 && !strings.HasSuffix(fields[5], ":*") {
+			peerAddr = fields[5]
+		}
+
 		// Parse Process Info
 		processInfo := ""
+		var pid int32
 		if len(fields) > 6 {
 			// users:(("nginx",pid=1234,fd=6))
 			rawProc := strings.Join(fields[6:], " ")
 			// Extract meaningful info
-			// Regex to extract name and pid
-			re := regexp.MustCompile(`users:\(\("([^"]+)",pid=(\d+),`)
-			matches := re.FindStringSubmatch(rawProc)
+			matches := processRegex.FindStringSubmatch(rawProc)
 			if len(matches) == 3 {
 				processInfo = fmt.Sprintf("%s (pid=%s)", matches[1], matches[2])
+				if n, err := strconv.ParseInt(matches[2], 10, 32); err == nil {
+					pid = int32(n)
+				}
 			} else {
 				processInfo = rawProc // Fallback
 			}
 		}
 
 		results = append(results, PortStatus{
-			Port:     p,
-			Protocol: protocol,
-			State:    state,
-			Process:  processInfo,
+			Port:         p,
+			Protocol:     proto,
+			Family:       addressFamily(host),
+			LocalAddress: host,
+			State:        state,
+			PeerAddress:  peerAddr,
+			Process:      processInfo,
+			PID:          pid,
 		})
 	}
 
-	return results, nil
+	return results
+}
+
+// splitAddress splits an ss address into host and port.
+// Handles 127.0.0.1:80, [::]:80, [fe80::1%eth0]:22, *:80 and bare IPv6 such as fe80::1:80
+func splitAddress(addr string) (string, int, error) {
+	var host, portStr string
+	if strings.HasPrefix(addr, "[") {
+		end := strings.Index(addr, "]")
+		if end == -1 || end+1 >= len(addr) || addr[end+1] != ':' {
+			return "", 0, fmt.Errorf("malformed address %q", addr)
+		}
+		host = addr[1:end]
+		portStr = addr[end+2:]
+	} else {
+		// The port always follows the final colon, even for bare IPv6
+		lastColon := strings.LastIndex(addr, ":")
+		if lastColon == -1 {
+			return "", 0, fmt.Errorf("missing port in address %q", addr)
+		}
+		host = addr[:lastColon]
+		portStr = addr[lastColon+1:]
+	}
+
+	p, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in address %q", addr)
+	}
+	return host, p, nil
+}
+
+// addressFamily classifies a host from ss output as ipv4, ipv6 or any
+func addressFamily(host string) string {
+	if host == "*" {
+		return "any"
+	}
+	if strings.Contains(host, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
 }
@@ -0,0 +1,225 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const ssFixture = `tcp   LISTEN 0      4096   0.0.0.0:22         0.0.0.0:*     users:(("sshd",pid=812,fd=3))
+tcp   ESTAB  0      0      10.0.0.5:22        10.0.0.9:51234 users:(("sshd",pid=1402,fd=4))
+udp   UNCONN 0      0      0.0.0.0:68         0.0.0.0:*     users:(("dhclient",pid=640,fd=7))
+tcp   LISTEN 0      511    [::]:80            [::]:*        users:(("nginx",pid=900,fd=6))
+`
+
+func TestParseSSOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     int
+		protocol string
+		expected []PortStatus
+	}{
+		{
+			name:     "All protocols",
+			port:     0,
+			protocol: "all",
+			expected: []PortStatus{
+				{Port: 22, Protocol: "tcp", Family: "ipv4", LocalAddress: "0.0.0.0", State: "LISTEN", Process: "sshd (pid=812)", PID: 812},
+				{Port: 22, Protocol: "tcp", Family: "ipv4", LocalAddress: "10.0.0.5", State: "ESTAB", PeerAddress: "10.0.0.9:51234", Process: "sshd (pid=1402)", PID: 1402},
+				{Port: 68, Protocol: "udp", Family: "ipv4", LocalAddress: "0.0.0.0", State: "UNCONN", Process: "dhclient (pid=640)", PID: 640},
+				{Port: 80, Protocol: "tcp", Family: "ipv6", LocalAddress: "::", State: "LISTEN", Process: "nginx (pid=900)", PID: 900},
+			},
+		},
+		{
+			name:     "UDP only",
+			port:     0,
+			protocol: "udp",
+			expected: []PortStatus{
+				{Port: 68, Protocol: "udp", Family: "ipv4", LocalAddress: "0.0.0.0", State: "UNCONN", Process: "dhclient (pid=640)", PID: 640},
+			},
+		},
+		{
+			name:     "Specific TCP port",
+			port:     80,
+			protocol: "tcp",
+			expected: []PortStatus{
+				{Port: 80, Protocol: "tcp", Family: "ipv6", LocalAddress: "::", State: "LISTEN", Process: "nginx (pid=900)", PID: 900},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSSOutput(ssFixture, tt.port, tt.protocol)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseSSOutput() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		addr     string
+		wantHost string
+		wantPort int
+		wantFam  string
+		wantErr  bool
+	}{
+		{addr: "[::]:443", wantHost: "::", wantPort: 443, wantFam: "ipv6"},
+		{addr: "[fe80::1%eth0]:22", wantHost: "fe80::1%eth0", wantPort: 22, wantFam: "ipv6"},
+		{addr: "127.0.0.1:80", wantHost: "127.0.0.1", wantPort: 80, wantFam: "ipv4"},
+		{addr: "fe80::1:80", wantHost: "fe80::1", wantPort: 80, wantFam: "ipv6"},
+		{addr: "*:53", wantHost: "*", wantPort: 53, wantFam: "any"},
+		{addr: "0.0.0.0:*", wantErr: true},
+		{addr: "[::1]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			host, p, err := splitAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if host != tt.wantHost || p != tt.wantPort {
+				t.Errorf("splitAddress() = %q, %d, want %q, %d", host, p, tt.wantHost, tt.wantPort)
+			}
+			if fam := addressFamily(host); fam != tt.wantFam {
+				t.Errorf("addressFamily() = %q, want %q", fam, tt.wantFam)
+			}
+		})
+	}
+}
+
+func TestParseSocketStates(t *testing.T) {
+	output := `tcp   LISTEN     0      4096   0.0.0.0:22         0.0.0.0:*
+tcp   ESTAB      0      0      10.0.0.5:22        10.0.0.9:51234
+tcp   ESTAB      0      0      10.0.0.5:443       10.0.0.7:40112
+tcp   TIME-WAIT  0      0      10.0.0.5:443       10.0.0.7:40100
+tcp   CLOSE-WAIT 1      0      10.0.0.5:5432      10.0.0.8:33000
+udp   UNCONN     0      0      0.0.0.0:68         0.0.0.0:*
+udp   ESTAB      0      0      10.0.0.5:41000     10.0.0.1:53
+`
+	got := parseSocketStates(output)
+	want := &SocketSummary{
+		TCP:      map[string]int{"LISTEN": 1, "ESTAB": 2, "TIME_WAIT": 1, "CLOSE_WAIT": 1},
+		TCPTotal: 5,
+		UDP:      map[string]int{"UNCONN": 1, "ESTAB": 1},
+		UDPTotal: 2,
+		Total:    7,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSocketStates() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolvePeers(t *testing.T) {
+	var lock sync.Mutex
+	lookups := make(map[string]int)
+	orig := lookupAddr
+	lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		lock.Lock()
+		lookups[addr]++
+		lock.Unlock()
+		switch addr {
+		case "93.184.216.34":
+			return []string{"example.com."}, nil
+		case "fe80::1":
+			return []string{"router.lan."}, nil
+		}
+		return nil, errors.New("no PTR record")
+	}
+	defer func() { lookupAddr = orig }()
+
+	results := []PortStatus{
+		{Port: 22, PeerAddress: "93.184.216.34:51000"},
+		{Port: 22, PeerAddress: "93.184.216.34:51001"},
+		{Port: 443, PeerAddress: "[fe80::1%eth0]:40000"},
+		{Port: 8080, PeerAddress: "10.0.0.9:33000"},
+		{Port: 53},
+	}
+	resolvePeers(context.Background(), results)
+
+	want := []string{"example.com", "example.com", "router.lan", "", ""}
+	for i, r := range results {
+		if r.PeerHost != want[i] {
+			t.Errorf("results[%d].PeerHost = %q, want %q", i, r.PeerHost, want[i])
+		}
+	}
+	if lookups["93.184.216.34"] != 1 {
+		t.Errorf("93.184.216.34 looked up %d times, want 1", lookups["93.184.216.34"])
+	}
+	if len(lookups) != 3 {
+		t.Errorf("lookups = %v, want 3 distinct peers", lookups)
+	}
+}
+
+func TestQueryBuildArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     QueryOptions
+		expected []string
+		wantErr  bool
+	}{
+		{name: "No options", opts: QueryOptions{}, expected: []string{"-H"}},
+		{
+			name:     "Flags and state",
+			opts:     QueryOptions{Flags: []string{"tcp", "Numeric", "info", "tcp"}, State: "time-wait"},
+			expected: []string{"-H", "-t", "-n", "-i", "state", "time-wait"},
+		},
+		{
+			name:     "Port filter",
+			opts:     QueryOptions{Flags: []string{"tcp"}, Port: 443},
+			expected: []string{"-H", "-t", "(", "sport", "=", ":443", "or", "dport", "=", ":443", ")"},
+		},
+		{name: "Unknown flag", opts: QueryOptions{Flags: []string{"-K"}}, wantErr: true},
+		{name: "Flag injection", opts: QueryOptions{Flags: []string{"tcp -F /etc/shadow"}}, wantErr: true},
+		{name: "Unknown state", opts: QueryOptions{State: "established; rm"}, wantErr: true},
+		{name: "Port out of range", opts: QueryOptions{Port: 70000}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.buildArgs()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got args %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTCPInfo(t *testing.T) {
+	output := "0      0      10.0.0.5:22     10.0.0.9:51234\n" +
+		"\t ts sack cubic wscale:7,7 rto:204 rtt:0.512/0.3 ato:40 mss:1448 pmtu:1500 rcvmss:1448 advmss:1448 cwnd:10 ssthresh:7 bytes_sent:12345 bytes_acked:12345 bytes_received:6789 send 226.2Mbps lastsnd:4 pacing_rate 452.5Mbps delivery_rate 100Mbps app_limited retrans:1/3 minrtt:0.2\n" +
+		"0      36     [::1]:8080      [::1]:40000\n" +
+		"\t bbr wscale:10,10 rto:204 rtt:0.102/0.123 mss:65483 cwnd:18 bbr:(bw:1bps,mrtt:0.01)\n" +
+		"0      0      10.0.0.5:443    10.0.0.7:1234\n" +
+		"\t wscale:7,7 rto:1000 mss:536\n" +
+		"0      0      10.0.0.5:444    10.0.0.7:1235\n"
+
+	want := []TCPInfo{
+		{
+			LocalAddress: "10.0.0.5:22", PeerAddress: "10.0.0.9:51234", Congestion: "cubic",
+			RTTMs: 0.512, RTTVarMs: 0.3, MinRTTMs: 0.2, Cwnd: 10, SSThresh: 7, MSS: 1448,
+			Retrans: 1, RetransTotal: 3, BytesSent: 12345, BytesRecv: 6789, SendRate: "226.2Mbps", DeliveryRate: "100Mbps",
+		},
+		{LocalAddress: "[::1]:8080", PeerAddress: "[::1]:40000", SendQ: 36, Congestion: "bbr", RTTMs: 0.102, RTTVarMs: 0.123, Cwnd: 18, MSS: 65483},
+	}
+	if got := parseTCPInfo(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTCPInfo() =\n%+v\nwant\n%+v", got, want)
+	}
+}
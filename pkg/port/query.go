@@ -0,0 +1,101 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryFlags maps the ss options ss_query accepts to their short flag.
+// Anything that could read files (-F), kill sockets (-K) or change the output
+// target is deliberately left out.
+var queryFlags = map[string]string{
+	"tcp":       "-t",
+	"udp":       "-u",
+	"raw":       "-w",
+	"unix":      "-x",
+	"listening": "-l",
+	"all":       "-a",
+	"numeric":   "-n",
+	"resolve":   "-r",
+	"processes": "-p",
+	"info":      "-i",
+	"memory":    "-m",
+	"timers":    "-o",
+	"extended":  "-e",
+	"ipv4":      "-4",
+	"ipv6":      "-6",
+	"summary":   "-s",
+}
+
+// queryStates are the state filters ss accepts after "state"
+var queryStates = map[string]bool{
+	"all": true, "connected": true, "synchronized": true, "bucket": true, "big": true,
+	"established": true, "syn-sent": true, "syn-recv": true, "fin-wait-1": true, "fin-wait-2": true,
+	"time-wait": true, "closed": true, "close-wait": true, "last-ack": true, "listening": true, "closing": true,
+}
+
+// QueryOptions is an ss invocation built from allowlisted parts
+type QueryOptions struct {
+	Flags []string // Names from queryFlags, e.g. "tcp", "numeric", "info"
+	State string   // Optional state filter, e.g. "time-wait"
+	Port  int      // Optional source or destination port filter (0 = any)
+}
+
+// buildArgs validates opts and returns the ss argv.
+// Every element comes from the allowlist or is a formatted integer, so no user text reaches ss.
+func (opts QueryOptions) buildArgs() ([]string, error) {
+	args := []string{"-H"}
+	seen := make(map[string]bool)
+	for _, f := range opts.Flags {
+		name := strings.ToLower(strings.TrimSpace(f))
+		flag, ok := queryFlags[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid flag '%s'. Allowed flags: %s", f, allowedList(queryFlags))
+		}
+		if !seen[flag] {
+			seen[flag] = true
+			args = append(args, flag)
+		}
+	}
+	if state := strings.ToLower(strings.TrimSpace(opts.State)); state != "" {
+		if !queryStates[state] {
+			return nil, fmt.Errorf("invalid state '%s'. Allowed states: %s", opts.State, allowedList(queryStates))
+		}
+		args = append(args, "state", state)
+	}
+	if opts.Port < 0 || opts.Port > 65535 {
+		return nil, fmt.Errorf("invalid port %d: must be between 0 (any) and 65535", opts.Port)
+	}
+	if opts.Port > 0 {
+		p := ":" + strconv.Itoa(opts.Port)
+		args = append(args, "(", "sport", "=", p, "or", "dport", "=", p, ")")
+	}
+	return args, nil
+}
+
+// Query runs ss with the allowlisted options and returns its raw output
+func Query(ctx context.Context, opts QueryOptions) (string, error) {
+	args, err := opts.buildArgs()
+	if err != nil {
+		return "", err
+	}
+	output, err := exec.CommandContext(ctx, "ss", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ss command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// allowedList returns the sorted keys of an allowlist joined by ", "
+func allowedList[V any](m map[string]V) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
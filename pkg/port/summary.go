@@ -0,0 +1,51 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SocketSummary counts sockets per state, the quick check for connection leaks and exhaustion
+type SocketSummary struct {
+	TCP      map[string]int `json:"tcp"` // e.g. ESTAB, TIME_WAIT, CLOSE_WAIT, LISTEN
+	TCPTotal int            `json:"tcp_total"`
+	UDP      map[string]int `json:"udp"` // UNCONN, or ESTAB for connected UDP sockets
+	UDPTotal int            `json:"udp_total"`
+	Total    int            `json:"total"`
+}
+
+// SummarizeSockets counts TCP and UDP sockets by state using ss -tuanH
+func SummarizeSockets(ctx context.Context) (*SocketSummary, error) {
+	output, err := exec.CommandContext(ctx, "ss", "-tuanH").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ss command failed: %w", err)
+	}
+	return parseSocketStates(string(output)), nil
+}
+
+// parseSocketStates tallies the Netid and State columns of ss output.
+// State names use underscores (TIME-WAIT becomes TIME_WAIT) so they read like kernel state names.
+func parseSocketStates(output string) *SocketSummary {
+	summary := &SocketSummary{TCP: make(map[string]int), UDP: make(map[string]int)}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		state := strings.ReplaceAll(fields[1], "-", "_")
+		switch fields[0] {
+		case "tcp":
+			summary.TCP[state]++
+			summary.TCPTotal++
+		case "udp":
+			summary.UDP[state]++
+			summary.UDPTotal++
+		default:
+			continue
+		}
+		summary.Total++
+	}
+	return summary
+}
@@ -0,0 +1,157 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// DefaultTCPInfoLimit and MaxTCPInfoLimit bound the connections GetTCPInfo returns
+const (
+	DefaultTCPInfoLimit = 100
+	MaxTCPInfoLimit     = 1000
+)
+
+// TCPInfo holds the kernel TCP metrics of one established connection, as reported by ss -ti
+type TCPInfo struct {
+	LocalAddress string  `json:"local_address"`
+	PeerAddress  string  `json:"peer_address"`
+	RecvQ        int     `json:"recv_q"`
+	SendQ        int     `json:"send_q"`
+	Congestion   string  `json:"congestion,omitempty"` // Congestion control algorithm, e.g. cubic or bbr
+	RTTMs        float64 `json:"rtt_ms"`               // Smoothed RTT
+	RTTVarMs     float64 `json:"rtt_var_ms"`
+	MinRTTMs     float64 `json:"min_rtt_ms,omitempty"`
+	Cwnd         int     `json:"cwnd"` // Congestion window in segments
+	SSThresh     int     `json:"ssthresh,omitempty"`
+	MSS          int     `json:"mss,omitempty"`
+	Retrans      int     `json:"retrans"`       // Unacknowledged retransmitted segments
+	RetransTotal int     `json:"retrans_total"` // Retransmissions over the connection's lifetime
+	BytesSent    int64   `json:"bytes_sent,omitempty"`
+	BytesRecv    int64   `json:"bytes_received,omitempty"`
+	SendRate     string  `json:"send_rate,omitempty"`     // e.g. 226.2Mbps
+	DeliveryRate string  `json:"delivery_rate,omitempty"` // e.g. 100Mbps
+}
+
+// TCPInfoResult lists connection metrics, Total counts every connection with metrics before the limit
+type TCPInfoResult struct {
+	Connections []TCPInfo `json:"connections"`
+	Total       int       `json:"total"`
+}
+
+// GetTCPInfo returns kernel TCP metrics of established connections using ss -tinH state established.
+// port narrows the connections to a local or peer port (0 = any).
+func GetTCPInfo(ctx context.Context, port, limit int) (*TCPInfoResult, error) {
+	if limit <= 0 {
+		limit = DefaultTCPInfoLimit
+	}
+	if limit > MaxTCPInfoLimit {
+		limit = MaxTCPInfoLimit
+	}
+	args, err := QueryOptions{Flags: []string{"tcp", "info", "numeric"}, State: "established", Port: port}.buildArgs()
+	if err != nil {
+		return nil, err
+	}
+	output, err := exec.CommandContext(ctx, "ss", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ss command failed: %w", err)
+	}
+
+	conns := parseTCPInfo(string(output))
+	res := &TCPInfoResult{Connections: conns, Total: len(conns)}
+	if len(conns) > limit {
+		res.Connections = conns[:limit]
+	}
+	return res, nil
+}
+
+// parseTCPInfo pairs each connection line of ss -ti output with the indented info line following it.
+// A single state filter drops the State column, so the addresses are taken from the end of the line.
+// Connections without an info line or without an RTT are skipped.
+func parseTCPInfo(output string) []TCPInfo {
+	conns := []TCPInfo{}
+	var current *TCPInfo
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			current = nil
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			n := len(fields)
+			recvQ, err1 := strconv.Atoi(fields[n-4])
+			sendQ, err2 := strconv.Atoi(fields[n-3])
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			current = &TCPInfo{LocalAddress: fields[n-2], PeerAddress: fields[n-1], RecvQ: recvQ, SendQ: sendQ}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if parseTCPInfoLine(current, line) {
+			conns = append(conns, *current)
+		}
+		current = nil
+	}
+	return conns
+}
+
+// tcpOptionFlags are the bare words ss prints before the congestion control algorithm
+var tcpOptionFlags = map[string]bool{"ts": true, "sack": true, "ecn": true, "ecnseen": true, "fastopen": true}
+
+// parseTCPInfoLine fills info from an ss info line such as
+// "cubic wscale:7,7 rto:204 rtt:0.5/0.25 mss:1448 cwnd:10 retrans:0/2 send 226.2Mbps".
+// It reports whether the line carried an RTT.
+func parseTCPInfoLine(info *TCPInfo, line string) bool {
+	fields := strings.Fields(line)
+	hasRTT, seenValue := false, false
+	for i, f := range fields {
+		key, value, found := strings.Cut(f, ":")
+		if !found {
+			switch {
+			case f == "send" && i+1 < len(fields):
+				info.SendRate = fields[i+1]
+			case f == "delivery_rate" && i+1 < len(fields):
+				info.DeliveryRate = fields[i+1]
+			case !seenValue && !tcpOptionFlags[f]:
+				// The algorithm follows the option flags and precedes every key:value field
+				info.Congestion = f
+			}
+			continue
+		}
+		seenValue = true
+		switch key {
+		case "rtt":
+			avg, variance, _ := strings.Cut(value, "/")
+			if v, err := strconv.ParseFloat(avg, 64); err == nil {
+				info.RTTMs = v
+				hasRTT = true
+			}
+			info.RTTVarMs, _ = strconv.ParseFloat(variance, 64)
+		case "minrtt":
+			info.MinRTTMs, _ = strconv.ParseFloat(value, 64)
+		case "cwnd":
+			info.Cwnd, _ = strconv.Atoi(value)
+		case "ssthresh":
+			info.SSThresh, _ = strconv.Atoi(value)
+		case "mss":
+			info.MSS, _ = strconv.Atoi(value)
+		case "retrans":
+			cur, total, _ := strings.Cut(value, "/")
+			info.Retrans, _ = strconv.Atoi(cur)
+			info.RetransTotal, _ = strconv.Atoi(total)
+		case "bytes_sent":
+			info.BytesSent, _ = strconv.ParseInt(value, 10, 64)
+		case "bytes_received":
+			info.BytesRecv, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return hasRTT
+}
@@ -0,0 +1,115 @@
+package route
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ashton2914/mcp-netutil/pkg/oui"
+)
+
+// Neighbor is a single entry of the ARP / NDP neighbor table
+type Neighbor struct {
+	Family    string `json:"family"` // ipv4 or ipv6
+	IP        string `json:"ip"`
+	MAC       string `json:"mac,omitempty"`
+	Vendor    string `json:"vendor,omitempty"` // Owner of the MAC's OUI prefix
+	Interface string `json:"interface"`
+	State     string `json:"state"` // IPv4: complete, incomplete, permanent; IPv6: reachable, stale, failed, ...
+}
+
+const procARP = "/proc/net/arp"
+
+// ARP entry flags from linux/if_arp.h
+const (
+	atfComplete  = 0x02
+	atfPermanent = 0x04
+)
+
+// GetNeighbors returns the IPv4 ARP cache from /proc and the IPv6 neighbor table from ip -6 neigh
+// If the ip command is unavailable only IPv4 entries are returned
+func GetNeighbors(ctx context.Context) ([]Neighbor, error) {
+	f, err := os.Open(procARP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procARP, err)
+	}
+	neighbors, err := parseARP(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := exec.CommandContext(ctx, "ip", "-6", "neigh", "show").Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return neighbors, nil
+	}
+	return append(neighbors, parseIPNeigh(string(output), "ipv6")...), nil
+}
+
+// parseARP parses /proc/net/arp
+// IP address HW type Flags HW address Mask Device
+func parseARP(r io.Reader) ([]Neighbor, error) {
+	neighbors := make([]Neighbor, 0)
+	scanner := bufio.NewScanner(r)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		flags, _ := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		state := "incomplete"
+		switch {
+		case flags&atfPermanent != 0:
+			state = "permanent"
+		case flags&atfComplete != 0:
+			// The ARP cache does not track reachability, only that the MAC was resolved
+			state = "complete"
+		}
+
+		n := Neighbor{Family: "ipv4", IP: fields[0], Interface: fields[5], State: state}
+		if fields[3] != "00:00:00:00:00:00" {
+			n.MAC = fields[3]
+			n.Vendor = oui.Lookup(n.MAC)
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors, scanner.Err()
+}
+
+// parseIPNeigh parses ip neigh output
+// fe80::1 dev eth0 lladdr aa:bb:cc:dd:ee:ff router REACHABLE
+func parseIPNeigh(output, family string) []Neighbor {
+	neighbors := make([]Neighbor, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		n := Neighbor{Family: family, IP: fields[0], State: strings.ToLower(fields[len(fields)-1])}
+		for i := 1; i < len(fields)-1; i++ {
+			switch fields[i] {
+			case "dev":
+				n.Interface = fields[i+1]
+			case "lladdr":
+				n.MAC = fields[i+1]
+				n.Vendor = oui.Lookup(n.MAC)
+			}
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors
+}
@@ -0,0 +1,209 @@
+package route
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Route is a single entry of the kernel routing table
+type Route struct {
+	Family      string   `json:"family"` // ipv4 or ipv6
+	Destination string   `json:"destination"`
+	Gateway     string   `json:"gateway,omitempty"`
+	Interface   string   `json:"interface"`
+	Metric      uint32   `json:"metric"`
+	Flags       []string `json:"flags"`
+}
+
+const (
+	procRouteIPv4 = "/proc/net/route"
+	procRouteIPv6 = "/proc/net/ipv6_route"
+)
+
+// Route flags from linux/route.h
+var routeFlags = []struct {
+	bit  uint32
+	name string
+}{
+	{0x0001, "up"},
+	{0x0002, "gateway"},
+	{0x0004, "host"},
+	{0x0010, "dynamic"},
+	{0x0020, "modified"},
+	{0x0200, "reject"},
+}
+
+// GetRoutes returns the IPv4 and IPv6 routing tables read from /proc
+func GetRoutes() ([]Route, error) {
+	f, err := os.Open(procRouteIPv4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procRouteIPv4, err)
+	}
+	routes, err := parseIPv4Routes(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// IPv6 may be disabled, in which case the file does not exist
+	f6, err := os.Open(procRouteIPv6)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return routes, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", procRouteIPv6, err)
+	}
+	defer f6.Close()
+	routes6, err := parseIPv6Routes(f6)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(routes, routes6...), nil
+}
+
+// DefaultRoute picks the default route with the lowest metric, preferring IPv4 over IPv6
+func DefaultRoute(routes []Route) (Route, bool) {
+	var best Route
+	found := false
+	for _, family := range []string{"ipv4", "ipv6"} {
+		for _, r := range routes {
+			if r.Family != family || r.Gateway == "" || (r.Destination != "0.0.0.0/0" && r.Destination != "::/0") {
+				continue
+			}
+			if !found || r.Metric < best.Metric {
+				best, found = r, true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return best, false
+}
+
+// parseIPv4Routes parses /proc/net/route
+// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+func parseIPv4Routes(r io.Reader) ([]Route, error) {
+	routes := make([]Route, 0)
+	scanner := bufio.NewScanner(r)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+
+		dst, err := parseHexIPv4(fields[1])
+		if err != nil {
+			continue
+		}
+		gw, err := parseHexIPv4(fields[2])
+		if err != nil {
+			continue
+		}
+		mask, err := parseHexIPv4(fields[7])
+		if err != nil {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[3], 16, 32)
+		metric, _ := strconv.ParseUint(fields[6], 10, 32)
+
+		ones, _ := net.IPMask(mask.To4()).Size()
+		route := Route{
+			Family:      "ipv4",
+			Destination: fmt.Sprintf("%s/%d", dst, ones),
+			Interface:   fields[0],
+			Metric:      uint32(metric),
+			Flags:       decodeFlags(uint32(flags)),
+		}
+		if !gw.Equal(net.IPv4zero) {
+			route.Gateway = gw.String()
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseIPv6Routes parses /proc/net/ipv6_route
+// dest dest_prefix src src_prefix next_hop metric refcnt use flags iface
+func parseIPv6Routes(r io.Reader) ([]Route, error) {
+	routes := make([]Route, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		dst, err := parseHexIPv6(fields[0])
+		if err != nil {
+			continue
+		}
+		prefix, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil {
+			continue
+		}
+		gw, err := parseHexIPv6(fields[4])
+		if err != nil {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+		flags, _ := strconv.ParseUint(fields[8], 16, 32)
+
+		route := Route{
+			Family:      "ipv6",
+			Destination: fmt.Sprintf("%s/%d", dst, prefix),
+			Interface:   fields[9],
+			Metric:      uint32(metric),
+			Flags:       decodeFlags(uint32(flags)),
+		}
+		if !gw.Equal(net.IPv6zero) {
+			route.Gateway = gw.String()
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseHexIPv4 decodes the little-endian hex form used in /proc/net/route
+func parseHexIPv4(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return nil, fmt.Errorf("invalid ipv4 hex %q", s)
+	}
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(b))
+	return ip, nil
+}
+
+// parseHexIPv6 decodes the 32 hex digit form used in /proc/net/ipv6_route
+func parseHexIPv6(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		return nil, fmt.Errorf("invalid ipv6 hex %q", s)
+	}
+	return net.IP(b), nil
+}
+
+// decodeFlags converts route flag bits to their names
+func decodeFlags(flags uint32) []string {
+	names := make([]string, 0)
+	for _, f := range routeFlags {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,115 @@
+package route
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIPv4Routes(t *testing.T) {
+	input := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+	expected := []Route{
+		{Family: "ipv4", Destination: "0.0.0.0/0", Gateway: "192.168.1.1", Interface: "eth0", Metric: 100, Flags: []string{"up", "gateway"}},
+		{Family: "ipv4", Destination: "192.168.1.0/24", Interface: "eth0", Metric: 100, Flags: []string{"up"}},
+	}
+
+	got, err := parseIPv4Routes(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseIPv4Routes() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseIPv4Routes() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestParseIPv6Routes(t *testing.T) {
+	input := `fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+`
+	expected := []Route{
+		{Family: "ipv6", Destination: "fe80::/64", Interface: "eth0", Metric: 256, Flags: []string{"up"}},
+		{Family: "ipv6", Destination: "::/0", Gateway: "fe80::1", Interface: "eth0", Metric: 1024, Flags: []string{"up", "gateway"}},
+	}
+
+	got, err := parseIPv6Routes(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseIPv6Routes() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseIPv6Routes() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestDefaultRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []Route
+		want   string // gateway, empty when none is found
+	}{
+		{"none", []Route{{Family: "ipv4", Destination: "192.168.1.0/24", Interface: "eth0"}}, ""},
+		{"lowest metric", []Route{
+			{Family: "ipv4", Destination: "0.0.0.0/0", Gateway: "10.0.0.1", Metric: 600},
+			{Family: "ipv4", Destination: "0.0.0.0/0", Gateway: "192.168.1.1", Metric: 100},
+		}, "192.168.1.1"},
+		{"ipv4 preferred", []Route{
+			{Family: "ipv6", Destination: "::/0", Gateway: "fe80::1", Metric: 1},
+			{Family: "ipv4", Destination: "0.0.0.0/0", Gateway: "192.168.1.1", Metric: 100},
+		}, "192.168.1.1"},
+		{"ipv6 only", []Route{{Family: "ipv6", Destination: "::/0", Gateway: "fe80::1", Metric: 1024}}, "fe80::1"},
+		{"on-link default", []Route{{Family: "ipv4", Destination: "0.0.0.0/0", Interface: "wg0"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DefaultRoute(tt.routes)
+			if ok != (tt.want != "") || got.Gateway != tt.want {
+				t.Errorf("DefaultRoute() = %+v, %v, want gateway %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseARP(t *testing.T) {
+	input := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        eth0
+192.168.1.50     0x1         0x0         00:00:00:00:00:00     *        eth0
+192.168.1.60     0x1         0x2         b8:27:eb:12:34:56     *        eth0
+`
+	expected := []Neighbor{
+		{Family: "ipv4", IP: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:ff", Interface: "eth0", State: "complete"},
+		{Family: "ipv4", IP: "192.168.1.50", Interface: "eth0", State: "incomplete"},
+		{Family: "ipv4", IP: "192.168.1.60", MAC: "b8:27:eb:12:34:56", Vendor: "Raspberry Pi Foundation", Interface: "eth0", State: "complete"},
+	}
+
+	got, err := parseARP(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseARP() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseARP() = %+v, want %+v", got, expected)
+	}
+
+	empty, err := parseARP(strings.NewReader("IP address       HW type     Flags       HW address            Mask     Device\n"))
+	if err != nil || len(empty) != 0 {
+		t.Errorf("parseARP() on empty table = %+v, %v, want empty", empty, err)
+	}
+}
+
+func TestParseIPNeigh(t *testing.T) {
+	input := `fe80::1 dev eth0 lladdr aa:bb:cc:dd:ee:ff router REACHABLE
+fe80::2 dev eth0 lladdr 11:22:33:44:55:66 STALE
+fe80::3 dev eth0 FAILED
+`
+	expected := []Neighbor{
+		{Family: "ipv6", IP: "fe80::1", MAC: "aa:bb:cc:dd:ee:ff", Interface: "eth0", State: "reachable"},
+		{Family: "ipv6", IP: "fe80::2", MAC: "11:22:33:44:55:66", Interface: "eth0", State: "stale"},
+		{Family: "ipv6", IP: "fe80::3", Interface: "eth0", State: "failed"},
+	}
+
+	got := parseIPNeigh(input, "ipv6")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseIPNeigh() = %+v, want %+v", got, expected)
+	}
+}
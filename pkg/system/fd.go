@@ -0,0 +1,165 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	// DefaultFDLimit is how many processes fd_usage lists when no limit is given
+	DefaultFDLimit = 20
+	// MaxFDLimit bounds the process list
+	MaxFDLimit = 200
+	// DefaultFDThreshold is the share of the soft limit (percent) at which a process is near its limit
+	DefaultFDThreshold = 80.0
+)
+
+const procFileNr = "/proc/sys/fs/file-nr"
+
+// FDProcess is the open file descriptor count of one process
+type FDProcess struct {
+	PID          int32   `json:"pid"`
+	Name         string  `json:"name"`
+	Username     string  `json:"username,omitempty"`
+	OpenFDs      int32   `json:"open_fds"`
+	SoftLimit    uint64  `json:"soft_limit,omitempty"` // RLIMIT_NOFILE, omitted when unlimited or unreadable
+	HardLimit    uint64  `json:"hard_limit,omitempty"`
+	UsagePercent float64 `json:"usage_percent,omitempty"` // OpenFDs relative to SoftLimit
+	NearLimit    bool    `json:"near_limit"`
+}
+
+// SystemFDs is the system-wide file handle usage from /proc/sys/fs/file-nr
+type SystemFDs struct {
+	Allocated    uint64  `json:"allocated"`
+	Max          uint64  `json:"max"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// FDUsage is the result of GetFDUsage
+type FDUsage struct {
+	System    *SystemFDs  `json:"system,omitempty"`
+	Threshold float64     `json:"threshold_percent"`
+	NearLimit []FDProcess `json:"near_limit"` // Every process at or above the threshold, highest usage first
+	Processes []FDProcess `json:"processes"`  // Processes with the most open descriptors
+}
+
+// GetFDUsage reports open file descriptors per process, sorted descending and capped at limit,
+// with the processes whose count reaches threshold percent of their soft RLIMIT_NOFILE.
+// Processes that cannot be inspected (exited, or other users' without root) are skipped.
+func GetFDUsage(ctx context.Context, limit int, threshold float64) (*FDUsage, error) {
+	if limit <= 0 {
+		limit = DefaultFDLimit
+	}
+	if limit > MaxFDLimit {
+		limit = MaxFDLimit
+	}
+	if threshold <= 0 {
+		threshold = DefaultFDThreshold
+	}
+	if threshold > 100 {
+		return nil, fmt.Errorf("invalid threshold %.1f: must be at most 100", threshold)
+	}
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	var fds []FDProcess
+	for _, p := range procs {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		n, err := p.NumFDsWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		fp := FDProcess{PID: p.Pid, Name: "unknown", OpenFDs: n}
+		if name, err := p.NameWithContext(ctx); err == nil {
+			fp.Name = name
+		}
+		if u, err := p.UsernameWithContext(ctx); err == nil {
+			fp.Username = u
+		}
+		if limits, err := p.RlimitWithContext(ctx); err == nil {
+			for _, l := range limits {
+				if l.Resource == process.RLIMIT_NOFILE {
+					fp.setLimits(l.Soft, l.Hard)
+				}
+			}
+		}
+		fds = append(fds, fp)
+	}
+
+	res := rankFDs(fds, limit, threshold)
+	if data, err := os.ReadFile(procFileNr); err == nil {
+		res.System, _ = parseFileNr(string(data))
+	}
+	return res, nil
+}
+
+// setLimits records the soft and hard limits, ignoring "unlimited"
+func (fp *FDProcess) setLimits(soft, hard uint64) {
+	if soft > 0 && soft < math.MaxInt64 {
+		fp.SoftLimit = soft
+		fp.UsagePercent = round2(float64(fp.OpenFDs) / float64(soft) * 100)
+	}
+	if hard > 0 && hard < math.MaxInt64 {
+		fp.HardLimit = hard
+	}
+}
+
+// rankFDs flags processes at or above threshold and sorts by open descriptors, keeping limit
+func rankFDs(fds []FDProcess, limit int, threshold float64) *FDUsage {
+	res := &FDUsage{Threshold: threshold, NearLimit: []FDProcess{}}
+	for i := range fds {
+		if fds[i].SoftLimit > 0 && fds[i].UsagePercent >= threshold {
+			fds[i].NearLimit = true
+			res.NearLimit = append(res.NearLimit, fds[i])
+		}
+	}
+	sort.SliceStable(res.NearLimit, func(i, j int) bool {
+		return res.NearLimit[i].UsagePercent > res.NearLimit[j].UsagePercent
+	})
+
+	sorted := append([]FDProcess(nil), fds...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].OpenFDs > sorted[j].OpenFDs })
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	res.Processes = sorted
+	return res
+}
+
+// parseFileNr parses /proc/sys/fs/file-nr: allocated, allocated but unused (always 0 since 2.6), max
+func parseFileNr(data string) (*SystemFDs, error) {
+	fields := strings.Fields(data)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected file-nr format %q", data)
+	}
+	var values [3]uint64
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file-nr value %q", f)
+		}
+		values[i] = v
+	}
+	s := &SystemFDs{Allocated: values[0], Max: values[2]}
+	if s.Max > 0 {
+		s.UsagePercent = round2(float64(s.Allocated) / float64(s.Max) * 100)
+	}
+	return s, nil
+}
+
+// round2 rounds to two decimal places
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
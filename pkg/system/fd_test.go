@@ -0,0 +1,71 @@
+package system
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseFileNr(t *testing.T) {
+	got, err := parseFileNr("3296\t0\t9223372036854775807\n")
+	if err != nil {
+		t.Fatalf("parseFileNr() error = %v", err)
+	}
+	if got.Allocated != 3296 || got.Max != math.MaxInt64 || got.UsagePercent != 0 {
+		t.Errorf("parseFileNr() = %+v", got)
+	}
+
+	got, err = parseFileNr("8000 0 10000")
+	if err != nil {
+		t.Fatalf("parseFileNr() error = %v", err)
+	}
+	if got.UsagePercent != 80 {
+		t.Errorf("parseFileNr() usage = %v, want 80", got.UsagePercent)
+	}
+
+	for _, bad := range []string{"", "1 2", "a 0 10"} {
+		if _, err := parseFileNr(bad); err == nil {
+			t.Errorf("parseFileNr(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRankFDs(t *testing.T) {
+	mk := func(pid, open int32, soft uint64) FDProcess {
+		fp := FDProcess{PID: pid, OpenFDs: open}
+		fp.setLimits(soft, soft*4)
+		return fp
+	}
+	fds := []FDProcess{
+		mk(1, 120, 1024),
+		mk(2, 950, 1024),    // 92.77%
+		mk(3, 50000, 1<<20), // Most descriptors but far from its limit
+		mk(4, 820, 1024),    // 80.08%
+		mk(5, 10, math.MaxUint64),
+	}
+
+	res := rankFDs(fds, 3, 80)
+
+	var pids []int32
+	for _, p := range res.Processes {
+		pids = append(pids, p.PID)
+	}
+	if want := []int32{3, 2, 4}; !reflect.DeepEqual(pids, want) {
+		t.Errorf("Processes PIDs = %v, want %v", pids, want)
+	}
+
+	pids = nil
+	for _, p := range res.NearLimit {
+		pids = append(pids, p.PID)
+		if !p.NearLimit {
+			t.Errorf("PID %d listed near limit without NearLimit set", p.PID)
+		}
+	}
+	if want := []int32{2, 4}; !reflect.DeepEqual(pids, want) {
+		t.Errorf("NearLimit PIDs = %v, want %v", pids, want)
+	}
+
+	if fds[4].SoftLimit != 0 || fds[4].UsagePercent != 0 {
+		t.Errorf("unlimited soft limit reported as %+v", fds[4])
+	}
+}
@@ -0,0 +1,50 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// HostInfo describes the machine for support tickets: kernel, distro, architecture and virtualization
+type HostInfo struct {
+	Hostname             string `json:"hostname"`
+	OS                   string `json:"os"`
+	Platform             string `json:"platform"`
+	PlatformFamily       string `json:"platform_family,omitempty"`
+	PlatformVersion      string `json:"platform_version"`
+	KernelVersion        string `json:"kernel_version"`
+	KernelArch           string `json:"kernel_arch"`
+	VirtualizationSystem string `json:"virtualization_system,omitempty"`
+	VirtualizationRole   string `json:"virtualization_role,omitempty"` // host or guest
+	LogicalCPUs          int    `json:"logical_cpus"`
+	UptimeSeconds        uint64 `json:"uptime_seconds"`
+}
+
+// GetHostInfo returns the kernel, OS and hardware summary of the machine
+func GetHostInfo(ctx context.Context) (*HostInfo, error) {
+	info, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host info: %w", err)
+	}
+	cpus, err := cpu.CountsWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count CPUs: %w", err)
+	}
+
+	return &HostInfo{
+		Hostname:             info.Hostname,
+		OS:                   info.OS,
+		Platform:             info.Platform,
+		PlatformFamily:       info.PlatformFamily,
+		PlatformVersion:      info.PlatformVersion,
+		KernelVersion:        info.KernelVersion,
+		KernelArch:           info.KernelArch,
+		VirtualizationSystem: info.VirtualizationSystem,
+		VirtualizationRole:   info.VirtualizationRole,
+		LogicalCPUs:          cpus,
+		UptimeSeconds:        info.Uptime,
+	}, nil
+}
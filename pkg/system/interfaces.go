@@ -0,0 +1,67 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ashton2914/mcp-netutil/pkg/oui"
+)
+
+// InterfaceInfo describes the configuration of a network interface
+type InterfaceInfo struct {
+	Name         string   `json:"name"`
+	HardwareAddr string   `json:"hardware_addr,omitempty"`
+	Vendor       string   `json:"vendor,omitempty"` // Owner of the MAC's OUI prefix
+	MTU          int      `json:"mtu"`
+	Up           bool     `json:"up"`
+	Loopback     bool     `json:"loopback"`
+	Flags        []string `json:"flags"`
+	IPv4         []string `json:"ipv4,omitempty"` // CIDR notation, e.g. "192.168.1.10/24"
+	IPv6         []string `json:"ipv6,omitempty"`
+}
+
+// GetInterfaces lists network interfaces with their MAC, MTU, flags and addresses
+func GetInterfaces() ([]InterfaceInfo, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	results := make([]InterfaceInfo, 0, len(ifaces))
+	for _, iface := range ifaces {
+		info := InterfaceInfo{
+			Name:         iface.Name,
+			HardwareAddr: iface.HardwareAddr.String(),
+			MTU:          iface.MTU,
+			Up:           iface.Flags&net.FlagUp != 0,
+			Loopback:     iface.Flags&net.FlagLoopback != 0,
+			Flags:        strings.Split(iface.Flags.String(), "|"),
+		}
+		if iface.Flags == 0 {
+			info.Flags = []string{}
+		}
+		if info.HardwareAddr != "" {
+			info.Vendor = oui.Lookup(info.HardwareAddr)
+		}
+
+		addrs, err := iface.Addrs()
+		if err == nil {
+			for _, addr := range addrs {
+				ipNet, ok := addr.(*net.IPNet)
+				if !ok {
+					continue
+				}
+				if ipNet.IP.To4() != nil {
+					info.IPv4 = append(info.IPv4, ipNet.String())
+				} else {
+					info.IPv6 = append(info.IPv6, ipNet.String())
+				}
+			}
+		}
+
+		results = append(results, info)
+	}
+
+	return results, nil
+}
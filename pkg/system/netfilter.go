@@ -0,0 +1,69 @@
+package system
+
+import (
+	"fmt"
+	stdnet "net"
+	"path"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// NetworkFilter selects the interfaces reported by GetNetworkUsage, e.g. only the uplinks on a
+// container host with dozens of veth and cni interfaces
+type NetworkFilter struct {
+	Include  []string // Glob patterns such as "eth*", "en*" or "bond*", empty includes every interface
+	Exclude  []string // Glob patterns such as "veth*" or "docker0", applied after Include
+	Loopback bool     // Report loopback interfaces, left out by default
+}
+
+// validate rejects malformed glob patterns
+func (f NetworkFilter) validate() error {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid interface pattern '%s': %w", p, err)
+		}
+	}
+	return nil
+}
+
+// allows reports whether an interface passes the filter
+func (f NetworkFilter) allows(name string) bool {
+	if !f.Loopback && isLoopback(name) {
+		return false
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	return !matchAny(f.Exclude, name)
+}
+
+// apply keeps the counters of the interfaces that pass the filter
+func (f NetworkFilter) apply(counters []net.IOCountersStat) []net.IOCountersStat {
+	kept := make([]net.IOCountersStat, 0, len(counters))
+	for _, c := range counters {
+		if f.allows(c.Name) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// matchAny reports whether name matches one of the glob patterns
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback reports whether the named interface is a loopback interface
+func isLoopback(name string) bool {
+	if iface, err := stdnet.InterfaceByName(name); err == nil {
+		return iface.Flags&stdnet.FlagLoopback != 0
+	}
+	// Interface gone or not visible, fall back to the usual names ("lo", "lo0", Windows "Loopback Pseudo-Interface 1")
+	return name == "lo" || name == "lo0" || strings.HasPrefix(strings.ToLower(name), "loopback")
+}
@@ -0,0 +1,39 @@
+package system
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNetworkFilter(t *testing.T) {
+	names := []string{"lo", "eth0", "ens3", "bond0", "docker0", "veth1a2b", "cni0"}
+	tests := []struct {
+		name   string
+		filter NetworkFilter
+		want   []string
+	}{
+		{"default drops loopback", NetworkFilter{}, []string{"eth0", "ens3", "bond0", "docker0", "veth1a2b", "cni0"}},
+		{"include loopback", NetworkFilter{Loopback: true}, names},
+		{"uplinks only", NetworkFilter{Include: []string{"eth*", "en*", "bond*"}}, []string{"eth0", "ens3", "bond0"}},
+		{"exclude container interfaces", NetworkFilter{Exclude: []string{"veth*", "cni*", "docker0"}}, []string{"eth0", "ens3", "bond0"}},
+		{"exclude wins over include", NetworkFilter{Include: []string{"e*"}, Exclude: []string{"ens*"}}, []string{"eth0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, n := range names {
+				if tt.filter.allows(n) {
+					got = append(got, n)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowed %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := (NetworkFilter{Include: []string{"eth["}}).validate(); err == nil {
+		t.Error("validate() with a malformed pattern returned no error")
+	}
+}
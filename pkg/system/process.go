@@ -1,8 +1,13 @@
 package system
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"runtime"
 	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/net"
@@ -10,170 +15,598 @@ import (
 )
 
 type ProcessInfo struct {
-	PID  int32  `json:"pid"`
-	Name string `json:"name"`
-	Val  string `json:"value"` // Formatted value (e.g. "12.5%" or "1024 MB")
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	Username   string  `json:"username,omitempty"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemPercent float32 `json:"mem_percent"`
+	RSSBytes   uint64  `json:"rss_bytes"`
+	Val        string  `json:"value,omitempty"`       // Formatted sort value (e.g. "12.5%" or "1024 MB"), empty when sorted by name
+	PPID       int32   `json:"ppid,omitempty"`        // Zombie processes only
+	ParentName string  `json:"parent_name,omitempty"` // Zombie processes only
 }
 
-// KillProcess terminates a process by its PID
-func KillProcess(pid int32) error {
+// KillResult describes the outcome of signalling a process
+type KillResult struct {
+	PID       int32  `json:"pid"`
+	Signal    string `json:"signal"`
+	Exited    bool   `json:"exited"`
+	Escalated bool   `json:"escalated,omitempty"` // SIGKILL was sent after the graceful timeout
+}
+
+// signalNames maps tool-facing signal names to signals
+var signalNames = map[string]syscall.Signal{
+	"term": syscall.SIGTERM,
+	"kill": syscall.SIGKILL,
+	"int":  syscall.SIGINT,
+	"hup":  syscall.SIGHUP,
+}
+
+const (
+	// exitPollInterval is how often a signalled process is checked for exit
+	exitPollInterval = 100 * time.Millisecond
+	// MaxExitWait is the longest SignalAndWait waits for a process to exit before giving up or escalating
+	MaxExitWait = 60 * time.Second
+)
+
+// ParseSignal converts a signal name (term, kill, int, hup) to a syscall.Signal
+// An empty name defaults to SIGTERM
+func ParseSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return syscall.SIGTERM, nil
+	}
+	sig, ok := signalNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("invalid signal '%s'. Allowed signals: term, kill, int, hup", name)
+	}
+	return sig, nil
+}
+
+// SignalProcess sends sig to the process with the given PID
+func SignalProcess(pid int32, sig syscall.Signal) error {
 	p, err := process.NewProcess(pid)
 	if err != nil {
 		return fmt.Errorf("process not found: %w", err)
 	}
-	return p.Kill()
+	if err := p.SendSignal(sig); err != nil {
+		return fmt.Errorf("failed to send %s to process %d: %w", sig, pid, err)
+	}
+	return nil
 }
 
-// KillProcessByName terminates a process by its name
-// It kills ALL processes matching the name
-func KillProcessByName(name string) error {
+// SignalAndWait sends sig to the process and waits up to wait (at most MaxExitWait) for it to exit
+// If graceful is true and the process is still alive after wait, SIGKILL is sent. Graceful is only
+// accepted with SIGTERM. SIGHUP asks a process to reload rather than exit, so it returns right after delivery.
+// When ctx ends during the wait the process is not escalated and ctx's error is returned.
+func SignalAndWait(ctx context.Context, pid int32, sig syscall.Signal, wait time.Duration, graceful bool) (*KillResult, error) {
+	if wait > MaxExitWait {
+		return nil, fmt.Errorf("invalid timeout %s: must be at most %s", wait, MaxExitWait)
+	}
+	if graceful && sig != syscall.SIGTERM {
+		return nil, fmt.Errorf("graceful is only supported with signal term, not %s", sig)
+	}
+	if err := SignalProcess(pid, sig); err != nil {
+		return nil, err
+	}
+
+	res := &KillResult{PID: pid, Signal: sig.String()}
+	if sig == syscall.SIGHUP {
+		return res, nil
+	}
+	exited, err := waitForExit(ctx, pid, wait)
+	res.Exited = exited
+	if err != nil {
+		return res, err
+	}
+	if res.Exited || !graceful {
+		return res, nil
+	}
+
+	if err := SignalProcess(pid, syscall.SIGKILL); err != nil {
+		// The process may have exited between the check and the escalation
+		if processGone(pid) {
+			res.Exited = true
+			return res, nil
+		}
+		return res, err
+	}
+	res.Escalated = true
+	res.Exited, err = waitForExit(ctx, pid, time.Second)
+	return res, err
+}
+
+// waitForExit polls until the process is gone, the timeout elapses or ctx ends
+func waitForExit(ctx context.Context, pid int32, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if processGone(pid) {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		if err := sleepContext(ctx, exitPollInterval); err != nil {
+			return false, err
+		}
+	}
+}
+
+// processGone reports whether the process no longer exists or is a zombie
+func processGone(pid int32) bool {
+	exists, err := process.PidExists(pid)
+	if err != nil || !exists {
+		return true
+	}
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return true
+	}
+	status, err := p.Status()
+	if err != nil {
+		return false
+	}
+	for _, st := range status {
+		if st == process.Zombie {
+			return true
+		}
+	}
+	return false
+}
+
+// KillByNameResult describes the outcome of KillProcessByName
+type KillByNameResult struct {
+	Name    string        `json:"name"`
+	DryRun  bool          `json:"dry_run"`
+	Matches []int32       `json:"matches"`
+	Killed  []int32       `json:"killed,omitempty"`
+	Failed  []KillFailure `json:"failed,omitempty"`
+	Skipped []int32       `json:"skipped,omitempty"` // Requested PIDs that no longer match the name
+}
+
+// KillFailure records a process that could not be signalled
+type KillFailure struct {
+	PID   int32  `json:"pid"`
+	Error string `json:"error"`
+}
+
+// FindProcessesByName returns the PIDs of all processes whose name equals name
+// The calling process is never included
+func FindProcessesByName(name string) ([]int32, error) {
 	procs, err := process.Processes()
 	if err != nil {
-		return fmt.Errorf("failed to list processes: %w", err)
+		return nil, fmt.Errorf("failed to list processes: %w", err)
 	}
 
-	killed := 0
+	selfPID := int32(os.Getpid())
+	pids := make([]int32, 0)
 	for _, p := range procs {
+		if p.Pid == selfPID {
+			continue
+		}
 		pName, err := p.Name()
 		if err != nil {
 			continue // Skip processes we can't access
 		}
 		if pName == name {
-			if err := p.Kill(); err != nil {
-				return fmt.Errorf("failed to kill process %d (%s): %w", p.Pid, pName, err)
+			pids = append(pids, p.Pid)
+		}
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	return pids, nil
+}
+
+// KillProcessByName sends sig to ALL processes matching the name
+// When dryRun is true, matching PIDs are returned without signalling them.
+// When pids is not empty only those PIDs are signalled, and only if they still match the name,
+// so a confirmed call acts on the processes reviewed in the dry run and not on ones started since.
+func KillProcessByName(name string, sig syscall.Signal, dryRun bool, pids []int32) (*KillByNameResult, error) {
+	if name == "" {
+		return nil, fmt.Errorf("process name cannot be empty")
+	}
+
+	matches, err := FindProcessesByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &KillByNameResult{Name: name, DryRun: dryRun, Matches: matches}
+	if dryRun {
+		return res, nil
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no process found with name %s", name)
+	}
+
+	var targets []int32
+	targets, res.Skipped = selectPIDs(matches, pids, int32(os.Getpid()))
+	for _, pid := range targets {
+		if err := SignalProcess(pid, sig); err != nil {
+			res.Failed = append(res.Failed, KillFailure{PID: pid, Error: err.Error()})
+			continue
+		}
+		res.Killed = append(res.Killed, pid)
+	}
+
+	return res, nil
+}
+
+// selectPIDs returns the matches to signal and the requested PIDs skipped because they no longer match.
+// An empty requested list selects every match. selfPID is never selected.
+func selectPIDs(matches, requested []int32, selfPID int32) (targets, skipped []int32) {
+	if len(requested) == 0 {
+		for _, pid := range matches {
+			if pid != selfPID {
+				targets = append(targets, pid)
 			}
-			killed++
 		}
+		return targets, nil
 	}
 
-	if killed == 0 {
-		return fmt.Errorf("no process found with name %s", name)
+	matched := make(map[int32]bool, len(matches))
+	for _, pid := range matches {
+		matched[pid] = true
 	}
+	seen := make(map[int32]bool, len(requested))
+	for _, pid := range requested {
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		if pid == selfPID || !matched[pid] {
+			skipped = append(skipped, pid)
+			continue
+		}
+		targets = append(targets, pid)
+	}
+	return targets, skipped
+}
 
-	return nil
+// DefaultTopN is the number of processes reported when no explicit count is given
+const DefaultTopN = 10
+
+// MaxTopN is the upper bound accepted for the top-N process count
+const MaxTopN = 100
+
+// ProcessOptions controls the process rankings of GetProcessStats
+type ProcessOptions struct {
+	TopN       int    // Processes per ranking, default 10, at most 100
+	SortBy     string // cpu, mem, rss or name; empty returns the default top CPU and top memory rankings
+	NameFilter string // Only rank processes whose name contains this (case-insensitive)
+	// IncludeKernelThreads ranks kernel threads and the calling process too, which are left out by
+	// default because transient kthreads and the scan itself distort the top list on idle systems
+	IncludeKernelThreads bool
 }
 
-// GetProcessStats returns the top 10 CPU and Memory consuming processes
-// It monitors CPU usage over the specified duration
-func GetProcessStats(duration time.Duration) (topCPU []ProcessInfo, topMem []ProcessInfo, err error) {
-	procs, err := process.Processes()
+// ProcessStats holds the rankings returned by GetProcessStats
+type ProcessStats struct {
+	TopCPU []ProcessInfo // Set when SortBy is empty
+	TopMem []ProcessInfo // Set when SortBy is empty
+	Sorted []ProcessInfo // Set when SortBy is given
+	// Zombies lists defunct processes not yet reaped by their parent, regardless of the name filter
+	Zombies []ProcessInfo
+}
+
+// processSortKeys are the accepted SortBy values
+var processSortKeys = map[string]bool{"cpu": true, "mem": true, "rss": true, "name": true}
+
+// withDefaults validates opts and fills in unset values
+func (opts ProcessOptions) withDefaults() (ProcessOptions, error) {
+	if opts.TopN <= 0 {
+		opts.TopN = DefaultTopN
+	}
+	if opts.TopN > MaxTopN {
+		opts.TopN = MaxTopN
+	}
+	opts.SortBy = strings.ToLower(opts.SortBy)
+	if opts.SortBy != "" && !processSortKeys[opts.SortBy] {
+		return opts, fmt.Errorf("invalid sort_by '%s'. Allowed values: cpu, mem, rss, name", opts.SortBy)
+	}
+	return opts, nil
+}
+
+// GetProcessStats ranks processes by CPU usage measured over duration and by memory.
+// The name filter and sort are applied before truncating to TopN.
+// Kernel threads and the calling process itself are excluded from the ranking unless IncludeKernelThreads is set
+func GetProcessStats(ctx context.Context, duration time.Duration, opts ProcessOptions) (*ProcessStats, error) {
+	opts, err := opts.withDefaults()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to list processes: %w", err)
+		return nil, err
 	}
 
-	// Wrapper to hold process and its stats
-	type procStats struct {
-		pid  int32
-		name string
-		p    *process.Process
-		cpu  float64
-		mem  float32
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
 	}
 
-	// Create wrappers and initialize
-	wrappers := make([]*procStats, 0, len(procs))
+	selfPID := int32(os.Getpid())
+
+	// Initialize the CPU counter of every process, the second call below measures over duration
+	sampled := make([]*process.Process, 0, len(procs))
 	for _, p := range procs {
-		// Initialize CPU counter
+		if !opts.IncludeKernelThreads && (p.Pid == selfPID || isKernelThread(p)) {
+			continue
+		}
 		p.Percent(0)
-		wrappers = append(wrappers, &procStats{p: p, pid: p.Pid})
+		sampled = append(sampled, p)
+	}
+
+	if err := sleepContext(ctx, duration); err != nil {
+		return nil, err
+	}
+
+	infos := make([]ProcessInfo, 0, len(sampled))
+	zombies := []ProcessInfo{}
+	for _, p := range sampled {
+		info := ProcessInfo{PID: p.Pid, Name: "unknown"}
+		if n, err := p.Name(); err == nil {
+			info.Name = n
+		}
+		// A process that exited since the listing just fails these lookups and keeps zero values
+		if status, err := p.Status(); err == nil && isZombie(status) {
+			zombies = append(zombies, zombieInfo(p, info))
+			continue
+		}
+		if c, err := p.Percent(0); err == nil {
+			info.CPUPercent = c
+		}
+		// Memory is instantaneous
+		if m, err := p.MemoryPercent(); err == nil {
+			info.MemPercent = m
+		}
+		if mi, err := p.MemoryInfo(); err == nil {
+			info.RSSBytes = mi.RSS
+		}
+		if u, err := p.Username(); err == nil {
+			info.Username = u
+		}
+		infos = append(infos, info)
 	}
 
-	time.Sleep(duration)
+	stats := &ProcessStats{Zombies: zombies}
+	if opts.SortBy != "" {
+		stats.Sorted = rankProcesses(infos, opts.SortBy, opts.NameFilter, opts.TopN)
+	} else {
+		stats.TopCPU = rankProcesses(infos, "cpu", opts.NameFilter, opts.TopN)
+		stats.TopMem = rankProcesses(infos, "mem", opts.NameFilter, opts.TopN)
+	}
+	return stats, nil
+}
 
-	for _, w := range wrappers {
-		// Check CPU
-		if c, err := w.p.Percent(0); err == nil {
-			w.cpu = c
+// isZombie reports whether a process status from p.Status() marks a defunct process
+func isZombie(status []string) bool {
+	for _, st := range status {
+		if st == process.Zombie {
+			return true
 		}
+	}
+	return false
+}
 
-		// Check Memory (instant is fine)
-		if m, err := w.p.MemoryPercent(); err == nil {
-			w.mem = m
+// zombieInfo adds the parent of a zombie to info, the parent is the one that must reap it
+func zombieInfo(p *process.Process, info ProcessInfo) ProcessInfo {
+	info.Val = "zombie"
+	if u, err := p.Username(); err == nil {
+		info.Username = u
+	}
+	ppid, err := p.Ppid()
+	if err != nil {
+		return info
+	}
+	info.PPID = ppid
+	if parent, err := process.NewProcess(ppid); err == nil {
+		if n, err := parent.Name(); err == nil {
+			info.ParentName = n
 		}
+	}
+	return info
+}
 
-		// Try to get name
-		if n, err := w.p.Name(); err == nil {
-			w.name = n
-		} else {
-			w.name = "unknown"
+// rankProcesses filters procs by name, sorts them by sortBy and keeps the first topN.
+// Numeric keys sort descending, names ascending; Val is formatted from the sort key.
+func rankProcesses(procs []ProcessInfo, sortBy, nameFilter string, topN int) []ProcessInfo {
+	filter := strings.ToLower(nameFilter)
+	ranked := make([]ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		if filter == "" || strings.Contains(strings.ToLower(p.Name), filter) {
+			ranked = append(ranked, p)
 		}
 	}
 
-	// Sort and extract top 10 CPU
-	sort.Slice(wrappers, func(i, j int) bool {
-		return wrappers[i].cpu > wrappers[j].cpu
-	})
+	var less func(a, b ProcessInfo) bool
+	switch sortBy {
+	case "mem":
+		less = func(a, b ProcessInfo) bool { return a.MemPercent > b.MemPercent }
+	case "rss":
+		less = func(a, b ProcessInfo) bool { return a.RSSBytes > b.RSSBytes }
+	case "name":
+		less = func(a, b ProcessInfo) bool {
+			an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name)
+			if an != bn {
+				return an < bn
+			}
+			return a.PID < b.PID
+		}
+	default:
+		less = func(a, b ProcessInfo) bool { return a.CPUPercent > b.CPUPercent }
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return less(ranked[i], ranked[j]) })
+
+	if len(ranked) > topN {
+		ranked = ranked[:topN]
+	}
+	for i := range ranked {
+		switch sortBy {
+		case "mem":
+			ranked[i].Val = fmt.Sprintf("%.2f%%", ranked[i].MemPercent)
+		case "rss":
+			ranked[i].Val = HumanizeBytes(float64(ranked[i].RSSBytes))
+		case "name":
+			// The name is already in its own field
+		default:
+			ranked[i].Val = fmt.Sprintf("%.2f%%", ranked[i].CPUPercent)
+		}
+	}
+	return ranked
+}
 
-	count := 10
-	if len(wrappers) < 10 {
-		count = len(wrappers)
+// MaxProcessMatches caps the number of entries returned by FindProcesses
+const MaxProcessMatches = 200
+
+// ProcessEntry describes a single process returned by FindProcesses
+type ProcessEntry struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	Username   string  `json:"username,omitempty"`
+	Cmdline    string  `json:"cmdline,omitempty"`
+	CPUPercent float64 `json:"cpu_percent"` // Average since process start
+	RSSBytes   uint64  `json:"rss_bytes"`
+}
+
+// FindProcesses returns processes whose name contains nameSubstr (case-insensitive)
+// An empty nameSubstr matches all processes. Results are ordered by CPU usage
+// and capped at MaxProcessMatches.
+func FindProcesses(nameSubstr string) ([]ProcessEntry, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
 	}
 
-	topCPU = make([]ProcessInfo, 0, count)
-	for i := 0; i < count; i++ {
-		w := wrappers[i]
-		// Filter out 0 usage if undesired, but typically top processes might include low usage if system is idle
-		topCPU = append(topCPU, ProcessInfo{
-			PID:  w.pid,
-			Name: w.name,
-			Val:  fmt.Sprintf("%.2f%%", w.cpu),
-		})
+	filter := strings.ToLower(nameSubstr)
+	results := make([]ProcessEntry, 0)
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue // Process exited or is inaccessible
+		}
+		if filter != "" && !strings.Contains(strings.ToLower(name), filter) {
+			continue
+		}
+
+		entry := ProcessEntry{PID: p.Pid, Name: name}
+		if u, err := p.Username(); err == nil {
+			entry.Username = u
+		}
+		if c, err := p.Cmdline(); err == nil {
+			entry.Cmdline = c
+		}
+		if c, err := p.CPUPercent(); err == nil {
+			entry.CPUPercent = c
+		}
+		if mi, err := p.MemoryInfo(); err == nil {
+			entry.RSSBytes = mi.RSS
+		}
+		results = append(results, entry)
 	}
 
-	// Sort and extract top 10 Memory
-	sort.Slice(wrappers, func(i, j int) bool {
-		return wrappers[i].mem > wrappers[j].mem
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CPUPercent > results[j].CPUPercent
 	})
 
-	topMem = make([]ProcessInfo, 0, count)
-	for i := 0; i < count; i++ {
-		w := wrappers[i]
-		topMem = append(topMem, ProcessInfo{
-			PID:  w.pid,
-			Name: w.name,
-			Val:  fmt.Sprintf("%.2f%%", w.mem),
-		})
+	if len(results) > MaxProcessMatches {
+		results = results[:MaxProcessMatches]
+	}
+
+	return results, nil
+}
+
+// isKernelThread reports whether p is a kernel thread
+func isKernelThread(p *process.Process) bool {
+	name, _ := p.Name()
+	var ppid int32
+	if runtime.GOOS == "linux" {
+		ppid, _ = p.Ppid()
 	}
+	return kernelThread(runtime.GOOS, p.Pid, ppid, name)
+}
 
-	return topCPU, topMem, nil
+// kernelThread reports whether a process is a kernel thread.
+// On Linux all kernel threads are kthreadd (PID 2) or its children, elsewhere PID 2
+// is an ordinary process. Kernel threads without a command line are also listed with
+// a bracketed name such as "[kworker/0:1]".
+func kernelThread(goos string, pid, ppid int32, name string) bool {
+	if len(name) > 2 && strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		return true
+	}
+	return goos == "linux" && (pid == 2 || ppid == 2)
 }
 
 type NetworkStats struct {
 	Interface string `json:"interface"`
 	Rx        string `json:"rx"` // e.g. "10.5 KB/s"
 	Tx        string `json:"tx"` // e.g. "5.2 KB/s"
+	// Errors and drops during the sample window, a nonzero drop count points at NIC or ring buffer saturation
+	ErrIn   uint64 `json:"errin"`
+	ErrOut  uint64 `json:"errout"`
+	DropIn  uint64 `json:"dropin"`
+	DropOut uint64 `json:"dropout"`
+	// Counters since boot (or since the driver last reset them)
+	RxBytesTotal uint64 `json:"rx_bytes_total"`
+	TxBytesTotal uint64 `json:"tx_bytes_total"`
+	ErrInTotal   uint64 `json:"errin_total"`
+	ErrOutTotal  uint64 `json:"errout_total"`
+	DropInTotal  uint64 `json:"dropin_total"`
+	DropOutTotal uint64 `json:"dropout_total"`
 }
 
-// GetNetworkUsage returns network usage per interface over the duration
-func GetNetworkUsage(duration time.Duration) ([]NetworkStats, error) {
-	startStats, err := net.IOCounters(true)
+const (
+	// DefaultNetworkSample is the sample window of the network_usage tool
+	DefaultNetworkSample = time.Second
+	// MinNetworkSample and MaxNetworkSample bound the window, shorter ones give noisy rates
+	MinNetworkSample = 100 * time.Millisecond
+	MaxNetworkSample = 30 * time.Second
+)
+
+// GetNetworkUsage returns network usage over the duration for every interface that passes filter
+func GetNetworkUsage(ctx context.Context, duration time.Duration, filter NetworkFilter) ([]NetworkStats, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
+	startStats, err := net.IOCountersWithContext(ctx, true)
 	if err != nil {
 		return nil, err
 	}
 
-	time.Sleep(duration)
+	if err := sleepContext(ctx, duration); err != nil {
+		return nil, err
+	}
 
-	endStats, err := net.IOCounters(true)
+	endStats, err := net.IOCountersWithContext(ctx, true)
 	if err != nil {
 		return nil, err
 	}
 
-	var results []NetworkStats
-	seconds := duration.Seconds()
+	return networkStats(startStats, filter.apply(endStats), duration.Seconds()), nil
+}
 
+// networkStats computes per-interface rates from two counter snapshots taken seconds apart.
+// Interfaces missing from either snapshot are skipped.
+func networkStats(startStats, endStats []net.IOCountersStat, seconds float64) []NetworkStats {
+	results := make([]NetworkStats, 0, len(endStats))
 	for _, end := range endStats {
 		for _, start := range startStats {
 			if end.Name == start.Name {
-				rxBytes := end.BytesRecv - start.BytesRecv
-				txBytes := end.BytesSent - start.BytesSent
+				rxBytes := counterDelta(start.BytesRecv, end.BytesRecv)
+				txBytes := counterDelta(start.BytesSent, end.BytesSent)
 
 				rxRate := float64(rxBytes) / seconds
 				txRate := float64(txBytes) / seconds
 
 				results = append(results, NetworkStats{
-					Interface: end.Name,
-					Rx:        humanizeBytes(rxRate) + "/s",
-					Tx:        humanizeBytes(txRate) + "/s",
+					Interface:    end.Name,
+					Rx:           HumanizeBytes(rxRate) + "/s",
+					Tx:           HumanizeBytes(txRate) + "/s",
+					ErrIn:        counterDelta(start.Errin, end.Errin),
+					ErrOut:       counterDelta(start.Errout, end.Errout),
+					DropIn:       counterDelta(start.Dropin, end.Dropin),
+					DropOut:      counterDelta(start.Dropout, end.Dropout),
+					RxBytesTotal: end.BytesRecv,
+					TxBytesTotal: end.BytesSent,
+					ErrInTotal:   end.Errin,
+					ErrOutTotal:  end.Errout,
+					DropInTotal:  end.Dropin,
+					DropOutTotal: end.Dropout,
 				})
 				break
 			}
@@ -185,10 +618,32 @@ func GetNetworkUsage(duration time.Duration) ([]NetworkStats, error) {
 		return results[i].Interface < results[j].Interface
 	})
 
-	return results, nil
+	return results
+}
+
+// counterDelta returns end - start, or 0 if the counter went backwards because it was reset
+// (driver reload) or wrapped (32-bit counters), instead of underflowing into an absurd rate
+func counterDelta(start, end uint64) uint64 {
+	if end < start {
+		return 0
+	}
+	return end - start
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func humanizeBytes(s float64) string {
+// HumanizeBytes renders a byte count with a binary unit, e.g. "2.1 GB"
+func HumanizeBytes(s float64) string {
 	sizes := []string{"B", "KB", "MB", "GB", "TB"}
 	i := 0
 	for s >= 1024 && i < len(sizes)-1 {
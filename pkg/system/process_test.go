@@ -0,0 +1,253 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+func TestRankProcesses(t *testing.T) {
+	procs := []ProcessInfo{
+		{PID: 1, Name: "systemd", CPUPercent: 0.1, MemPercent: 0.2, RSSBytes: 12 << 20},
+		{PID: 100, Name: "nginx", CPUPercent: 5, MemPercent: 1.5, RSSBytes: 40 << 20},
+		{PID: 101, Name: "nginx", CPUPercent: 12, MemPercent: 1.1, RSSBytes: 60 << 20},
+		{PID: 102, Name: "nginx", CPUPercent: 2, MemPercent: 2.4, RSSBytes: 30 << 20},
+		{PID: 200, Name: "postgres", CPUPercent: 30, MemPercent: 8, RSSBytes: 512 << 20},
+		{PID: 300, Name: "Nginx-exporter", CPUPercent: 0.5, MemPercent: 0.3, RSSBytes: 20 << 20},
+	}
+
+	tests := []struct {
+		name       string
+		sortBy     string
+		nameFilter string
+		topN       int
+		wantPIDs   []int32
+		wantVal    string // Val of the first entry
+	}{
+		{"cpu", "cpu", "", 3, []int32{200, 101, 100}, "30.00%"},
+		{"mem", "mem", "", 2, []int32{200, 102}, "8.00%"},
+		{"rss", "rss", "", 2, []int32{200, 101}, "512.0 MB"},
+		{"name", "name", "", 4, []int32{100, 101, 102, 300}, ""},
+		{"mem among nginx", "mem", "nginx", 10, []int32{102, 100, 101, 300}, "2.40%"},
+		{"filter truncates after sorting", "cpu", "NGINX", 2, []int32{101, 100}, "12.00%"},
+		{"no match", "cpu", "redis", 10, []int32{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankProcesses(procs, tt.sortBy, tt.nameFilter, tt.topN)
+			pids := make([]int32, 0, len(got))
+			for _, p := range got {
+				pids = append(pids, p.PID)
+			}
+			if !reflect.DeepEqual(pids, tt.wantPIDs) {
+				t.Fatalf("rankProcesses() PIDs = %v, want %v", pids, tt.wantPIDs)
+			}
+			if len(got) > 0 && got[0].Val != tt.wantVal {
+				t.Errorf("rankProcesses()[0].Val = %q, want %q", got[0].Val, tt.wantVal)
+			}
+		})
+	}
+
+	// The input order is left untouched so the same samples can be ranked twice
+	if procs[0].PID != 1 || procs[4].PID != 200 {
+		t.Errorf("rankProcesses() reordered its input")
+	}
+}
+
+func TestProcessOptionsWithDefaults(t *testing.T) {
+	tests := []struct {
+		opts    ProcessOptions
+		want    ProcessOptions
+		wantErr bool
+	}{
+		{ProcessOptions{}, ProcessOptions{TopN: DefaultTopN}, false},
+		{ProcessOptions{TopN: 500, SortBy: "RSS"}, ProcessOptions{TopN: MaxTopN, SortBy: "rss"}, false},
+		{ProcessOptions{SortBy: "pid"}, ProcessOptions{}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.opts.withDefaults()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("withDefaults(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("withDefaults(%+v) = %+v, want %+v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestIsZombie(t *testing.T) {
+	tests := []struct {
+		status []string
+		want   bool
+	}{
+		{[]string{"zombie"}, true},
+		{[]string{"sleep"}, false},
+		{[]string{"running", "zombie"}, true},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isZombie(tt.status); got != tt.want {
+			t.Errorf("isZombie(%v) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestKernelThread(t *testing.T) {
+	tests := []struct {
+		goos      string
+		pid, ppid int32
+		name      string
+		want      bool
+	}{
+		{"linux", 2, 0, "kthreadd", true},
+		{"linux", 812, 2, "kworker/0:1", true},
+		{"linux", 900, 1, "sshd", false},
+		{"linux", 913, 0, "[kworker/u8:2]", true},
+		{"darwin", 2, 1, "launchd_helper", false},
+		{"darwin", 540, 2, "mds", false},
+		{"freebsd", 12, 0, "[intr]", true},
+		{"windows", 4, 0, "System", false},
+		{"linux", 1000, 1, "[]", false},
+	}
+	for _, tt := range tests {
+		if got := kernelThread(tt.goos, tt.pid, tt.ppid, tt.name); got != tt.want {
+			t.Errorf("kernelThread(%s, %d, %d, %q) = %v, want %v", tt.goos, tt.pid, tt.ppid, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectPIDs(t *testing.T) {
+	const self = 50
+	tests := []struct {
+		name                  string
+		matches, requested    []int32
+		wantTargets, wantSkip []int32
+	}{
+		{"all matches", []int32{10, 20, self}, nil, []int32{10, 20}, nil},
+		{"reviewed subset", []int32{10, 20, 30}, []int32{20}, []int32{20}, nil},
+		{"no longer matching", []int32{10, 30}, []int32{10, 20}, []int32{10}, []int32{20}},
+		{"self requested", []int32{10, self}, []int32{self, 10, 10}, []int32{10}, []int32{self}},
+	}
+	for _, tt := range tests {
+		targets, skipped := selectPIDs(tt.matches, tt.requested, self)
+		if !reflect.DeepEqual(targets, tt.wantTargets) || !reflect.DeepEqual(skipped, tt.wantSkip) {
+			t.Errorf("%s: selectPIDs() = %v, %v, want %v, %v", tt.name, targets, skipped, tt.wantTargets, tt.wantSkip)
+		}
+	}
+}
+
+func TestNetworkStats(t *testing.T) {
+	start := []net.IOCountersStat{
+		{Name: "eth0", BytesRecv: 1000, BytesSent: 500, Errin: 1, Dropin: 2},
+		{Name: "gone0", BytesRecv: 10},
+	}
+	end := []net.IOCountersStat{
+		{Name: "lo", BytesRecv: 5},
+		{Name: "eth0", BytesRecv: 1000 + 2*2048, BytesSent: 500 + 2*100, Errin: 1, Errout: 3, Dropin: 7, Dropout: 4},
+	}
+
+	got := networkStats(start, end, 2)
+	want := []NetworkStats{
+		{
+			Interface:    "eth0",
+			Rx:           "2.0 KB/s",
+			Tx:           "100.0 B/s",
+			ErrOut:       3,
+			DropIn:       5,
+			DropOut:      4,
+			RxBytesTotal: 5096,
+			TxBytesTotal: 700,
+			ErrInTotal:   1,
+			ErrOutTotal:  3,
+			DropInTotal:  7,
+			DropOutTotal: 4,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("networkStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNetworkStatsCounterReset(t *testing.T) {
+	start := []net.IOCountersStat{{Name: "eth0", BytesRecv: 1<<32 - 100, BytesSent: 5000, Dropin: 9}}
+	end := []net.IOCountersStat{{Name: "eth0", BytesRecv: 200, BytesSent: 6024, Dropin: 1}}
+
+	got := networkStats(start, end, 1)
+	if len(got) != 1 {
+		t.Fatalf("networkStats() = %+v, want one interface", got)
+	}
+	if got[0].Rx != "0.0 B/s" || got[0].DropIn != 0 {
+		t.Errorf("networkStats() after a counter reset = rx %s, dropin %d, want 0", got[0].Rx, got[0].DropIn)
+	}
+	if got[0].Tx != "1.0 KB/s" {
+		t.Errorf("networkStats() tx = %s, want 1.0 KB/s", got[0].Tx)
+	}
+}
+
+func TestSignalAndWait(t *testing.T) {
+	if _, err := SignalAndWait(context.Background(), 1<<30, syscall.SIGHUP, 2*MaxExitWait, false); err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Errorf("SignalAndWait() error = %v, want the timeout rejected", err)
+	}
+	if _, err := SignalAndWait(context.Background(), 1<<30, syscall.SIGHUP, time.Second, true); err == nil || !strings.Contains(err.Error(), "graceful") {
+		t.Errorf("SignalAndWait() error = %v, want graceful rejected for SIGHUP", err)
+	}
+
+	// A child that ignores SIGTERM: the wait must end with ctx instead of running to the timeout
+	cmd := startTrapped(t, "TERM")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, err := SignalAndWait(ctx, int32(cmd.Process.Pid), syscall.SIGTERM, 30*time.Second, true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SignalAndWait() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("SignalAndWait() returned after %s, want shortly after ctx ended", elapsed)
+	}
+	if res.Exited || res.Escalated {
+		t.Errorf("SignalAndWait() = %+v, want the process left running and not escalated", res)
+	}
+}
+
+func TestSignalAndWaitHUP(t *testing.T) {
+	// SIGHUP asks for a reload: no wait for an exit and never an escalation to SIGKILL
+	cmd := startTrapped(t, "HUP")
+	start := time.Now()
+	res, err := SignalAndWait(context.Background(), int32(cmd.Process.Pid), syscall.SIGHUP, 2*time.Second, false)
+	if err != nil {
+		t.Fatalf("SignalAndWait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SignalAndWait() returned after %s, want right after delivery", elapsed)
+	}
+	if res.Escalated {
+		t.Errorf("SignalAndWait() = %+v, want SIGHUP not escalated", res)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if processGone(int32(cmd.Process.Pid)) {
+		t.Error("process exited after SIGHUP, want it left running")
+	}
+}
+
+// startTrapped starts a shell that ignores sig and kills it when the test ends
+func startTrapped(t *testing.T, sig string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", `trap "" `+sig+`; sleep 30`)
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sh: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	time.Sleep(200 * time.Millisecond) // Let the shell install the trap
+	return cmd
+}
@@ -0,0 +1,41 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+// TemperatureReading is a single temperature sensor value in degrees Celsius
+type TemperatureReading struct {
+	Sensor   string  `json:"sensor"`
+	Current  float64 `json:"current"`
+	High     float64 `json:"high,omitempty"`
+	Critical float64 `json:"critical,omitempty"`
+}
+
+// GetTemperatures returns the readings of all temperature sensors
+// An empty slice (not an error) is returned on machines without sensors, such as most VMs
+func GetTemperatures(ctx context.Context) ([]TemperatureReading, error) {
+	temps, err := sensors.TemperaturesWithContext(ctx)
+	if err != nil && len(temps) == 0 {
+		// gopsutil reports unreadable sensor files as warnings alongside partial results;
+		// with no results at all there is nothing to report
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to read temperature sensors: %w", ctx.Err())
+		}
+		return []TemperatureReading{}, nil
+	}
+
+	readings := make([]TemperatureReading, 0, len(temps))
+	for _, t := range temps {
+		readings = append(readings, TemperatureReading{
+			Sensor:   t.SensorKey,
+			Current:  t.Temperature,
+			High:     t.High,
+			Critical: t.Critical,
+		})
+	}
+	return readings, nil
+}
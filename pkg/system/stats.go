@@ -4,22 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
 	"github.com/shirou/gopsutil/v4/mem"
 )
 
 type SystemStats struct {
-	CPU             CPUStats       `json:"cpu"`
-	Memory          MemoryStats    `json:"memory"`
-	Disk            DiskStats      `json:"disk"`
+	Host            *HostStats     `json:"host,omitempty"`
+	CPU             *CPUStats      `json:"cpu,omitempty"`
+	Memory          *MemoryStats   `json:"memory,omitempty"`
+	Disk            *DiskStats     `json:"disk,omitempty"`
 	TopCPUProcesses []ProcessInfo  `json:"top_cpu_processes,omitempty"`
 	TopMemProcesses []ProcessInfo  `json:"top_mem_processes,omitempty"`
+	ProcessSort     string         `json:"process_sort,omitempty"` // Key of Processes when sort_by is given
+	Processes       []ProcessInfo  `json:"processes,omitempty"`
+	ZombieProcesses []ProcessInfo  `json:"zombie_processes"` // Defunct processes with the parent that should reap them, always an array
 	Network         []NetworkStats `json:"network,omitempty"`
+	Warnings        []string       `json:"warnings"` // Sections that could not be collected and why
+}
+
+type HostStats struct {
+	UptimeSeconds uint64 `json:"uptime_seconds,omitempty"`
+	Uptime        string `json:"uptime,omitempty"`    // e.g. "3d 4h 12m"
+	BootTime      string `json:"boot_time,omitempty"` // RFC3339
 }
 
 type CPUStats struct {
@@ -40,20 +55,37 @@ type DiskStats struct {
 }
 
 // GetStats collects system statistics including CPU, Memory, Disk usage, top processes and network usage
-func GetStats(ctx context.Context) (string, error) {
+// procOpts controls the process rankings, the zero value lists the top 10 by CPU and by memory
+// diskPath is the mountpoint whose usage is reported, empty selects the root filesystem
+// netFilter selects the interfaces of the network section
+func GetStats(ctx context.Context, procOpts ProcessOptions, diskPath string, netFilter NetworkFilter) (string, error) {
+	if err := netFilter.validate(); err != nil {
+		return "", err
+	}
+	// Invalid arguments fail the call, only collection errors below become warnings
+	procOpts, err := procOpts.withDefaults()
+	if err != nil {
+		return "", err
+	}
+	if diskPath == "" {
+		diskPath = defaultDiskPath()
+	} else if err := checkMountpoint(ctx, diskPath); err != nil {
+		return "", err
+	}
+
 	// We need to collect stats that require a duration (CPU process, Network) in parallel
 	// to minimize total latency.
 	var wg sync.WaitGroup
 	wg.Add(3)
 
 	var (
-		cpuUsage       float64
-		cpuErr         error
-		topCPU, topMem []ProcessInfo
-		procErr        error
-		netStats       []NetworkStats
-		netErr         error
-		scanDuration   = 5 * time.Second
+		cpuUsage     float64
+		cpuErr       error
+		procStats    *ProcessStats
+		procErr      error
+		netStats     []NetworkStats
+		netErr       error
+		scanDuration = 5 * time.Second
 	)
 
 	// 1. Overall System CPU Usage (5s)
@@ -73,70 +105,75 @@ func GetStats(ctx context.Context) (string, error) {
 	// 2. Top Processes (5s)
 	go func() {
 		defer wg.Done()
-		topCPU, topMem, procErr = GetProcessStats(scanDuration)
+		procStats, procErr = GetProcessStats(ctx, scanDuration, procOpts)
 	}()
 
 	// 3. Network Usage (5s)
 	go func() {
 		defer wg.Done()
-		netStats, netErr = GetNetworkUsage(scanDuration)
+		netStats, netErr = GetNetworkUsage(ctx, scanDuration, netFilter)
 	}()
 
 	// Wait for all duration-based checks
 	wg.Wait()
 
-	// Check for errors in critical parts (logging them might be better than failing all?)
-	// For now, if comprehensive info fails, we return error.
-	if cpuErr != nil {
-		return "", fmt.Errorf("failed to get cpu stats: %w", cpuErr)
+	// A failing section is reported in Warnings and left out, so one subsystem that is unavailable
+	// (e.g. network counters in some containers) does not hide the others
+	stats := SystemStats{ZombieProcesses: []ProcessInfo{}, Warnings: []string{}}
+	sections, failed := 0, 0
+	warn := func(section string, err error) bool {
+		sections++
+		if err != nil {
+			failed++
+			stats.Warnings = append(stats.Warnings, fmt.Sprintf("%s: %v", section, err))
+			return false
+		}
+		return true
+	}
+
+	if warn("cpu", cpuErr) {
+		stats.CPU = &CPUStats{UsagePercent: cpuUsage}
 	}
-	if procErr != nil {
-		// Non-critical, just log or ignore?
-		// The prompt implies we *should* have it. Let's return error if it fails significantly.
-		return "", fmt.Errorf("failed to get process stats: %w", procErr)
+	if warn("processes", procErr) {
+		stats.TopCPUProcesses = procStats.TopCPU
+		stats.TopMemProcesses = procStats.TopMem
+		stats.Processes = procStats.Sorted
+		stats.ZombieProcesses = procStats.Zombies
+		if procStats.Sorted != nil {
+			stats.ProcessSort = procOpts.SortBy
+		}
 	}
-	if netErr != nil {
-		return "", fmt.Errorf("failed to get network stats: %w", netErr)
+	if warn("network", netErr) {
+		stats.Network = netStats
 	}
 
 	// Instantaneous Checks (Memory, Disk)
-
-	// Virtual Memory
 	vMem, err := mem.VirtualMemoryWithContext(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get memory stats: %w", err)
+	if warn("memory", err) {
+		stats.Memory = &MemoryStats{
+			Total:       vMem.Total,
+			Available:   vMem.Available,
+			UsedPercent: vMem.UsedPercent,
+		}
 	}
-
-	// Disk Usage
-	// Windows uses "C:\", Unix/Linux/macOS uses "/"
-	diskPath := "/"
-	if runtime.GOOS == "windows" {
-		diskPath = "C:\\"
+	diskStats, err := getDiskStats(ctx, diskPath)
+	if warn("disk", err) {
+		stats.Disk = &diskStats
 	}
 
-	dUsage, err := disk.UsageWithContext(ctx, diskPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to get disk usage: %w", err)
+	if failed == sections {
+		return "", fmt.Errorf("failed to collect any system stats: %s", strings.Join(stats.Warnings, "; "))
 	}
 
-	stats := SystemStats{
-		CPU: CPUStats{
-			UsagePercent: cpuUsage,
-		},
-		Memory: MemoryStats{
-			Total:       vMem.Total,
-			Available:   vMem.Available,
-			UsedPercent: vMem.UsedPercent,
-		},
-		Disk: DiskStats{
-			Path:        "/",
-			Total:       dUsage.Total,
-			Free:        dUsage.Free,
-			UsedPercent: dUsage.UsedPercent,
-		},
-		TopCPUProcesses: topCPU,
-		TopMemProcesses: topMem,
-		Network:         netStats,
+	// Host uptime, non-critical so errors just leave the section out
+	if uptime, err := host.UptimeWithContext(ctx); err == nil {
+		stats.Host = &HostStats{
+			UptimeSeconds: uptime,
+			Uptime:        formatUptime(uptime),
+		}
+		if boot, err := host.BootTimeWithContext(ctx); err == nil {
+			stats.Host.BootTime = time.Unix(int64(boot), 0).Format(time.RFC3339)
+		}
 	}
 
 	jsonData, err := json.MarshalIndent(stats, "", "  ")
@@ -146,3 +183,63 @@ func GetStats(ctx context.Context) (string, error) {
 
 	return string(jsonData), nil
 }
+
+// formatUptime renders seconds as e.g. "3d 4h 12m"
+func formatUptime(seconds uint64) string {
+	days := seconds / 86400
+	hours := (seconds % 86400) / 3600
+	minutes := (seconds % 3600) / 60
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// getDiskStats reports usage of the filesystem at path, labelled with the path actually queried
+func getDiskStats(ctx context.Context, path string) (DiskStats, error) {
+	usage, err := disk.UsageWithContext(ctx, path)
+	if err != nil {
+		return DiskStats{}, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+	return DiskStats{
+		Path:        path,
+		Total:       usage.Total,
+		Free:        usage.Free,
+		UsedPercent: usage.UsedPercent,
+	}, nil
+}
+
+// defaultDiskPath is the root filesystem, "C:\" on Windows and "/" elsewhere
+func defaultDiskPath() string {
+	if runtime.GOOS == "windows" {
+		return "C:\\"
+	}
+	return "/"
+}
+
+// checkMountpoint verifies that path exists and is a mountpoint, so usage is not silently
+// reported for whichever filesystem happens to contain it
+func checkMountpoint(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("invalid disk_path '%s': %w", path, err)
+	}
+	partitions, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to list mountpoints: %w", err)
+	}
+	want := trimMountpoint(path)
+	for _, p := range partitions {
+		if trimMountpoint(p.Mountpoint) == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid disk_path '%s': not a mountpoint", path)
+}
+
+// trimMountpoint normalizes a mountpoint for comparison, e.g. "/var/" and "/var", or "C:\\" and "C:"
+func trimMountpoint(path string) string {
+	return strings.TrimRight(filepath.Clean(path), `/\`)
+}
@@ -0,0 +1,34 @@
+package system
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetDiskStatsPath(t *testing.T) {
+	for _, path := range []string{defaultDiskPath(), t.TempDir()} {
+		got, err := getDiskStats(context.Background(), path)
+		if err != nil {
+			t.Fatalf("getDiskStats(%q) error = %v", path, err)
+		}
+		if got.Path != path {
+			t.Errorf("getDiskStats(%q).Path = %q, want the queried path", path, got.Path)
+		}
+		if got.Total == 0 {
+			t.Errorf("getDiskStats(%q).Total = 0, want the filesystem size", path)
+		}
+	}
+}
+
+func TestGetStatsInvalidSort(t *testing.T) {
+	start := time.Now()
+	_, err := GetStats(context.Background(), ProcessOptions{SortBy: "pid"}, "", NetworkFilter{})
+	if err == nil || !strings.Contains(err.Error(), "invalid sort_by") {
+		t.Fatalf("GetStats() error = %v, want invalid sort_by", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetStats() returned after %s, want the arguments rejected before the scan", elapsed)
+	}
+}
@@ -0,0 +1,36 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// Session is an active login session read from utmp
+type Session struct {
+	Username   string `json:"username"`
+	TTY        string `json:"tty"`
+	RemoteHost string `json:"remote_host,omitempty"` // Empty for local console sessions
+	LoginTime  string `json:"login_time"`            // RFC3339
+}
+
+// WhoIsLoggedIn returns the currently logged-in users, equivalent to who
+func WhoIsLoggedIn(ctx context.Context) ([]Session, error) {
+	users, err := host.UsersWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read utmp: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(users))
+	for _, u := range users {
+		sessions = append(sessions, Session{
+			Username:   u.User,
+			TTY:        u.Terminal,
+			RemoteHost: u.Host,
+			LoginTime:  time.Unix(int64(u.Started), 0).Format(time.RFC3339),
+		})
+	}
+	return sessions, nil
+}
@@ -1,39 +1,77 @@
 package systemd
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"regexp"
+	"strings"
 )
 
-// ControlService manages systemd services using systemctl
-// Supported actions: start, stop, restart, reload, enable, disable, status
-func ControlService(unit string, action string) (string, error) {
+// unitNameRegex is the whitelist of characters accepted in a unit name
+var unitNameRegex = regexp.MustCompile(`^[A-Za-z0-9@._\-]+(\.(service|socket|timer|target|mount|path))?$`)
+
+// validateUnitName rejects unit names that could be interpreted as flags or contain unexpected characters
+func validateUnitName(unit string) error {
 	if unit == "" {
-		return "", fmt.Errorf("unit name cannot be empty")
+		return fmt.Errorf("unit name cannot be empty")
+	}
+	if strings.HasPrefix(unit, "-") {
+		return fmt.Errorf("invalid unit name '%s': must not start with '-'", unit)
+	}
+	if len(unit) > 256 || !unitNameRegex.MatchString(unit) {
+		return fmt.Errorf("invalid unit name '%s'", unit)
 	}
+	return nil
+}
 
+// ControlService manages systemd services using systemctl
+// Supported actions: start, stop, restart, reload, enable, disable, status, mask, unmask, reset-failed, daemon-reload
+// daemon-reload does not take a unit.
+func ControlService(ctx context.Context, unit string, action string) (string, error) {
 	allowedActions := map[string]bool{
-		"start":   true,
-		"stop":    true,
-		"restart": true,
-		"reload":  true,
-		"enable":  true,
-		"disable": true,
-		"status":  true,
+		"start":         true,
+		"stop":          true,
+		"restart":       true,
+		"reload":        true,
+		"enable":        true,
+		"disable":       true,
+		"status":        true,
+		"mask":          true,
+		"unmask":        true,
+		"reset-failed":  true,
+		"daemon-reload": true,
 	}
 
 	if !allowedActions[action] {
-		return "", fmt.Errorf("invalid action '%s'. Allowed actions: start, stop, restart, reload, enable, disable, status", action)
+		return "", fmt.Errorf("invalid action '%s'. Allowed actions: start, stop, restart, reload, enable, disable, status, mask, unmask, reset-failed, daemon-reload", action)
+	}
+
+	// daemon-reload applies to the whole manager and takes no unit
+	if action == "daemon-reload" {
+		output, err := exec.CommandContext(ctx, "systemctl", "daemon-reload").CombinedOutput()
+		if err != nil {
+			return string(output), fmt.Errorf("failed to execute systemctl daemon-reload: %w, output: %s", err, string(output))
+		}
+		return string(output), nil
+	}
+
+	if err := validateUnitName(unit); err != nil {
+		return "", err
 	}
 
 	// systemctl <action> <unit>
-	cmd := exec.Command("systemctl", action, unit)
+	cmd := exec.CommandContext(ctx, "systemctl", action, unit)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// specific handling: 'status' returns non-zero exit code if service is stopped/failed, but we still want the output
 		if action == "status" {
 			return string(output), nil
 		}
+		// reset-failed on a unit that is not loaded (e.g. already garbage-collected) leaves nothing to reset
+		if action == "reset-failed" && strings.Contains(string(output), "not loaded") {
+			return string(output), nil
+		}
 		return string(output), fmt.Errorf("failed to execute systemctl %s %s: %w, output: %s", action, unit, err, string(output))
 	}
 
@@ -0,0 +1,34 @@
+package systemd
+
+import (
+	"testing"
+)
+
+func TestValidateUnitName(t *testing.T) {
+	tests := []struct {
+		unit    string
+		wantErr bool
+	}{
+		{unit: "nginx"},
+		{unit: "nginx.service"},
+		{unit: "getty@tty1.service"},
+		{unit: "backup.timer"},
+		{unit: "systemd-journald.socket"},
+		{unit: "", wantErr: true},
+		{unit: "--user", wantErr: true},
+		{unit: "-H", wantErr: true},
+		{unit: "-H remotehost", wantErr: true},
+		{unit: "nginx; reboot", wantErr: true},
+		{unit: "nginx service", wantErr: true},
+		{unit: "../../etc/passwd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			err := validateUnitName(tt.unit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateUnitName(%q) error = %v, wantErr %v", tt.unit, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,52 +3,301 @@ package systemd
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// JournalOptions narrows the entries returned by GetJournalLogs
+type JournalOptions struct {
+	Lines    int    // Number of lines to retrieve (default 100 if <= 0)
+	Since    string // RFC3339 or systemd time string, e.g. "yesterday", "2024-01-02 14:00", "-1h"
+	Until    string // RFC3339 or systemd time string
+	Priority string // 0-7 or a syslog level name (emerg ... debug)
+	Match    string // Case-insensitive pattern passed to journalctl -g
+	MaxBytes int    // Keep only the newest lines fitting this many bytes of log text (0 = no limit), applied after Lines
+}
+
+// JournalEntry is one entry of journalctl -o json output
+type JournalEntry struct {
+	Timestamp string `json:"timestamp"` // RFC3339 with microseconds
+	Priority  int    `json:"priority"`  // Syslog priority, 0 (emerg) to 7 (debug)
+	Unit      string `json:"unit,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	Message   string `json:"message"`
+}
+
+// maxJournalLine bounds a single journal line read from journalctl, longer lines fail the read
+const maxJournalLine = 1024 * 1024
+
+// ErrUnitNotFound is returned by GetJournalLogs when the unit has no entries because systemd does not know it
+var ErrUnitNotFound = errors.New("unit not found")
+
+// timeSpecRegex matches the character set of systemd time specifications
+var timeSpecRegex = regexp.MustCompile(`^[A-Za-z0-9 :.+\-]+$`)
+
+// journalPriorities are the priority values accepted by journalctl -p
+var journalPriorities = map[string]bool{
+	"0": true, "1": true, "2": true, "3": true, "4": true, "5": true, "6": true, "7": true,
+	"emerg": true, "alert": true, "crit": true, "err": true,
+	"warning": true, "notice": true, "info": true, "debug": true,
+}
+
 // GetJournalLogs retrieves the logs for a specific unit
-// lines: number of lines to retrieve (default 100 if <= 0)
-func GetJournalLogs(unit string, lines int) ([]string, error) {
-	if unit == "" {
-		return nil, fmt.Errorf("unit name cannot be empty")
+func GetJournalLogs(ctx context.Context, unit string, opts JournalOptions) ([]string, error) {
+	args, err := journalArgs(unit, opts)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := runJournal(ctx, unit, args)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := readJournalLines(stdout, opts.MaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error reading journal output: %w", err)
+	}
+
+	// journalctl exits 0 without entries for an unknown unit too, so ask systemd whether the unit exists
+	if noEntries(logs) {
+		return emptyJournalResult(unit, unitLoadState(ctx, unit))
 	}
 
+	return logs, nil
+}
+
+// GetJournalEntries retrieves the logs for a specific unit as structured entries using journalctl -o json.
+// It also returns how many older entries were omitted because of opts.MaxBytes, which counts the message text.
+func GetJournalEntries(ctx context.Context, unit string, opts JournalOptions) ([]JournalEntry, int, error) {
+	args, err := journalArgs(unit, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	stdout, err := runJournal(ctx, unit, append(args, "-o", "json"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, omitted, err := parseJournalJSON(stdout, opts.MaxBytes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading journal output: %w", err)
+	}
+
+	if len(entries) == 0 && omitted == 0 {
+		if _, err := emptyJournalResult(unit, unitLoadState(ctx, unit)); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return entries, omitted, nil
+}
+
+// journalArgs validates opts and builds the journalctl arguments
+func journalArgs(unit string, opts JournalOptions) ([]string, error) {
+	if err := validateUnitName(unit); err != nil {
+		return nil, err
+	}
+
+	lines := opts.Lines
 	if lines <= 0 {
 		lines = 100
 	}
 
-	// journalctl -u <unit> -n <lines> --no-pager
-	cmd := exec.Command("journalctl", "-u", unit, "-n", fmt.Sprintf("%d", lines), "--no-pager")
+	// journalctl -u <unit> -n <lines> --no-pager [-S since] [-U until] [-p priority] [-g match]
+	args := []string{"-u", unit, "-n", fmt.Sprintf("%d", lines), "--no-pager"}
+
+	if opts.Since != "" {
+		since, err := normalizeTimeSpec(opts.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+		args = append(args, "-S", since)
+	}
+	if opts.Until != "" {
+		until, err := normalizeTimeSpec(opts.Until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+		args = append(args, "-U", until)
+	}
+	if opts.Priority != "" {
+		if !journalPriorities[strings.ToLower(opts.Priority)] {
+			return nil, fmt.Errorf("invalid priority '%s'. Allowed priorities: 0-7 or emerg, alert, crit, err, warning, notice, info, debug", opts.Priority)
+		}
+		args = append(args, "-p", strings.ToLower(opts.Priority))
+	}
+	if opts.Match != "" {
+		// The pattern is passed as its own argv element, never through a shell
+		if strings.ContainsRune(opts.Match, 0) {
+			return nil, fmt.Errorf("invalid match: pattern contains a null byte")
+		}
+		args = append(args, "-g", opts.Match, "--case-sensitive=false")
+	}
+	return args, nil
+}
+
+// runJournal runs journalctl and returns its standard output
+func runJournal(ctx context.Context, unit string, args []string) (*bytes.Buffer, error) {
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
+		if msg := strings.ToLower(stderr.String()); strings.Contains(msg, "no such unit") || strings.Contains(msg, "not found") {
+			return nil, fmt.Errorf("%w: '%s': %s", ErrUnitNotFound, unit, strings.TrimSpace(stderr.String()))
+		}
 		return nil, fmt.Errorf("failed to run journalctl: %w, stderr: %s", err, stderr.String())
 	}
+	return &stdout, nil
+}
 
+// readJournalLines splits journalctl output into lines. With maxBytes (> 0) only the newest lines that
+// fit are kept, the older ones are dropped and a notice with their count is put before them.
+func readJournalLines(r io.Reader, maxBytes int) ([]string, error) {
 	var logs []string
-	scanner := bufio.NewScanner(&stdout)
+	size, dropped := 0, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJournalLine)
 	for scanner.Scan() {
-		logs = append(logs, scanner.Text())
+		line := scanner.Text()
+		size += len(line) + 1
+		logs = append(logs, line)
+		// journalctl prints oldest first, so the oldest lines give way to newer ones
+		for maxBytes > 0 && size > maxBytes && len(logs) > 0 {
+			size -= len(logs[0]) + 1
+			logs = logs[1:]
+			dropped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if dropped > 0 {
+		notice := fmt.Sprintf("...[truncated: max_bytes %d reached, %d earlier lines omitted]", maxBytes, dropped)
+		logs = append([]string{notice}, logs...)
 	}
+	return logs, nil
+}
 
+// journalRecord holds the journal fields JournalEntry is built from, journalctl -o json prints every value as a string
+type journalRecord struct {
+	RealtimeTimestamp string          `json:"__REALTIME_TIMESTAMP"`
+	Priority          string          `json:"PRIORITY"`
+	SystemdUnit       string          `json:"_SYSTEMD_UNIT"`
+	Unit              string          `json:"UNIT"` // Set on systemd's own messages about a unit, e.g. "Started nginx.service"
+	PID               string          `json:"_PID"`
+	Message           json.RawMessage `json:"MESSAGE"`
+}
+
+// parseJournalJSON parses journalctl -o json output, one JSON object per line.
+// With maxBytes (> 0) only the newest entries whose message text fits are kept, older ones are counted as omitted.
+func parseJournalJSON(r io.Reader, maxBytes int) ([]JournalEntry, int, error) {
+	entries := []JournalEntry{}
+	size, omitted := 0, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJournalLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, 0, fmt.Errorf("malformed journal entry: %w", err)
+		}
+		entry := JournalEntry{Unit: rec.Unit, Message: journalMessage(rec.Message)}
+		if entry.Unit == "" {
+			entry.Unit = rec.SystemdUnit
+		}
+		if usec, err := strconv.ParseInt(rec.RealtimeTimestamp, 10, 64); err == nil {
+			entry.Timestamp = time.UnixMicro(usec).Format("2006-01-02T15:04:05.000000Z07:00")
+		}
+		entry.Priority, _ = strconv.Atoi(rec.Priority)
+		entry.PID, _ = strconv.Atoi(rec.PID)
+
+		size += len(entry.Message)
+		entries = append(entries, entry)
+		for maxBytes > 0 && size > maxBytes && len(entries) > 0 {
+			size -= len(entries[0].Message)
+			entries = entries[1:]
+			omitted++
+		}
+	}
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading journal output: %w", err)
+		return nil, 0, err
 	}
+	return entries, omitted, nil
+}
 
-	// journalctl can return extensive info, just returning lines is compliant with "retrieving the last 1000 entries"
-	if len(logs) == 0 {
-		return []string{fmt.Sprintf("No logs found for unit '%s'. Please check if the unit name is correct or if it has any logs.", unit)}, nil
+// journalMessage decodes a MESSAGE field, which journalctl prints as an array of bytes when it is not valid UTF-8
+func journalMessage(raw json.RawMessage) string {
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err == nil {
+		return msg
 	}
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err == nil {
+		b := make([]byte, len(ints))
+		for i, v := range ints {
+			b[i] = byte(v)
+		}
+		return strings.ToValidUTF8(string(b), "\ufffd")
+	}
+	return ""
+}
 
-	// Handle case where journalctl prints "-- No entries --" or similar
-	if len(logs) == 1 && strings.Contains(logs[0], "-- No entries --") {
-		return logs, nil
+// noEntries reports whether journalctl printed no entries, only notices such as "-- No entries --"
+func noEntries(logs []string) bool {
+	for _, line := range logs {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "-- ") && line != "No journal files were found." {
+			return false
+		}
 	}
+	return true
+}
 
-	return logs, nil
+// unitLoadState returns the LoadState of a unit, empty when systemctl cannot tell
+func unitLoadState(ctx context.Context, unit string) string {
+	output, err := exec.CommandContext(ctx, "systemctl", "show", unit, "--property=LoadState", "--value", "--no-pager").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// emptyJournalResult explains a journal query without entries based on the unit's LoadState.
+// An unknown unit is an error so a mistyped name is not mistaken for a quiet service.
+func emptyJournalResult(unit, loadState string) ([]string, error) {
+	switch loadState {
+	case "not-found":
+		return nil, fmt.Errorf("%w: '%s' is not known to systemd, check the unit name (systemd_list_units lists the loaded units)", ErrUnitNotFound, unit)
+	case "":
+		return []string{fmt.Sprintf("No logs found for unit '%s'. Please check if the unit name is correct or if it has any logs.", unit)}, nil
+	default:
+		return []string{fmt.Sprintf("No logs found for unit '%s'. The unit exists (load state: %s) but has no journal entries matching the query.", unit, loadState)}, nil
+	}
+}
+
+// normalizeTimeSpec validates a time string before it is passed to journalctl.
+// RFC3339 timestamps are converted to the "YYYY-MM-DD hh:mm:ss" local form journalctl understands,
+// anything else must look like a systemd time specification.
+func normalizeTimeSpec(spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+	if t, err := time.Parse(time.RFC3339, spec); err == nil {
+		return t.Local().Format("2006-01-02 15:04:05"), nil
+	}
+	if len(spec) > 64 || !timeSpecRegex.MatchString(spec) {
+		return "", fmt.Errorf("malformed time '%s'", spec)
+	}
+	return spec, nil
 }
@@ -0,0 +1,135 @@
+package systemd
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTimeSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "yesterday"},
+		{spec: "2024-01-02 14:00"},
+		{spec: "2024-01-02 14:30:00"},
+		{spec: "-1h"},
+		{spec: "2024-01-02T14:00:00Z"},
+		{spec: "", wantErr: true},
+		{spec: "today; rm -rf /", wantErr: true},
+		{spec: "$(id)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := normalizeTimeSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalizeTimeSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmptyJournalResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		logs      []string
+		loadState string
+		empty     bool
+		wantErr   bool
+	}{
+		{name: "no output", logs: nil, loadState: "loaded", empty: true},
+		{name: "no entries notice", logs: []string{"-- No entries --"}, loadState: "not-found", empty: true, wantErr: true},
+		{name: "no journal files", logs: []string{"No journal files were found.", "-- No entries --"}, loadState: "", empty: true},
+		{name: "masked unit", logs: []string{"-- No entries --"}, loadState: "masked", empty: true},
+		{name: "entries", logs: []string{"-- Boot 1a2b --", "Jan 02 14:00:00 host sshd[1]: Server listening"}, empty: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := noEntries(tt.logs); got != tt.empty {
+				t.Fatalf("noEntries() = %v, want %v", got, tt.empty)
+			}
+			if !tt.empty {
+				return
+			}
+			logs, err := emptyJournalResult("nginx", tt.loadState)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("emptyJournalResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrUnitNotFound) {
+				t.Errorf("error %v is not ErrUnitNotFound", err)
+			}
+			if !tt.wantErr && len(logs) != 1 {
+				t.Errorf("emptyJournalResult() = %v, want one notice", logs)
+			}
+		})
+	}
+}
+
+func TestReadJournalLines(t *testing.T) {
+	output := "first line\nsecond line\nthird line\n"
+	tests := []struct {
+		name     string
+		maxBytes int
+		want     []string
+	}{
+		{name: "no limit", maxBytes: 0, want: []string{"first line", "second line", "third line"}},
+		{name: "limit above output", maxBytes: 1000, want: []string{"first line", "second line", "third line"}},
+		{name: "limit exactly the last two lines", maxBytes: 23, want: []string{"...[truncated: max_bytes 23 reached, 1 earlier lines omitted]", "second line", "third line"}},
+		{name: "limit below first line", maxBytes: 5, want: []string{"...[truncated: max_bytes 5 reached, 3 earlier lines omitted]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readJournalLines(strings.NewReader(output), tt.maxBytes)
+			if err != nil {
+				t.Fatalf("readJournalLines() error = %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("readJournalLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJournalJSON(t *testing.T) {
+	output := `{"__REALTIME_TIMESTAMP":"1704204000123456","PRIORITY":"6","_SYSTEMD_UNIT":"nginx.service","_PID":"812","MESSAGE":"GET / 200","_HOSTNAME":"web1"}
+{"__REALTIME_TIMESTAMP":"1704204001000000","PRIORITY":"3","_SYSTEMD_UNIT":"init.scope","UNIT":"nginx.service","_PID":"1","MESSAGE":"nginx.service: Main process exited, code=exited, status=1/FAILURE"}
+{"__REALTIME_TIMESTAMP":"1704204002000000","PRIORITY":"4","_SYSTEMD_UNIT":"nginx.service","_PID":"812","MESSAGE":[98,97,100,255,33]}
+{"__REALTIME_TIMESTAMP":"1704204003000000","PRIORITY":"6","_SYSTEMD_UNIT":"nginx.service","MESSAGE":null}
+`
+	ts := func(usec int64) string {
+		return time.UnixMicro(usec).Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+
+	entries, omitted, err := parseJournalJSON(strings.NewReader(output), 0)
+	if err != nil {
+		t.Fatalf("parseJournalJSON() error = %v", err)
+	}
+	want := []JournalEntry{
+		{Timestamp: ts(1704204000123456), Priority: 6, Unit: "nginx.service", PID: 812, Message: "GET / 200"},
+		{Timestamp: ts(1704204001000000), Priority: 3, Unit: "nginx.service", PID: 1, Message: "nginx.service: Main process exited, code=exited, status=1/FAILURE"},
+		{Timestamp: ts(1704204002000000), Priority: 4, Unit: "nginx.service", PID: 812, Message: "bad�!"},
+		{Timestamp: ts(1704204003000000), Priority: 6, Unit: "nginx.service"},
+	}
+	if !reflect.DeepEqual(entries, want) || omitted != 0 {
+		t.Errorf("parseJournalJSON() = %+v, %d\nwant %+v, 0", entries, omitted, want)
+	}
+
+	entries, omitted, err = parseJournalJSON(strings.NewReader(output), 20)
+	if err != nil {
+		t.Fatalf("parseJournalJSON() error = %v", err)
+	}
+	// The newest entries are kept: the 7 byte message and the empty one
+	if !reflect.DeepEqual(entries, want[2:]) || omitted != 2 {
+		t.Errorf("parseJournalJSON() with max_bytes = %+v, %d omitted, want %+v, 2", entries, omitted, want[2:])
+	}
+
+	if _, _, err := parseJournalJSON(strings.NewReader("{not json\n"), 0); err == nil {
+		t.Error("parseJournalJSON() accepted malformed output")
+	}
+}
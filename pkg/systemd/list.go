@@ -1,28 +1,114 @@
 package systemd
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"strings"
 )
 
-// ListUnits returns a list of loaded systemd units (services)
-// Wraps: systemctl list-units --type=service --all --no-pager
-func ListUnits() (string, error) {
-	cmd := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-pager")
+// FailedUnit is a single row of systemctl --failed output
+type FailedUnit struct {
+	Unit        string `json:"unit"`
+	Load        string `json:"load"`
+	Active      string `json:"active"`
+	Sub         string `json:"sub"`
+	Description string `json:"description"`
+}
+
+// unitTypes are the unit types accepted by ListUnits and ListUnitFiles
+var unitTypes = map[string]bool{
+	"service": true,
+	"timer":   true,
+	"socket":  true,
+	"target":  true,
+	"mount":   true,
+	"all":     true,
+}
+
+// typeArgs validates unitType and returns the matching systemctl filter arguments
+// An empty unitType defaults to service
+func typeArgs(unitType string) ([]string, error) {
+	if unitType == "" {
+		unitType = "service"
+	}
+	if !unitTypes[unitType] {
+		return nil, fmt.Errorf("invalid type '%s'. Allowed types: service, timer, socket, target, mount, all", unitType)
+	}
+	if unitType == "all" {
+		return nil, nil
+	}
+	return []string{"--type=" + unitType}, nil
+}
+
+// ListUnits returns a list of loaded systemd units of the given type (default service)
+// Wraps: systemctl list-units --type=<type> --all --no-pager
+// For timers, systemctl list-timers --all is used so the next/last trigger times are included
+func ListUnits(ctx context.Context, unitType string) (string, error) {
+	filter, err := typeArgs(unitType)
+	if err != nil {
+		return "", err
+	}
+
+	args := append([]string{"list-units"}, filter...)
+	if unitType == "timer" {
+		args = []string{"list-timers"}
+	}
+	args = append(args, "--all", "--no-pager")
+
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("failed to execute systemctl list-units: %w, output: %s", err, string(output))
+		return "", fmt.Errorf("failed to execute systemctl %s: %w, output: %s", args[0], err, string(output))
 	}
 	return string(output), nil
 }
 
-// ListUnitFiles returns a list of installed systemd unit files (services)
-// Wraps: systemctl list-unit-files --type=service --no-pager
-func ListUnitFiles() (string, error) {
-	cmd := exec.Command("systemctl", "list-unit-files", "--type=service", "--no-pager")
+// ListUnitFiles returns a list of installed systemd unit files of the given type (default service)
+// Wraps: systemctl list-unit-files --type=<type> --no-pager
+func ListUnitFiles(ctx context.Context, unitType string) (string, error) {
+	filter, err := typeArgs(unitType)
+	if err != nil {
+		return "", err
+	}
+
+	args := append([]string{"list-unit-files"}, filter...)
+	args = append(args, "--no-pager")
+
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to execute systemctl list-unit-files: %w, output: %s", err, string(output))
 	}
 	return string(output), nil
 }
+
+// ListFailedUnits returns the units currently in the failed state
+// Wraps: systemctl list-units --state=failed --no-pager --plain --no-legend
+func ListFailedUnits(ctx context.Context) ([]FailedUnit, error) {
+	cmd := exec.CommandContext(ctx, "systemctl", "list-units", "--state=failed", "--no-pager", "--plain", "--no-legend")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute systemctl list-units --state=failed: %w, output: %s", err, string(output))
+	}
+	return parseFailedUnits(string(output)), nil
+}
+
+// parseFailedUnits parses "UNIT LOAD ACTIVE SUB DESCRIPTION" rows
+func parseFailedUnits(output string) []FailedUnit {
+	units := make([]FailedUnit, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		units = append(units, FailedUnit{
+			Unit:        fields[0],
+			Load:        fields[1],
+			Active:      fields[2],
+			Sub:         fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return units
+}
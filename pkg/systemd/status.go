@@ -0,0 +1,83 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// UnitStatus holds the machine-readable state of a systemd unit
+type UnitStatus struct {
+	Unit        string `json:"unit"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	MainPID     int    `json:"main_pid"`
+	Memory      string `json:"memory,omitempty"` // Current memory usage in bytes, empty if not tracked
+	Since       string `json:"since,omitempty"`  // Time the unit entered its current active state
+	Result      string `json:"result,omitempty"`
+}
+
+// statusProperties are the properties requested from systemctl show
+var statusProperties = []string{
+	"LoadState",
+	"ActiveState",
+	"SubState",
+	"MainPID",
+	"MemoryCurrent",
+	"StateChangeTimestamp",
+	"Result",
+}
+
+// GetUnitStatus returns the parsed status of a unit
+// Wraps: systemctl show <unit> --property=...
+func GetUnitStatus(ctx context.Context, unit string) (*UnitStatus, error) {
+	if err := validateUnitName(unit); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "systemctl", "show", unit, "--property="+strings.Join(statusProperties, ","), "--no-pager")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute systemctl show %s: %w, output: %s", unit, err, string(output))
+	}
+
+	status := parseShowOutput(string(output))
+	status.Unit = unit
+	return status, nil
+}
+
+// parseShowOutput parses the KEY=VALUE lines printed by systemctl show
+func parseShowOutput(output string) *UnitStatus {
+	status := &UnitStatus{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "LoadState":
+			status.LoadState = value
+		case "ActiveState":
+			status.ActiveState = value
+		case "SubState":
+			status.SubState = value
+		case "MainPID":
+			status.MainPID, _ = strconv.Atoi(value)
+		case "MemoryCurrent":
+			// "[not set]" or the max uint64 value mean memory accounting is unavailable
+			if _, err := strconv.ParseUint(value, 10, 64); err == nil && value != "18446744073709551615" {
+				status.Memory = value
+			}
+		case "StateChangeTimestamp":
+			status.Since = value
+		case "Result":
+			status.Result = value
+		}
+	}
+	return status
+}
@@ -0,0 +1,80 @@
+package systemd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseShowOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected UnitStatus
+	}{
+		{
+			name: "Running service",
+			output: `LoadState=loaded
+ActiveState=active
+SubState=running
+MainPID=812
+MemoryCurrent=5427200
+StateChangeTimestamp=Mon 2026-10-12 09:14:02 UTC
+Result=success
+`,
+			expected: UnitStatus{
+				LoadState:   "loaded",
+				ActiveState: "active",
+				SubState:    "running",
+				MainPID:     812,
+				Memory:      "5427200",
+				Since:       "Mon 2026-10-12 09:14:02 UTC",
+				Result:      "success",
+			},
+		},
+		{
+			name: "Failed service without memory accounting",
+			output: `LoadState=loaded
+ActiveState=failed
+SubState=failed
+MainPID=0
+MemoryCurrent=[not set]
+StateChangeTimestamp=
+Result=exit-code
+`,
+			expected: UnitStatus{
+				LoadState:   "loaded",
+				ActiveState: "failed",
+				SubState:    "failed",
+				Result:      "exit-code",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseShowOutput(tt.output)
+			if *got != tt.expected {
+				t.Errorf("parseShowOutput() = %+v, want %+v", *got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseFailedUnits(t *testing.T) {
+	output := `nginx.service   loaded failed failed A high performance web server
+backup.service  loaded failed failed Nightly Backup
+`
+	expected := []FailedUnit{
+		{Unit: "nginx.service", Load: "loaded", Active: "failed", Sub: "failed", Description: "A high performance web server"},
+		{Unit: "backup.service", Load: "loaded", Active: "failed", Sub: "failed", Description: "Nightly Backup"},
+	}
+
+	got := parseFailedUnits(output)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseFailedUnits() = %+v, want %+v", got, expected)
+	}
+
+	if got := parseFailedUnits(""); len(got) != 0 {
+		t.Errorf("parseFailedUnits(\"\") = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,111 @@
+package target
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Target is the host named by user input such as "example.com", "example.com:443" or
+// "https://example.com/path", with the port and scheme when they were given
+type Target struct {
+	Host   string `json:"host"`
+	Port   int    `json:"port,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// Parse extracts the bare host from a hostname, IP, host:port or URL, so tools like ping and traceroute
+// can be given whatever form a user or model naturally writes. It does not check the host for unsafe
+// characters, callers still validate Host before passing it to a command.
+func Parse(raw string) (Target, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Target{}, fmt.Errorf("target cannot be empty")
+	}
+
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			return Target{}, fmt.Errorf("invalid target URL '%s'", raw)
+		}
+		t := Target{Host: u.Hostname(), Scheme: strings.ToLower(u.Scheme)}
+		if p := u.Port(); p != "" {
+			port, err := parsePort(p)
+			if err != nil {
+				return Target{}, err
+			}
+			t.Port = port
+		}
+		return t, nil
+	}
+
+	// Drop a path, e.g. "example.com/status"
+	if i := strings.Index(raw, "/"); i >= 0 {
+		raw = raw[:i]
+	}
+
+	// A bare IPv6 address has colons but no port. netip keeps the zone of scoped
+	// link-local addresses such as "fe80::1%eth0", which net.ParseIP rejects.
+	// ParseAddr takes everything after '%' as the zone, so "[fe80::1%eth0]:443" must not reach it
+	if addr, err := netip.ParseAddr(strings.Trim(raw, "[]")); err == nil && !strings.ContainsAny(addr.Zone(), "[]:") {
+		if addr.Zone() == "" {
+			addr = addr.Unmap()
+		}
+		return Target{Host: addr.String()}, nil
+	}
+
+	if strings.Contains(raw, ":") {
+		host, p, err := net.SplitHostPort(raw)
+		if err != nil || host == "" {
+			return Target{}, fmt.Errorf("invalid target '%s': use a host, host:port or URL", raw)
+		}
+		port, err := parsePort(p)
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Host: host, Port: port}, nil
+	}
+	return Target{Host: raw}, nil
+}
+
+// Host parses raw like Parse and validates the host, returning the bare host to pass to
+// ping, traceroute and similar commands
+func Host(raw string) (string, error) {
+	t, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := Validate(t.Host); err != nil {
+		return "", err
+	}
+	return t.Host, nil
+}
+
+// Validate rejects hosts that are empty, too long, could be parsed as flags or contain shell metacharacters
+func Validate(host string) error {
+	if host == "" {
+		return fmt.Errorf("target cannot be empty")
+	}
+	if strings.HasPrefix(host, "-") {
+		return fmt.Errorf("target must not start with '-'")
+	}
+	if strings.ContainsAny(host, ";&|`$<> \t\n") {
+		return fmt.Errorf("invalid characters in target")
+	}
+	if len(host) > 253 {
+		return fmt.Errorf("target too long")
+	}
+	return nil
+}
+
+// parsePort checks that p is a port number between 1 and 65535
+func parsePort(p string) (int, error) {
+	port, err := strconv.Atoi(p)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port '%s': must be between 1 and 65535", p)
+	}
+	return port, nil
+}
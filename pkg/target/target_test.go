@@ -0,0 +1,75 @@
+package target
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Target
+		wantErr bool
+	}{
+		{"hostname", "example.com", Target{Host: "example.com"}, false},
+		{"ipv4", "192.0.2.1", Target{Host: "192.0.2.1"}, false},
+		{"ipv6", "2001:db8::1", Target{Host: "2001:db8::1"}, false},
+		{"bracketed ipv6", "[2001:db8::1]", Target{Host: "2001:db8::1"}, false},
+		{"scoped ipv6", "fe80::1%eth0", Target{Host: "fe80::1%eth0"}, false},
+		{"scoped ipv6 and port", "[fe80::1%eth0]:443", Target{Host: "fe80::1%eth0", Port: 443}, false},
+		{"http url", "http://example.com", Target{Host: "example.com", Scheme: "http"}, false},
+		{"https url with path", "https://example.com/status?x=1", Target{Host: "example.com", Scheme: "https"}, false},
+		{"url with port and credentials", "HTTPS://user:pw@example.com:8443/", Target{Host: "example.com", Port: 8443, Scheme: "https"}, false},
+		{"ipv6 url", "http://[2001:db8::1]:8080/", Target{Host: "2001:db8::1", Port: 8080, Scheme: "http"}, false},
+		{"host and port", "example.com:443", Target{Host: "example.com", Port: 443}, false},
+		{"ipv6 and port", "[2001:db8::1]:53", Target{Host: "2001:db8::1", Port: 53}, false},
+		{"host and path", "example.com/health", Target{Host: "example.com"}, false},
+		{"surrounding space", "  example.com  ", Target{Host: "example.com"}, false},
+		{"empty", "", Target{}, true},
+		{"bad port", "example.com:http", Target{}, true},
+		{"port out of range", "example.com:70000", Target{}, true},
+		{"url without host", "https:///path", Target{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"example.com", "example.com", false},
+		{"http://example.com", "example.com", false},
+		{"https://example.com/path", "example.com", false},
+		{"example.com:443", "example.com", false},
+		{"fe80::1%eth0", "fe80::1%eth0", false},
+		{"[fe80::1%eth0]:443", "fe80::1%eth0", false},
+		{"[2001:db8::1]:443", "2001:db8::1", false},
+		{"https://-f/", "", true},
+		{"example.com;reboot", "", true},
+		{"-fexample.com", "", true},
+		{"example .com", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := Host(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Host(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package traceroute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/netdial"
+)
+
+// EnrichOptions selects which lookups Enrich performs
+type EnrichOptions struct {
+	ReverseDNS bool // PTR lookup per hop
+	ASN        bool // Origin ASN and AS name via Team Cymru DNS
+}
+
+const (
+	// enrichConcurrency bounds parallel lookups for one trace
+	enrichConcurrency = 8
+	// lookupTimeout bounds each PTR/TXT lookup so a slow resolver cannot stall the trace
+	lookupTimeout = 2 * time.Second
+	// enrichCacheTTL is how long lookups are reused across traces
+	enrichCacheTTL = time.Hour
+	// maxEnrichEntries caps the cached addresses, traces to arbitrary targets would grow it without bound
+	maxEnrichEntries = 1024
+)
+
+// hopInfo is the cached enrichment of one address
+type hopInfo struct {
+	hostname, asn, asName, prefix string
+	withASN                       bool // ASN lookup was attempted
+	expires                       time.Time
+}
+
+// enrichCache keeps lookups across calls since consecutive traces share most hops
+var enrichCache = struct {
+	entries map[string]hopInfo
+	sync.Mutex
+}{entries: make(map[string]hopInfo)}
+
+// resolver is used for all enrichment lookups
+var resolver = netdial.Resolver()
+
+// Enrich adds hostnames and ASN data to hops in place.
+// Each distinct address is looked up once, concurrently; failed lookups leave the fields empty.
+func Enrich(ctx context.Context, hops []Hop, opts EnrichOptions) {
+	if !opts.ReverseDNS && !opts.ASN {
+		return
+	}
+
+	addrs := make([]string, len(hops))
+	for i, hop := range hops {
+		addrs[i] = hop.Address
+	}
+	infos := netdial.LookupEach(addrs, enrichConcurrency, func(addr string) hopInfo {
+		return lookupHop(ctx, addr, opts)
+	})
+
+	for i := range hops {
+		info, ok := infos[hops[i].Address]
+		if !ok {
+			continue
+		}
+		if opts.ReverseDNS {
+			hops[i].Hostname = info.hostname
+		}
+		if opts.ASN {
+			hops[i].ASN, hops[i].ASName, hops[i].Prefix = info.asn, info.asName, info.prefix
+		}
+	}
+}
+
+// lookupHop returns the enrichment of addr, from the cache when fresh.
+// Only complete lookups are cached, a timed-out or failed one is retried by the next trace.
+func lookupHop(ctx context.Context, addr string, opts EnrichOptions) hopInfo {
+	enrichCache.Lock()
+	cached, ok := enrichCache.entries[addr]
+	enrichCache.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		// An entry cached without ASN data is only reused when ASN is not wanted
+		if !opts.ASN || cached.withASN {
+			return cached
+		}
+	}
+
+	var info hopInfo
+	ip := net.ParseIP(addr)
+
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	// The PTR lookup always runs so the cached entry also serves later ReverseDNS requests
+	names, err := resolver.LookupAddr(lookupCtx, addr)
+	if len(names) > 0 {
+		info.hostname = strings.TrimSuffix(names[0], ".")
+	}
+	complete := !lookupFailed(err)
+	info.withASN = opts.ASN
+	// Private, loopback and link-local ranges are not announced in BGP
+	if opts.ASN && ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate() {
+		info.asn, info.prefix, err = lookupOrigin(lookupCtx, ip)
+		complete = complete && !lookupFailed(err)
+		if info.asn != "" {
+			info.asName, err = lookupASName(lookupCtx, info.asn)
+			complete = complete && !lookupFailed(err)
+		}
+	}
+
+	if complete {
+		info.expires = time.Now().Add(enrichCacheTTL)
+		storeHopInfo(addr, info)
+	}
+	return info
+}
+
+// lookupFailed reports whether err leaves the answer unknown, a name without records is a valid answer
+func lookupFailed(err error) bool {
+	var dnsErr *net.DNSError
+	return err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound)
+}
+
+// storeHopInfo caches info, dropping expired entries or, if none expired, every entry when the cache is full
+func storeHopInfo(addr string, info hopInfo) {
+	enrichCache.Lock()
+	defer enrichCache.Unlock()
+	if len(enrichCache.entries) >= maxEnrichEntries {
+		now := time.Now()
+		for k, v := range enrichCache.entries {
+			if now.After(v.expires) {
+				delete(enrichCache.entries, k)
+			}
+		}
+		if len(enrichCache.entries) >= maxEnrichEntries {
+			enrichCache.entries = make(map[string]hopInfo)
+		}
+	}
+	enrichCache.entries[addr] = info
+}
+
+// lookupOrigin queries Team Cymru for the origin AS of ip, e.g. "15169 | 8.8.8.0/24 | US | arin | 2014-03-14"
+func lookupOrigin(ctx context.Context, ip net.IP) (asn, prefix string, err error) {
+	name, err := cymruOriginName(ip)
+	if err != nil {
+		return "", "", nil
+	}
+	txts, err := resolver.LookupTXT(ctx, name)
+	if err != nil || len(txts) == 0 {
+		return "", "", err
+	}
+	fields := splitCymru(txts[0])
+	if len(fields) < 2 || fields[0] == "" {
+		return "", "", nil
+	}
+	// Multi-origin prefixes list several ASNs separated by spaces, the first is reported
+	return "AS" + strings.Fields(fields[0])[0], fields[1], nil
+}
+
+// lookupASName queries Team Cymru for the AS name, e.g. "15169 | US | arin | 2000-03-30 | GOOGLE, US"
+func lookupASName(ctx context.Context, asn string) (string, error) {
+	txts, err := resolver.LookupTXT(ctx, asn+".asn.cymru.com")
+	if err != nil || len(txts) == 0 {
+		return "", err
+	}
+	fields := splitCymru(txts[0])
+	if len(fields) < 5 {
+		return "", nil
+	}
+	return fields[4], nil
+}
+
+// cymruOriginName builds the Team Cymru origin query name for ip,
+// reversed octets for IPv4 and reversed nibbles for IPv6
+func cymruOriginName(ip net.IP) (string, error) {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+	v6 := ip.To16()
+	if v6 == nil {
+		return "", fmt.Errorf("invalid IP %v", ip)
+	}
+	const hexDigits = "0123456789abcdef"
+	var b strings.Builder
+	for i := len(v6) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[v6[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[v6[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("origin6.asn.cymru.com")
+	return b.String(), nil
+}
+
+// splitCymru splits a Team Cymru TXT record on "|" and trims each field
+func splitCymru(txt string) []string {
+	fields := strings.Split(txt, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
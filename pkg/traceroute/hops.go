@@ -0,0 +1,60 @@
+package traceroute
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hop is a single line of traceroute output
+type Hop struct {
+	Number   int       `json:"hop"`
+	Address  string    `json:"address,omitempty"` // Empty when the hop did not answer
+	RTTMs    []float64 `json:"rtt_ms,omitempty"`  // 0 for tracert's sub-millisecond "<1 ms"
+	Hostname string    `json:"hostname,omitempty"`
+	ASN      string    `json:"asn,omitempty"`     // e.g. "AS15169"
+	ASName   string    `json:"as_name,omitempty"` // e.g. "GOOGLE, US"
+	Prefix   string    `json:"prefix,omitempty"`  // Announced prefix containing the address
+}
+
+// hopLineRegex matches the hop number at the start of traceroute/tracert lines
+var hopLineRegex = regexp.MustCompile(`^\s*(\d+)\s+(.*)$`)
+
+// rttRegex matches "0.512 ms", "<1 ms" and "12 ms"
+var rttRegex = regexp.MustCompile(`(<)?(\d+(?:\.\d+)?)\s*ms`)
+
+// ParseHops extracts hops from traceroute (Linux/macOS, -n) or tracert (Windows, -d) output
+func ParseHops(output string) []Hop {
+	var hops []Hop
+	for _, line := range strings.Split(output, "\n") {
+		m := hopLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+
+		hop := Hop{Number: n}
+		for _, rtt := range rttRegex.FindAllStringSubmatch(m[2], -1) {
+			if rtt[1] == "<" {
+				// tracert only knows the reply came within a millisecond
+				hop.RTTMs = append(hop.RTTMs, 0)
+			} else if v, err := strconv.ParseFloat(rtt[2], 64); err == nil {
+				hop.RTTMs = append(hop.RTTMs, v)
+			}
+		}
+		// The responding address is the first field that parses as an IP
+		for _, field := range strings.Fields(m[2]) {
+			field = strings.Trim(field, "()[]")
+			if ip := net.ParseIP(field); ip != nil {
+				hop.Address = ip.String()
+				break
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
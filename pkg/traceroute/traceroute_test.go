@@ -0,0 +1,133 @@
+package traceroute
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const linuxFixture = `traceroute to 8.8.8.8 (8.8.8.8), 20 hops max, 60 byte packets
+ 1  192.168.1.1  0.512 ms
+ 2  *
+ 3  100.64.0.1  8.231 ms
+ 4  8.8.8.8  12.004 ms
+`
+
+const windowsFixture = `
+Tracing route to 8.8.8.8 over a maximum of 20 hops
+
+  1    <1 ms    <1 ms    <1 ms  192.168.1.1
+  2     *        *        *     Request timed out.
+  3    12 ms    11 ms    12 ms  8.8.8.8
+
+Trace complete.
+`
+
+func TestParseHops(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Hop
+	}{
+		{
+			name:   "Linux",
+			output: linuxFixture,
+			want: []Hop{
+				{Number: 1, Address: "192.168.1.1", RTTMs: []float64{0.512}},
+				{Number: 2},
+				{Number: 3, Address: "100.64.0.1", RTTMs: []float64{8.231}},
+				{Number: 4, Address: "8.8.8.8", RTTMs: []float64{12.004}},
+			},
+		},
+		{
+			name:   "Windows",
+			output: windowsFixture,
+			want: []Hop{
+				{Number: 1, Address: "192.168.1.1", RTTMs: []float64{0, 0, 0}},
+				{Number: 2},
+				{Number: 3, Address: "8.8.8.8", RTTMs: []float64{12, 11, 12}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseHops(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHops() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCymruOriginName(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "8.8.4.4", want: "4.4.8.8.origin.asn.cymru.com"},
+		{ip: "2001:4860::8888", want: "8.8.8.8.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.6.8.4.1.0.0.2.origin6.asn.cymru.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := cymruOriginName(net.ParseIP(tt.ip))
+			if err != nil || got != tt.want {
+				t.Errorf("cymruOriginName() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "success", err: nil, want: false},
+		{name: "not found", err: &net.DNSError{Err: "no such host", IsNotFound: true}, want: false},
+		{name: "timeout", err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}, want: true},
+		{name: "cancelled", err: context.Canceled, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookupFailed(tt.err); got != tt.want {
+				t.Errorf("lookupFailed(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreHopInfoBounded(t *testing.T) {
+	enrichCache.Lock()
+	saved := enrichCache.entries
+	enrichCache.entries = make(map[string]hopInfo)
+	enrichCache.Unlock()
+	defer func() { enrichCache.entries = saved }()
+
+	fresh := time.Now().Add(time.Hour)
+	for i := 0; i < maxEnrichEntries+10; i++ {
+		storeHopInfo(fmt.Sprintf("10.0.%d.%d", i/256, i%256), hopInfo{expires: fresh})
+	}
+	if n := len(enrichCache.entries); n > maxEnrichEntries {
+		t.Errorf("cache holds %d entries, want at most %d", n, maxEnrichEntries)
+	}
+
+	// Expired entries are dropped first, fresh ones survive
+	enrichCache.entries = map[string]hopInfo{"192.0.2.1": {expires: fresh}}
+	for i := 1; i < maxEnrichEntries; i++ {
+		enrichCache.entries[fmt.Sprintf("10.1.%d.%d", i/256, i%256)] = hopInfo{expires: time.Now().Add(-time.Minute)}
+	}
+	storeHopInfo("192.0.2.2", hopInfo{expires: fresh})
+	if len(enrichCache.entries) != 2 {
+		t.Errorf("cache holds %d entries after pruning, want 2", len(enrichCache.entries))
+	}
+	if _, ok := enrichCache.entries["192.0.2.1"]; !ok {
+		t.Error("fresh entry was pruned")
+	}
+}
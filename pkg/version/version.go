@@ -0,0 +1,48 @@
+// Package version holds the build information of the server, set at build time with
+//
+//	go build -ldflags "-X github.com/ashton2914/mcp-netutil/pkg/version.Version=0.2.0 -X github.com/ashton2914/mcp-netutil/pkg/version.Commit=$(git rev-parse --short HEAD) -X github.com/ashton2914/mcp-netutil/pkg/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"runtime"
+	"time"
+)
+
+// Set with -ldflags -X, the defaults mark a development build
+var (
+	Version   = "0.2.0-dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// started is when the process started, used for the uptime
+var started = time.Now()
+
+// Info describes the running build
+type Info struct {
+	Version       string  `json:"version"`
+	Commit        string  `json:"commit"`
+	BuildDate     string  `json:"build_date"`
+	GoVersion     string  `json:"go_version"`
+	OS            string  `json:"os"`
+	Arch          string  `json:"arch"`
+	StartedAt     string  `json:"started_at"`
+	Uptime        string  `json:"uptime"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// Get returns the build information and the uptime of the process
+func Get() Info {
+	uptime := time.Since(started)
+	return Info{
+		Version:       Version,
+		Commit:        Commit,
+		BuildDate:     BuildDate,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		StartedAt:     started.UTC().Format(time.RFC3339),
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// toolPlatforms lists the operating systems a tool supports.
+// Tools missing from the map run on every platform.
+var toolPlatforms = map[string][]string{
+	// Linux ping flags (-M do, -i 0.2, -W seconds)
+	"latency_monitor": {"linux"},
+	"path_mtu":        {"linux"},
+	"net_health":      {"linux"},
+	// /proc/net and ip(8)
+	"routes":    {"linux"},
+	"neighbors": {"linux"},
+	// ss(8)
+	"port_status":    {"linux"},
+	"socket_summary": {"linux"},
+	"ss_query":       {"linux"},
+	"tcp_info":       {"linux"},
+	// /proc/<pid>/fd and /proc/sys/fs/file-nr
+	"fd_usage": {"linux"},
+	// systemd
+	"systemd_logs":            {"linux"},
+	"manage_service":          {"linux"},
+	"service_status":          {"linux"},
+	"systemd_list_units":      {"linux"},
+	"systemd_failed_units":    {"linux"},
+	"systemd_list_unit_files": {"linux"},
+	// journalctl, dmesg, last/lastb
+	"system_diagnostics": {"linux"},
+	// Windows has no signals besides kill
+	"pkill":         {"linux", "darwin"},
+	"pkill_by_name": {"linux", "darwin"},
+}
+
+// rootTools need root privileges to work, e.g. to signal other users' processes or change services.
+// When the server runs unprivileged (-allow-nonroot) they return an error instead of running.
+var rootTools = map[string]bool{
+	"pkill":          true,
+	"pkill_by_name":  true,
+	"manage_service": true,
+}
+
+// expensiveTools scan every process, run external probes for seconds or sample over a window.
+// They share the -max-concurrent slots so a client cannot run many of them at once.
+var expensiveTools = map[string]bool{
+	"system_stats":       true,
+	"list_processes":     true,
+	"fd_usage":           true,
+	"traceroute":         true,
+	"latency_monitor":    true,
+	"latency_multi":      true,
+	"bandwidth_test":     true,
+	"system_diagnostics": true,
+}
+
+// cacheableTools only read host state, so with -result-cache-ttl an identical call within the TTL
+// reuses the previous result. Network measurements (latency, traceroute, ...) are repeated on purpose
+// and tools that change state (pkill, manage_service, delete_records) are never cached.
+var cacheableTools = map[string]bool{
+	"system_stats":            true,
+	"network_usage":           true,
+	"list_processes":          true,
+	"fd_usage":                true,
+	"host_info":               true,
+	"sensors":                 true,
+	"network_interfaces":      true,
+	"logged_in_users":         true,
+	"routes":                  true,
+	"neighbors":               true,
+	"port_status":             true,
+	"socket_summary":          true,
+	"ss_query":                true,
+	"tcp_info":                true,
+	"service_status":          true,
+	"systemd_list_units":      true,
+	"systemd_failed_units":    true,
+	"systemd_list_unit_files": true,
+	"systemd_logs":            true,
+	"system_diagnostics":      true,
+}
+
+// hasRootPrivileges reports whether the process runs as root
+func hasRootPrivileges() bool {
+	return os.Geteuid() == 0
+}
+
+// toolSupported reports whether a tool works on the current OS
+func toolSupported(name string) bool {
+	platforms, ok := toolPlatforms[name]
+	if !ok {
+		return true
+	}
+	for _, p := range platforms {
+		if p == runtime.GOOS {
+			return true
+		}
+	}
+	return false
+}
+
+// registerTool registers a tool with the server if it is supported on the current OS
+// and enabled by -enable-tools/-disable-tools.
+// Tools that need root are replaced by an error stub when the server is unprivileged,
+// tools whose commands are not installed return an install hint until they are.
+func registerTool(server *mcp.Server, name string, description string, schema json.RawMessage, handler mcp.ToolHandler) {
+	selectedTools.markKnown(name)
+	if !selectedTools.allows(name) {
+		slog.Debug("Skipping disabled tool", "tool", name)
+		return
+	}
+	if !toolSupported(name) {
+		slog.Debug("Skipping tool not supported on this platform", "tool", name, "os", runtime.GOOS)
+		return
+	}
+	if rootTools[name] && !hasRootPrivileges() {
+		slog.Debug("Tool requires root and will return an error", "tool", name)
+		handler = func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+			msg := fmt.Sprintf("%s requires root privileges, but the server is running as uid %d. Restart it as root to use this tool.", name, os.Geteuid())
+			return mcp.CallToolResult{IsError: true, Content: []mcp.ToolContent{{Type: "text", Text: msg}}}, nil
+		}
+	}
+	description, handler = requireBinaries(name, description, handler)
+	registeredTools = append(registeredTools, name)
+	server.RegisterTool(name, description, schema, handler)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// registerPrompts adds the canned troubleshooting flows
+func registerPrompts(server *mcp.Server) {
+	server.RegisterPrompt(mcp.Prompt{
+		Name:        "diagnose_high_latency",
+		Description: "Diagnose high latency or packet loss to a host",
+		Arguments: []mcp.PromptArgument{
+			{Name: "host", Description: "Target IP or hostname", Required: true},
+		},
+	}, func(args map[string]string) ([]mcp.PromptMessage, error) {
+		host := args["host"]
+		return userPrompt(fmt.Sprintf(`Diagnose high latency to %[1]s on this server.
+
+1. Run the "latency" tool with target "%[1]s" and mode "standard" to measure average latency, jitter and packet loss.
+2. Run the "traceroute" tool with target "%[1]s" to find the hop where latency jumps or packets are lost.
+3. If packet loss is high, run "path_mtu" with target "%[1]s" to rule out fragmentation issues.
+4. Run "system_stats" to check whether local CPU or network saturation could explain the delay.
+
+Summarize where the latency is introduced (local host, local network, upstream hop, or destination) and suggest next steps.`, host)), nil
+	})
+
+	// The service prompt relies on the systemd tools, which are only registered on Linux
+	if toolSupported("service_status") && selectedTools.allows("service_status") {
+		server.RegisterPrompt(mcp.Prompt{
+			Name:        "diagnose_service_failure",
+			Description: "Find out why a systemd service is failing",
+			Arguments: []mcp.PromptArgument{
+				{Name: "service", Description: "Systemd unit name (e.g. nginx)", Required: true},
+			},
+		}, func(args map[string]string) ([]mcp.PromptMessage, error) {
+			service := args["service"]
+			return userPrompt(fmt.Sprintf(`Find out why the %[1]s service is failing on this server.
+
+1. Run "service_status" with unit "%[1]s" to get its load/active/sub state and last result.
+2. Run "systemd_logs" with unit "%[1]s", priority "err" and lines 200 to collect recent errors.
+3. Run "systemd_failed_units" to see whether other units are failing too (e.g. a dependency).
+4. If the logs point at resource exhaustion, run "system_diagnostics" to check for OOM kills and full disks.
+
+Explain the root cause and propose a fix. Do not restart the service with "manage_service" unless asked.`, service)), nil
+		})
+	}
+
+	server.RegisterPrompt(mcp.Prompt{
+		Name:        "diagnose_unreachable_host",
+		Description: "Work out why a host cannot be reached",
+		Arguments: []mcp.PromptArgument{
+			{Name: "host", Description: "Target IP or hostname", Required: true},
+		},
+	}, func(args map[string]string) ([]mcp.PromptMessage, error) {
+		host := args["host"]
+		return userPrompt(fmt.Sprintf(`Work out why %[1]s cannot be reached from this server.
+
+1. Run "latency" with target "%[1]s" and mode "quick" to confirm the host is unreachable.
+2. Run "routes" to see which route and gateway traffic to %[1]s uses.
+3. Run "neighbors" to check whether the gateway (or the host itself on the LAN) has resolved at layer 2.
+4. Run "traceroute" with target "%[1]s" to find the last responding hop.
+
+Conclude whether the problem is local routing, layer 2, an upstream network, or the host itself.`, host)), nil
+	})
+}
+
+// userPrompt wraps text as a single user message
+func userPrompt(text string) []mcp.PromptMessage {
+	return []mcp.PromptMessage{{Role: "user", Content: mcp.ToolContent{Type: "text", Text: text}}}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mcp_cache "github.com/ashton2914/mcp-netutil/pkg/cache"
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+const (
+	// recordsURIPrefix is the URI prefix of cached record resources
+	recordsURIPrefix = "netutil://records/"
+	// recentResourceRecords is how many individual records resources/list exposes
+	recentResourceRecords = 50
+	// toolResourceRecords is how many records a per-tool resource contains
+	toolResourceRecords = 20
+)
+
+// cacheResources exposes cached records as MCP resources:
+//
+//	netutil://records/{tool}       the newest records of a tool
+//	netutil://records/{tool}/{id}  a single record's stored output
+type cacheResources struct{}
+
+func (cacheResources) ListResources() ([]mcp.Resource, error) {
+	tools, err := mcp_cache.ToolNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []mcp.Resource
+	for _, tool := range tools {
+		resources = append(resources, mcp.Resource{
+			URI:         recordsURIPrefix + tool,
+			Name:        tool + " records",
+			Description: fmt.Sprintf("The %d most recent %s records", toolResourceRecords, tool),
+			MimeType:    "application/json",
+		})
+	}
+
+	records, err := mcp_cache.RecentRecords("", recentResourceRecords)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		resources = append(resources, mcp.Resource{
+			URI:      fmt.Sprintf("%s%s/%d", recordsURIPrefix, r.ToolName, r.ID),
+			Name:     fmt.Sprintf("%s at %s", r.ToolName, r.Timestamp),
+			MimeType: "text/plain",
+		})
+	}
+
+	return resources, nil
+}
+
+func (cacheResources) ReadResource(uri string) ([]mcp.ResourceContents, error) {
+	path, ok := strings.CutPrefix(uri, recordsURIPrefix)
+	if !ok || path == "" {
+		return nil, mcp.ErrResourceNotFound
+	}
+
+	tool, idStr, hasID := strings.Cut(path, "/")
+	if !hasID {
+		records, err := mcp_cache.RecentRecords(tool, toolResourceRecords)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, mcp.ErrResourceNotFound
+		}
+		data, _ := json.MarshalIndent(records, "", "  ")
+		return []mcp.ResourceContents{{URI: uri, MimeType: "application/json", Text: string(data)}}, nil
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, mcp.ErrResourceNotFound
+	}
+	record, err := mcp_cache.GetRecord(id)
+	if err == mcp_cache.ErrRecordNotFound || (err == nil && record.ToolName != tool) {
+		return nil, mcp.ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{{URI: uri, MimeType: "text/plain", Text: record.Output}}, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// sessionHeader carries the Streamable HTTP session ID issued on initialize
+const sessionHeader = "Mcp-Session-Id"
+
+const (
+	// maxStreamableSessions bounds the session table, the least recently used session is dropped beyond it
+	maxStreamableSessions = 1024
+	// streamableSessionIdle is how long an unused session stays valid, the client then has to initialize again
+	streamableSessionIdle = time.Hour
+)
+
+// streamableSessions tracks the sessions issued by the Streamable HTTP transport and when each was last used
+type streamableSessions struct {
+	lastUsed map[string]time.Time
+	lock     sync.Mutex
+}
+
+func newStreamableSessions() *streamableSessions {
+	return &streamableSessions{lastUsed: make(map[string]time.Time)}
+}
+
+// create issues a new random session ID
+func (s *streamableSessions) create() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	if len(s.lastUsed) >= maxStreamableSessions {
+		oldest, oldestTime := "", now
+		for sid, used := range s.lastUsed {
+			if now.Sub(used) > streamableSessionIdle {
+				delete(s.lastUsed, sid)
+			} else if used.Before(oldestTime) {
+				oldest, oldestTime = sid, used
+			}
+		}
+		if len(s.lastUsed) >= maxStreamableSessions {
+			delete(s.lastUsed, oldest)
+		}
+	}
+	s.lastUsed[id] = now
+	return id
+}
+
+// touch reports whether id is a live session and marks it as used
+func (s *streamableSessions) touch(id string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	used, ok := s.lastUsed[id]
+	if !ok {
+		return false
+	}
+	if time.Since(used) > streamableSessionIdle {
+		delete(s.lastUsed, id)
+		return false
+	}
+	s.lastUsed[id] = time.Now()
+	return true
+}
+
+// remove ends a session and reports whether it existed
+func (s *streamableSessions) remove(id string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.lastUsed[id]
+	delete(s.lastUsed, id)
+	return ok
+}
+
+// streamableHTTPHandler serves the single-endpoint Streamable HTTP transport.
+// A POSTed request is answered in the same HTTP response, either as a JSON body
+// or, when the client accepts text/event-stream, as a one-event SSE stream.
+// Notifications need no reply and are acknowledged with 202 Accepted.
+// A successful initialize issues a session ID in the Mcp-Session-Id header, which every later
+// request must send: without it the request is rejected with 400, an unknown or expired one with 404.
+// DELETE with the header ends the session.
+func streamableHTTPHandler(server *mcp.Server) http.HandlerFunc {
+	sessions := newStreamableSessions()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			if !sessions.remove(r.Header.Get(sessionHeader)) {
+				http.Error(w, "Unknown session", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Method != http.MethodPost {
+			// Server-initiated streams over GET are optional and not offered, legacy clients use /sse
+			w.Header().Set("Allow", "POST, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		req, errResp := mcp.ParseRequest(body)
+		if errResp != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errResp)
+			return
+		}
+
+		initialize := req.Method == "initialize"
+		if !initialize {
+			id := r.Header.Get(sessionHeader)
+			if id == "" {
+				http.Error(w, "Missing "+sessionHeader+" header, send initialize first", http.StatusBadRequest)
+				return
+			}
+			if !sessions.touch(id) {
+				http.Error(w, "Unknown session", http.StatusNotFound)
+				return
+			}
+		}
+
+		resp := server.HandleSessionRequest(r.Header.Get(sessionHeader), req)
+		if initialize && resp != nil && resp.Error == nil {
+			w.Header().Set(sessionHeader, sessions.create())
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		slog.Debug("Sending response", "transport", "streamable-http", "id", resp.ID, "error", resp.Error)
+
+		data, _ := json.Marshal(resp)
+		if acceptsEventStream(r) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// acceptsEventStream reports whether the Accept header lists text/event-stream
+func acceptsEventStream(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(v, ",") {
+			mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+			if strings.EqualFold(mediaType, "text/event-stream") {
+				return true
+			}
+		}
+	}
+	return false
+}
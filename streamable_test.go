@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashton2914/mcp-netutil/pkg/mcp"
+)
+
+// postMCP sends a JSON-RPC body to the handler with the given session and Accept headers
+func postMCP(h http.HandlerFunc, body, session, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.Header.Set(sessionHeader, session)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+const initializeBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`
+
+func TestStreamableHTTP(t *testing.T) {
+	h := streamableHTTPHandler(mcp.NewServer())
+
+	rec := postMCP(h, initializeBody, "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("initialize = %d %s, want 200 application/json", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("initialize body = %s, want the negotiated protocol version", rec.Body.String())
+	}
+	session := rec.Header().Get(sessionHeader)
+	if len(session) != 32 {
+		t.Fatalf("initialize %s = %q, want a 32 character session ID", sessionHeader, session)
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		session     string
+		accept      string
+		wantCode    int
+		wantType    string
+		wantContain string
+	}{
+		{
+			name:        "JSON response",
+			body:        `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			session:     session,
+			accept:      "application/json",
+			wantCode:    http.StatusOK,
+			wantType:    "application/json",
+			wantContain: `"id":2`,
+		},
+		{
+			name:        "SSE response",
+			body:        `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`,
+			session:     session,
+			accept:      "application/json, text/event-stream",
+			wantCode:    http.StatusOK,
+			wantType:    "text/event-stream",
+			wantContain: "event: message\ndata: {\"jsonrpc\":\"2.0\"",
+		},
+		{
+			name:     "notification",
+			body:     `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			session:  session,
+			wantCode: http.StatusAccepted,
+		},
+		{
+			name:        "malformed request",
+			body:        `{"jsonrpc":`,
+			session:     session,
+			wantCode:    http.StatusBadRequest,
+			wantType:    "application/json",
+			wantContain: `"code":-32700`,
+		},
+		{
+			name:     "missing session",
+			body:     `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown session",
+			body:     `{"jsonrpc":"2.0","id":5,"method":"tools/list"}`,
+			session:  "0123456789abcdef0123456789abcdef",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postMCP(h, tt.body, tt.session, tt.accept)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantType != "" && rec.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.wantType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantContain) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.wantContain)
+			}
+		})
+	}
+
+	// DELETE ends the session, later requests with it are rejected
+	req := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	req.Header.Set(sessionHeader, session)
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d, want 204", rec.Code)
+	}
+	if rec := postMCP(h, `{"jsonrpc":"2.0","id":6,"method":"tools/list"}`, session, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("request after DELETE = %d, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/mcp", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want 405", rec.Code)
+	}
+}
+
+func TestStreamableSessionsBounded(t *testing.T) {
+	s := newStreamableSessions()
+	first := s.create()
+	s.lastUsed[first] = time.Now().Add(-time.Minute)
+	for i := 0; i < maxStreamableSessions; i++ {
+		s.create()
+	}
+	if len(s.lastUsed) != maxStreamableSessions {
+		t.Errorf("sessions = %d, want %d", len(s.lastUsed), maxStreamableSessions)
+	}
+	if s.touch(first) {
+		t.Error("least recently used session survived the cap")
+	}
+}
@@ -1,7 +1,14 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ashton2914/mcp-netutil/pkg/mcp"
 )
@@ -88,3 +95,605 @@ func TestHandleRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestToolTimeout(t *testing.T) {
+	server := mcp.NewServer()
+	server.SetToolTimeout(50 * time.Millisecond)
+
+	cancelled := make(chan struct{})
+	server.RegisterTool("slow", "Blocks until cancelled", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return mcp.CallToolResult{}, ctx.Err()
+	})
+
+	got := server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name": "slow", "arguments": {}}`),
+		ID:      1,
+	})
+	if got == nil || got.Error == nil {
+		t.Fatalf("HandleRequest() = %v, want timeout error", got)
+	}
+	if got.Error.Code != -32000 || !strings.Contains(got.Error.Message, "timed out") {
+		t.Errorf("HandleRequest() error = %+v, want -32000 tool timed out", got.Error)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("tool context was not cancelled")
+	}
+}
+
+func TestCancelToolCall(t *testing.T) {
+	server := mcp.NewServer()
+	metrics := server.EnableMetrics()
+
+	started := make(chan struct{})
+	observed := make(chan error, 1)
+	server.RegisterTool("slow", "Blocks until cancelled", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		observed <- ctx.Err()
+		return mcp.CallToolResult{}, ctx.Err()
+	})
+
+	done := make(chan *mcp.JSONRPCResponse, 1)
+	go func() {
+		done <- server.HandleRequest(mcp.JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  "tools/call",
+			Params:  json.RawMessage(`{"name": "slow", "arguments": {}}`),
+			ID:      "req-7",
+		})
+	}()
+	<-started
+
+	// Cancelling an unknown request is ignored
+	server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "notifications/cancelled", Params: json.RawMessage(`{"requestId": 7}`)})
+	select {
+	case got := <-done:
+		t.Fatalf("call finished after cancelling another request: %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if resp := server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  json.RawMessage(`{"requestId": "req-7", "reason": "user aborted"}`),
+	}); resp != nil {
+		t.Errorf("notifications/cancelled got a response: %+v", resp)
+	}
+
+	select {
+	case err := <-observed:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not observe cancellation")
+	}
+
+	got := <-done
+	if got == nil || got.Error == nil || got.Error.Code != -32800 {
+		t.Fatalf("HandleRequest() = %+v, want -32800 request cancelled", got)
+	}
+	if got.ID != "req-7" {
+		t.Errorf("response ID = %v, want req-7", got.ID)
+	}
+
+	var buf bytes.Buffer
+	metrics.WriteTo(&buf)
+	if want := `netutil_tool_calls_total{tool="slow",status="cancelled"} 1`; !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestCancelToolCallSessions(t *testing.T) {
+	server := mcp.NewServer()
+
+	started := make(chan struct{}, 2)
+	server.RegisterTool("slow", "Blocks until cancelled", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return mcp.CallToolResult{}, ctx.Err()
+	})
+
+	call := func(session string) chan *mcp.JSONRPCResponse {
+		done := make(chan *mcp.JSONRPCResponse, 1)
+		go func() {
+			done <- server.HandleSessionRequest(session, mcp.JSONRPCRequest{
+				JSONRPC: "2.0",
+				Method:  "tools/call",
+				Params:  json.RawMessage(`{"name": "slow", "arguments": {}}`),
+				ID:      1,
+			})
+		}()
+		<-started
+		return done
+	}
+	doneA := call("a")
+	doneB := call("b")
+
+	// The same ID in the same session is rejected instead of replacing the running call
+	dup := server.HandleSessionRequest("a", mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name": "slow", "arguments": {}}`),
+		ID:      1,
+	})
+	if dup == nil || dup.Error == nil || dup.Error.Code != -32600 {
+		t.Fatalf("duplicate call = %+v, want -32600", dup)
+	}
+
+	// A cancellation only reaches the call of its own session
+	server.HandleSessionRequest("b", mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "notifications/cancelled", Params: json.RawMessage(`{"requestId": 1}`)})
+	select {
+	case got := <-doneB:
+		if got == nil || got.Error == nil || got.Error.Code != -32800 {
+			t.Fatalf("session b call = %+v, want -32800 request cancelled", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session b call was not cancelled")
+	}
+	select {
+	case got := <-doneA:
+		t.Fatalf("session a call finished after cancelling session b: %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The rejected duplicate did not take over the running call's cancellation
+	server.HandleSessionRequest("a", mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "notifications/cancelled", Params: json.RawMessage(`{"requestId": 1}`)})
+	select {
+	case got := <-doneA:
+		if got == nil || got.Error == nil || got.Error.Code != -32800 {
+			t.Fatalf("session a call = %+v, want -32800 request cancelled", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session a call was not cancelled")
+	}
+}
+
+func TestConfirmationToken(t *testing.T) {
+	server := mcp.NewServer()
+	var calls []map[string]interface{}
+	server.RegisterTool("kill", "Kills a process", json.RawMessage(`{"type": "object", "properties": {"pid": {"type": "integer"}}}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		calls = append(calls, args)
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: "killed"}}}, nil
+	})
+	server.RequireConfirmation("kill", func(args map[string]interface{}) (string, bool) {
+		return "Kill the process", args["pid"] != float64(1)
+	})
+
+	call := func(args string) mcp.CallToolResult {
+		t.Helper()
+		resp := server.HandleRequest(mcp.JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  "tools/call",
+			Params:  json.RawMessage(`{"name": "kill", "arguments": ` + args + `}`),
+			ID:      1,
+		})
+		if resp == nil || resp.Error != nil {
+			t.Fatalf("HandleRequest(%s) = %+v, want a result", args, resp)
+		}
+		return resp.Result.(mcp.CallToolResult)
+	}
+	issue := func(args string) mcp.ConfirmationRequest {
+		t.Helper()
+		res := call(args)
+		var req mcp.ConfirmationRequest
+		if res.IsError || json.Unmarshal([]byte(res.Content[0].Text), &req) != nil || !req.ConfirmationRequired || req.Token == "" {
+			t.Fatalf("first call = %+v, want a confirmation request", res)
+		}
+		return req
+	}
+
+	// The schema advertises the token argument
+	list := server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "tools/list", ID: 1})
+	schema, _ := json.Marshal(list.Result)
+	if !strings.Contains(string(schema), mcp.ConfirmationTokenArg) {
+		t.Errorf("tools/list does not include %s: %s", mcp.ConfirmationTokenArg, schema)
+	}
+
+	// Calls the summary does not require confirmation for run directly
+	if res := call(`{"pid": 1}`); res.IsError || len(calls) != 1 {
+		t.Fatalf("unguarded call = %+v, calls = %d", res, len(calls))
+	}
+
+	req := issue(`{"pid": 42}`)
+	if req.Summary != "Kill the process" || len(calls) != 1 {
+		t.Fatalf("confirmation request = %+v, calls = %d, want summary and no call", req, len(calls))
+	}
+
+	// A token only confirms the arguments it was issued for
+	if res := call(`{"pid": 43, "confirmation_token": "` + req.Token + `"}`); !res.IsError || len(calls) != 1 {
+		t.Fatalf("call with other arguments = %+v, want error", res)
+	}
+
+	if res := call(`{"pid": 42, "confirmation_token": "` + req.Token + `"}`); res.IsError || len(calls) != 2 {
+		t.Fatalf("confirmed call = %+v, want success", res)
+	}
+	if _, ok := calls[1][mcp.ConfirmationTokenArg]; ok {
+		t.Errorf("tool received the confirmation token: %v", calls[1])
+	}
+
+	// Tokens are single-use
+	if res := call(`{"pid": 42, "confirmation_token": "` + req.Token + `"}`); !res.IsError || len(calls) != 2 {
+		t.Fatalf("reused token = %+v, want error", res)
+	}
+
+	server.SetConfirmationTTL(time.Millisecond)
+	req = issue(`{"pid": 42}`)
+	time.Sleep(5 * time.Millisecond)
+	if res := call(`{"pid": 42, "confirmation_token": "` + req.Token + `"}`); !res.IsError || !strings.Contains(res.Content[0].Text, "expired") || len(calls) != 2 {
+		t.Fatalf("expired token = %+v, want error", res)
+	}
+}
+
+func TestMaxResultSize(t *testing.T) {
+	server := mcp.NewServer()
+	server.SetMaxResultSize(10)
+	server.RegisterTool("big", "Returns a large result", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: "0123456789abcdef"}, {Type: "text", Text: "short"}}}, nil
+	})
+
+	resp := server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "tools/call", Params: json.RawMessage(`{"name": "big", "arguments": {}}`), ID: 1})
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("HandleRequest() = %+v, want a result", resp)
+	}
+	content := resp.Result.(mcp.CallToolResult).Content
+	if want := "0123456789...[truncated 6 bytes]"; content[0].Text != want {
+		t.Errorf("content[0] = %q, want %q", content[0].Text, want)
+	}
+	if content[1].Text != "short" {
+		t.Errorf("content[1] = %q, want it unchanged", content[1].Text)
+	}
+
+	// Multi-byte characters are not split
+	if got := mcp.TruncateText("aé", 2); got != "a...[truncated 2 bytes]" {
+		t.Errorf("TruncateText() = %q", got)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	server := mcp.NewServer()
+	server.SetMaxConcurrent(1, 50*time.Millisecond)
+	server.LimitConcurrency("scan")
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	handler := func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		started <- struct{}{}
+		<-unblock
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: "done"}}}, nil
+	}
+	server.RegisterTool("scan", "Expensive", json.RawMessage(`{"type": "object"}`), handler)
+	server.RegisterTool("cheap", "Not limited", json.RawMessage(`{"type": "object"}`), handler)
+
+	call := func(name string, id int) *mcp.JSONRPCResponse {
+		return server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "tools/call", Params: json.RawMessage(`{"name": "` + name + `", "arguments": {}}`), ID: id})
+	}
+
+	first := make(chan *mcp.JSONRPCResponse, 1)
+	go func() { first <- call("scan", 1) }()
+	<-started
+
+	// The only slot is taken, so a second expensive call reports busy after the wait
+	resp := call("scan", 2)
+	result, ok := resp.Result.(mcp.CallToolResult)
+	if !ok || !result.IsError || !strings.Contains(result.Content[0].Text, "Server busy") {
+		t.Errorf("second call = %+v, want server busy error", resp)
+	}
+
+	// Tools that are not limited still run
+	cheap := make(chan *mcp.JSONRPCResponse, 1)
+	go func() { cheap <- call("cheap", 3) }()
+	<-started
+	unblock <- struct{}{}
+	unblock <- struct{}{}
+	for _, ch := range []chan *mcp.JSONRPCResponse{first, cheap} {
+		if r := <-ch; r.Error != nil || r.Result.(mcp.CallToolResult).IsError {
+			t.Errorf("call = %+v, want success", r)
+		}
+	}
+
+	// The slot is free again once the first call returned
+	go func() { <-started; unblock <- struct{}{} }()
+	if r := call("scan", 4); r.Error != nil || r.Result.(mcp.CallToolResult).IsError {
+		t.Errorf("call after release = %+v, want success", r)
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	server := mcp.NewServer()
+	server.SetResultCacheTTL(time.Minute)
+	server.CacheResults("stats")
+
+	calls := 0
+	handler := func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		calls++
+		return mcp.CallToolResult{Content: []mcp.ToolContent{{Type: "text", Text: "result"}}}, nil
+	}
+	server.RegisterTool("stats", "Read-only", json.RawMessage(`{"type": "object"}`), handler)
+	server.RegisterTool("kill", "Changes state", json.RawMessage(`{"type": "object"}`), handler)
+
+	call := func(name, args string) mcp.CallToolResult {
+		resp := server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "tools/call", Params: json.RawMessage(`{"name": "` + name + `", "arguments": ` + args + `}`), ID: 1})
+		if resp == nil || resp.Error != nil {
+			t.Fatalf("HandleRequest() = %+v, want a result", resp)
+		}
+		return resp.Result.(mcp.CallToolResult)
+	}
+
+	if r := call("stats", `{"a": 1, "b": 2}`); r.Meta != nil {
+		t.Errorf("first call meta = %v, want none", r.Meta)
+	}
+	// Same arguments in a different order hit the cache
+	r := call("stats", `{"b": 2, "a": 1}`)
+	if calls != 1 || r.Meta["cached"] != true || r.Content[0].Text != "result" {
+		t.Errorf("second call ran the tool (calls=%d) or lacks the cached marker: %+v", calls, r)
+	}
+	call("stats", `{"a": 2}`)
+	if calls != 2 {
+		t.Errorf("different arguments should run the tool, calls=%d", calls)
+	}
+
+	call("kill", `{}`)
+	call("kill", `{}`)
+	if calls != 4 {
+		t.Errorf("tools not marked cacheable must always run, calls=%d", calls)
+	}
+
+	server.SetResultCacheTTL(0)
+	call("stats", `{"a": 1, "b": 2}`)
+	if calls != 5 {
+		t.Errorf("disabled cache should run the tool, calls=%d", calls)
+	}
+}
+
+func TestToolCallLogging(t *testing.T) {
+	var buf bytes.Buffer
+	server := mcp.NewServer()
+	server.SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	server.RegisterTool("fail", "Always fails", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		return mcp.CallToolResult{}, errors.New("boom")
+	})
+
+	server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name": "fail", "arguments": {}}`),
+		ID:      1,
+	})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log output %q is not a single JSON entry: %v", buf.String(), err)
+	}
+	if entry["level"] != "ERROR" || entry["tool"] != "fail" || entry["method"] != "tools/call" || entry["error"] != "boom" {
+		t.Errorf("log entry = %v, want ERROR for tool fail with error boom", entry)
+	}
+	if _, ok := entry["duration"]; !ok {
+		t.Errorf("log entry = %v, want duration", entry)
+	}
+}
+
+func TestToolsListChangedNotification(t *testing.T) {
+	server := mcp.NewServer()
+
+	var got []mcp.JSONRPCNotification
+	server.SetNotificationHandler(func(n mcp.JSONRPCNotification) {
+		got = append(got, n)
+	})
+
+	handler := func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		return mcp.CallToolResult{}, nil
+	}
+	server.RegisterTool("dynamic", "Registered at runtime", json.RawMessage(`{"type": "object"}`), handler)
+
+	if len(got) != 1 || got[0].Method != "notifications/tools/list_changed" {
+		t.Fatalf("notifications after RegisterTool = %+v, want one notifications/tools/list_changed", got)
+	}
+
+	if !server.UnregisterTool("dynamic") {
+		t.Fatalf("UnregisterTool() = false, want true")
+	}
+	if len(got) != 2 {
+		t.Errorf("notifications after UnregisterTool = %d, want 2", len(got))
+	}
+
+	if server.UnregisterTool("dynamic") {
+		t.Errorf("UnregisterTool() of missing tool = true, want false")
+	}
+	if len(got) != 2 {
+		t.Errorf("notifications after unregistering a missing tool = %d, want 2", len(got))
+	}
+}
+
+func TestInitializeProtocolVersion(t *testing.T) {
+	server := mcp.NewServer()
+
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{name: "supported old version", params: `{"protocolVersion": "2024-11-05"}`, want: "2024-11-05"},
+		{name: "supported latest version", params: `{"protocolVersion": "2025-03-26"}`, want: "2025-03-26"},
+		{name: "unknown version", params: `{"protocolVersion": "1999-01-01"}`, want: mcp.LatestProtocolVersion},
+		{name: "no params", params: ``, want: mcp.LatestProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := server.HandleRequest(mcp.JSONRPCRequest{
+				JSONRPC: "2.0",
+				Method:  "initialize",
+				Params:  json.RawMessage(tt.params),
+				ID:      1,
+			})
+			if got == nil || got.Result == nil {
+				t.Fatalf("HandleRequest() = %v, want Result", got)
+			}
+			result := got.Result.(map[string]interface{})
+			if v := result["protocolVersion"]; v != tt.want {
+				t.Errorf("protocolVersion = %v, want %v", v, tt.want)
+			}
+		})
+	}
+}
+
+type stubResources struct{}
+
+func (stubResources) ListResources() ([]mcp.Resource, error) {
+	return []mcp.Resource{{URI: "netutil://records/latency/1", Name: "latency"}}, nil
+}
+
+func (stubResources) ReadResource(uri string) ([]mcp.ResourceContents, error) {
+	if uri != "netutil://records/latency/1" {
+		return nil, mcp.ErrResourceNotFound
+	}
+	return []mcp.ResourceContents{{URI: uri, Text: `{"avg_latency":"1 ms"}`}}, nil
+}
+
+func TestResources(t *testing.T) {
+	server := mcp.NewServer()
+	server.SetResourceProvider(stubResources{})
+
+	list := server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "resources/list", ID: 1})
+	if list == nil || list.Error != nil {
+		t.Fatalf("resources/list = %+v, want result", list)
+	}
+
+	read := server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "resources/read",
+		Params:  json.RawMessage(`{"uri": "netutil://records/latency/1"}`),
+		ID:      2,
+	})
+	if read == nil || read.Error != nil {
+		t.Fatalf("resources/read = %+v, want result", read)
+	}
+
+	missing := server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "resources/read",
+		Params:  json.RawMessage(`{"uri": "netutil://records/latency/2"}`),
+		ID:      3,
+	})
+	if missing == nil || missing.Error == nil || missing.Error.Code != -32002 {
+		t.Errorf("resources/read of missing uri = %+v, want -32002", missing)
+	}
+}
+
+func TestPrompts(t *testing.T) {
+	server := mcp.NewServer()
+	server.RegisterPrompt(mcp.Prompt{
+		Name:      "check_host",
+		Arguments: []mcp.PromptArgument{{Name: "host", Required: true}},
+	}, func(args map[string]string) ([]mcp.PromptMessage, error) {
+		return []mcp.PromptMessage{{Role: "user", Content: mcp.ToolContent{Type: "text", Text: "ping " + args["host"]}}}, nil
+	})
+
+	list := server.HandleRequest(mcp.JSONRPCRequest{JSONRPC: "2.0", Method: "prompts/list", ID: 1})
+	if list == nil || list.Error != nil {
+		t.Fatalf("prompts/list = %+v, want result", list)
+	}
+
+	get := server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "prompts/get",
+		Params:  json.RawMessage(`{"name": "check_host", "arguments": {"host": "example.com"}}`),
+		ID:      2,
+	})
+	if get == nil || get.Error != nil {
+		t.Fatalf("prompts/get = %+v, want result", get)
+	}
+	messages := get.Result.(map[string]interface{})["messages"].([]mcp.PromptMessage)
+	if len(messages) != 1 || messages[0].Content.Text != "ping example.com" {
+		t.Errorf("prompts/get messages = %+v, want rendered prompt", messages)
+	}
+
+	missingArg := server.HandleRequest(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "prompts/get",
+		Params:  json.RawMessage(`{"name": "check_host"}`),
+		ID:      3,
+	})
+	if missingArg == nil || missingArg.Error == nil || missingArg.Error.Code != -32602 {
+		t.Errorf("prompts/get without required argument = %+v, want -32602", missingArg)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantID   interface{}
+	}{
+		{name: "valid", body: `{"jsonrpc": "2.0", "method": "tools/list", "id": 1}`},
+		{name: "truncated json", body: `{"jsonrpc": "2.0", "id": 7, "method": `, wantCode: -32700, wantID: nil},
+		{name: "wrong field type keeps id", body: `{"jsonrpc": "2.0", "id": 7, "method": 5}`, wantCode: -32600, wantID: float64(7)},
+		{name: "missing method", body: `{"jsonrpc": "2.0", "id": "abc"}`, wantCode: -32600, wantID: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errResp := mcp.ParseRequest([]byte(tt.body))
+			if tt.wantCode == 0 {
+				if errResp != nil {
+					t.Fatalf("ParseRequest() error = %+v, want nil", errResp)
+				}
+				return
+			}
+			if errResp == nil || errResp.Error == nil {
+				t.Fatalf("ParseRequest() = nil, want error %d", tt.wantCode)
+			}
+			if errResp.Error.Code != tt.wantCode || errResp.ID != tt.wantID {
+				t.Errorf("ParseRequest() = code %d id %v, want code %d id %v", errResp.Error.Code, errResp.ID, tt.wantCode, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestToolCallMetrics(t *testing.T) {
+	server := mcp.NewServer()
+	metrics := server.EnableMetrics()
+	metrics.RegisterGauge("netutil_sse_clients", "Number of connected SSE clients.", func() float64 { return 2 })
+
+	server.RegisterTool("ok", "Succeeds", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		return mcp.CallToolResult{}, nil
+	})
+	server.RegisterTool("bad", "Reports an error", json.RawMessage(`{"type": "object"}`), func(ctx context.Context, args map[string]interface{}) (mcp.CallToolResult, error) {
+		return mcp.CallToolResult{IsError: true}, nil
+	})
+
+	for i, name := range []string{"ok", "ok", "bad"} {
+		server.HandleRequest(mcp.JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  "tools/call",
+			Params:  json.RawMessage(`{"name": "` + name + `", "arguments": {}}`),
+			ID:      i + 1,
+		})
+	}
+
+	var buf bytes.Buffer
+	if _, err := metrics.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`netutil_tool_calls_total{tool="ok",status="success"} 2`,
+		`netutil_tool_calls_total{tool="bad",status="error"} 1`,
+		`netutil_tool_duration_seconds_bucket{tool="ok",le="+Inf"} 2`,
+		`netutil_tool_duration_seconds_count{tool="bad"} 1`,
+		`netutil_sse_clients 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
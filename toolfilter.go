@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// toolSelection is the effective tool set from -enable-tools and -disable-tools
+type toolSelection struct {
+	enabled  map[string]bool // nil enables every tool
+	disabled map[string]bool
+	known    map[string]bool // Every tool passed to registerTool, to catch misspelled names
+}
+
+// selectedTools is consulted by registerTool, the zero value enables every tool
+var selectedTools toolSelection
+
+// newToolSelection parses the comma-separated flag values
+func newToolSelection(enable, disable string) toolSelection {
+	sel := toolSelection{disabled: parseToolList(disable)}
+	// A list without names, e.g. " , ", enables every tool like an empty flag instead of none
+	if enabled := parseToolList(enable); len(enabled) > 0 {
+		sel.enabled = enabled
+	}
+	return sel
+}
+
+// parseToolList splits a comma-separated list of tool names
+func parseToolList(list string) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// allows reports whether a tool is in the effective set, disabling wins over enabling
+func (s *toolSelection) allows(name string) bool {
+	if s.disabled[name] {
+		return false
+	}
+	return s.enabled == nil || s.enabled[name]
+}
+
+// markKnown records a tool name offered for registration
+func (s *toolSelection) markKnown(name string) {
+	if s.known == nil {
+		s.known = make(map[string]bool)
+	}
+	s.known[name] = true
+}
+
+// validate reports names in either list that no registered tool has, which would silently leave a tool enabled
+func (s *toolSelection) validate() error {
+	var unknown []string
+	for _, list := range []map[string]bool{s.enabled, s.disabled} {
+		for name := range list {
+			if !s.known[name] {
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown tool names: %s", strings.Join(unknown, ", "))
+}
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestToolSelectionAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		enable  string
+		disable string
+		want    map[string]bool
+	}{
+		{
+			name: "default enables everything",
+			want: map[string]bool{"latency": true, "pkill": true},
+		},
+		{
+			name:   "enable only",
+			enable: "latency, traceroute",
+			want:   map[string]bool{"latency": true, "traceroute": true, "pkill": false},
+		},
+		{
+			name:    "disable only",
+			disable: "pkill,pkill_by_name",
+			want:    map[string]bool{"latency": true, "pkill": false, "pkill_by_name": false},
+		},
+		{
+			name:    "disable wins",
+			enable:  "latency,pkill",
+			disable: "pkill",
+			want:    map[string]bool{"latency": true, "pkill": false, "traceroute": false},
+		},
+		{
+			name:   "blank enable list",
+			enable: " , ",
+			want:   map[string]bool{"latency": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := newToolSelection(tt.enable, tt.disable)
+			for tool, want := range tt.want {
+				if got := sel.allows(tool); got != want {
+					t.Errorf("allows(%q) = %v, want %v", tool, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestToolSelectionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		enable  string
+		disable string
+		wantErr string
+	}{
+		{name: "no lists"},
+		{name: "known names", enable: "latency,traceroute", disable: "pkill"},
+		{name: "unknown enabled", enable: "latency,latnecy", wantErr: "unknown tool names: latnecy"},
+		{name: "unknown in both lists sorted", enable: "zzz", disable: "aaa,pkill", wantErr: "unknown tool names: aaa, zzz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := newToolSelection(tt.enable, tt.disable)
+			for _, tool := range []string{"latency", "traceroute", "pkill"} {
+				sel.markKnown(tool)
+			}
+			err := sel.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}